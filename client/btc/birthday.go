@@ -0,0 +1,42 @@
+package btc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Block time stamps may be hours off the time a block was found, and the
+// wallet's clock may be off too, so the birthday height found for a birthday
+// date is that of a block a day before.
+const birthdayTimeMargin = 24 * time.Hour
+
+// updateWalletBirthday finds the block height of the wallet birthday from the
+// client headers if the wallet knows only the birthday date
+func (ec *BtcElectrumClient) updateWalletBirthday() {
+	w := ec.GetWallet()
+	if w == nil || w.BirthdayHeight() > 0 || w.CreationDate().IsZero() {
+		return
+	}
+	h := ec.clientHeaders
+	if !h.Synced() {
+		return
+	}
+	height, ok := h.HeightForTime(w.CreationDate().Add(-birthdayTimeMargin))
+	if !ok {
+		// older than the stored headers
+		return
+	}
+	err := w.SetBirthdayHeight(height)
+	if err != nil {
+		fmt.Printf("cannot set wallet birthday height - %v\n", err)
+	}
+}
+
+// beforeBirthday returns true for history confirmed before the wallet
+// birthday, which cannot have wallet transactions
+func beforeBirthday(w wallet.ElectrumWallet, h electrumx.History) bool {
+	return h.Height > 0 && h.Height < w.BirthdayHeight()
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 
 	"github.com/dev-warrior777/go-electrum-client/client"
 	"github.com/dev-warrior777/go-electrum-client/electrumx"
@@ -27,6 +28,14 @@ type BtcElectrumClient struct {
 	cancelAddressStatusNotify context.CancelFunc
 	// cancel stale headersNotify thread after network restart
 	cancelHeadersNotify context.CancelFunc
+	// live fee rates from the node shared by the client and wallet
+	feeEstimator *wallet.NetworkFeeEstimator
+	// unconfirmed sends checked for eviction and stuck
+	pendingSends pendingSends
+	// serializes the checks of unconfirmed wallet txs
+	unconfirmedMtx sync.Mutex
+	// scripthashes watched by confirmation and spend waiters
+	waiters scripthashWaiters
 }
 
 func NewBtcElectrumClient(cfg *client.ClientConfig) client.ElectrumClient {
@@ -38,6 +47,7 @@ func NewBtcElectrumClient(cfg *client.ClientConfig) client.ElectrumClient {
 		cancelHeadersNotify:       nil,
 	}
 	ec.clientHeaders = NewHeaders(cfg)
+	ec.feeEstimator = wallet.NewNetworkFeeEstimator(&nodeFeeSource{&ec}, cfg.FeeConfTargets)
 	return &ec
 }
 
@@ -126,6 +136,7 @@ func (ec *BtcElectrumClient) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	ec.monitorPendingSends(ctx)
 	return nil
 }
 
@@ -163,6 +174,7 @@ func (ec *BtcElectrumClient) listenNetworkRestarted(ctx context.Context) error {
 						fmt.Println("network restart cancelAddressStatusNotify == <nil>")
 					}
 					ec.SyncWallet(ctx)
+					ec.resubscribeWaiters(ctx)
 				}
 			}
 		}
@@ -180,9 +192,28 @@ func (ec *BtcElectrumClient) Stop() {
 	fmt.Printf("client stopped\n")
 }
 
+// makeWalletConfig makes the wallet config from the client config and adds
+// the client's live fee estimator.
+func (ec *BtcElectrumClient) makeWalletConfig() *wallet.WalletConfig {
+	walletCfg := ec.ClientConfig.MakeWalletConfig()
+	walletCfg.FeeEstimator = ec.feeEstimator
+	return walletCfg
+}
+
+// makeNewWalletConfig makes the wallet config for a new wallet which is born
+// at the current tip if the headers are synced.
+func (ec *BtcElectrumClient) makeNewWalletConfig() *wallet.WalletConfig {
+	walletCfg := ec.makeWalletConfig()
+	if tip, synced := ec.Tip(); synced {
+		walletCfg.BirthdayHeight = tip
+	}
+	return walletCfg
+}
+
 // CreateWallet makes a new wallet with a new seed. The password is to encrypt
-// stored xpub, xprv and other sensitive data.
-func (ec *BtcElectrumClient) CreateWallet(pw string) error {
+// stored xpub, xprv and other sensitive data. passphrase is an optional BIP39
+// passphrase; if given it is needed with the mnemonic to recreate the wallet.
+func (ec *BtcElectrumClient) CreateWallet(pw, passphrase string) error {
 	if ec.walletExists() {
 		return errors.New("wallet already exists")
 	}
@@ -191,19 +222,20 @@ func (ec *BtcElectrumClient) CreateWallet(pw string) error {
 		return err
 	}
 
-	walletCfg := ec.ClientConfig.MakeWalletConfig()
+	walletCfg := ec.makeNewWalletConfig()
 
-	ec.Wallet, err = wltbtc.NewBtcElectrumWallet(walletCfg, pw)
+	ec.Wallet, err = wltbtc.NewBtcElectrumWallet(walletCfg, pw, passphrase)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// RecreateWallet recreates a wallet from an existing mnemonic seed.
-// The password is to encrypt the stored xpub, xprv and other sensitive data
-// and can be different from the original wallet's password.
-func (ec *BtcElectrumClient) RecreateWallet(ctx context.Context, pw, mnenomic string) error {
+// RecreateWallet recreates a wallet from an existing mnemonic seed and its
+// BIP39 passphrase, if any, and rescans including account discovery. The
+// password is to encrypt the stored xpub, xprv and other sensitive data and
+// can be different from the original wallet's password.
+func (ec *BtcElectrumClient) RecreateWallet(ctx context.Context, pw, mnenomic, passphrase string) error {
 	if ec.walletExists() {
 		//TODO: should we backup any wallet file that exists
 		return errors.New("wallet already exists")
@@ -212,8 +244,8 @@ func (ec *BtcElectrumClient) RecreateWallet(ctx context.Context, pw, mnenomic st
 	if err != nil {
 		return err
 	}
-	walletCfg := ec.ClientConfig.MakeWalletConfig()
-	ec.Wallet, err = wltbtc.RecreateElectrumWallet(walletCfg, pw, mnenomic)
+	walletCfg := ec.makeWalletConfig()
+	ec.Wallet, err = wltbtc.RecreateElectrumWallet(walletCfg, pw, mnenomic, passphrase)
 	if err != nil {
 		return err
 	}
@@ -223,9 +255,103 @@ func (ec *BtcElectrumClient) RecreateWallet(ctx context.Context, pw, mnenomic st
 	if err != nil {
 		return err
 	}
+	// The seed may be from another wallet using the standard derivation paths
+	_, err = ec.DiscoverAccounts(ctx, pw)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
+// CreateElectrumSeedWallet makes a new wallet with a new Electrum seed of
+// seedType and returns the mnemonic, which the user should save offline.
+func (ec *BtcElectrumClient) CreateElectrumSeedWallet(pw string, seedType wallet.ElectrumSeedType) (string, error) {
+	if ec.walletExists() {
+		return "", errors.New("wallet already exists")
+	}
+	err := ec.getDatastore()
+	if err != nil {
+		return "", err
+	}
+	walletCfg := ec.makeNewWalletConfig()
+	var mnemonic string
+	ec.Wallet, mnemonic, err = wltbtc.NewElectrumSeedWallet(walletCfg, pw, seedType)
+	if err != nil {
+		return "", err
+	}
+	return mnemonic, nil
+}
+
+// RecreateElectrumSeedWallet recreates a wallet from the mnemonic of an
+// Electrum standard or segwit wallet and rescans. The seed type is taken from
+// the mnemonic.
+func (ec *BtcElectrumClient) RecreateElectrumSeedWallet(ctx context.Context, pw, mnemonic string) error {
+	if ec.walletExists() {
+		return errors.New("wallet already exists")
+	}
+	err := ec.getDatastore()
+	if err != nil {
+		return err
+	}
+	walletCfg := ec.makeWalletConfig()
+	ec.Wallet, err = wltbtc.RecreateElectrumSeedWallet(walletCfg, pw, mnemonic)
+	if err != nil {
+		return err
+	}
+	return ec.RescanWallet(ctx)
+}
+
+// ImportDescriptors makes a new wallet from the BIP380 output descriptors of
+// an account's receive (/0/*) and change (/1/*) chains, as exported by
+// ExportDescriptors, and rescans. Public descriptors make a watch-only wallet.
+// The password is to encrypt the stored descriptors.
+func (ec *BtcElectrumClient) ImportDescriptors(ctx context.Context, pw, receive, change string) error {
+	if ec.walletExists() {
+		return errors.New("wallet already exists")
+	}
+	err := ec.getDatastore()
+	if err != nil {
+		return err
+	}
+	walletCfg := ec.makeWalletConfig()
+	ec.Wallet, err = wltbtc.ImportDescriptorWallet(walletCfg, pw, receive, change)
+	if err != nil {
+		return err
+	}
+	return ec.RescanWallet(ctx)
+}
+
+// MasterFingerprint returns the wallet's hex master key fingerprint. Show it
+// to the user after create or recreate so they can check a BIP39 passphrase.
+func (ec *BtcElectrumClient) MasterFingerprint() (string, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return "", ErrNoWallet
+	}
+	return w.MasterFingerprint()
+}
+
+// ExportDescriptors exports the wallet's receive and change output
+// descriptors with key origin and checksum. The password is only needed for
+// private descriptors.
+func (ec *BtcElectrumClient) ExportDescriptors(pw string, private bool) (*wallet.WalletDescriptors, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	return w.ExportDescriptors(pw, private)
+}
+
+// ChangePassword changes the wallet password. The stored xpub, xprv and
+// other sensitive data are re-encrypted with the new password.
+func (ec *BtcElectrumClient) ChangePassword(oldPw, newPw string) error {
+	w := ec.GetWallet()
+	if w == nil {
+		return ErrNoWallet
+	}
+	return w.ChangePassword(oldPw, newPw)
+}
+
 // LoadWallet loads an existing wallet. The password is required to decrypt
 // the stored xpub, xprv and other sensitive data
 func (ec *BtcElectrumClient) LoadWallet(pw string) error {
@@ -236,11 +362,12 @@ func (ec *BtcElectrumClient) LoadWallet(pw string) error {
 	if err != nil {
 		return err
 	}
-	walletCfg := ec.ClientConfig.MakeWalletConfig()
+	walletCfg := ec.makeWalletConfig()
 	ec.Wallet, err = wltbtc.LoadBtcElectrumWallet(walletCfg, pw)
 	if err != nil {
 		return err
 	}
+	ec.updateWalletBirthday()
 	return nil
 }
 
@@ -256,21 +383,23 @@ func (ec *BtcElectrumClient) CloseWallet() {
 // Tip() (int64, bool)
 // GetBlockHeader(height int64) *wire.BlockHeader
 // GetBlockHeaders(startHeight, count int64) ([]*wire.BlockHeader, error)
-// RegisterTipChangeNotify(tipChange func(height int64)) error
-// UnegisterTipChangeNotify()
+// SubscribeTipChanges(buffer int) *client.TipSubscription
 
 // Interface methods in client_wallet.go
 //
 // Spend(amount int64, toAddress string, feeLevel wallet.FeeLevel, broadcast bool) (string, string, error)
 // GetPrivKeyForAddress(pw, addr string) (string, error)
+// SignMessage(pw, addr, message string) (string, error)
+// VerifyMessage(addr, message, signature string) (bool, error)
 // Broadcast(ctx context.Context, rawTx []byte) (string, error)
 // FeeRate(ctx context.Context, confTarget int64) (int64, error)
 // ListUnspent() ([]wallet.Utxo, error)
-// UnusedAddress(ctx context.Context) (string, error)
+// UnusedAddress(ctx context.Context) (string, bool, error)
 // ChangeAddress(ctx context.Context) (string, error)
 // Balance() (int64, int64, error)
 // FreezeUTXO((txid string, out uint32) error
 // UnFreezeUTXO((txid string, out uint32) error
+// SubscribeWalletEvents(buffer int) (*wallet.EventSubscription, error)
 // GetWalletTx(txid string) (int, bool, []byte, error)
 // GetWalletSpents() ([]wallet.Stxo, error)
 
@@ -280,5 +409,10 @@ func (ec *BtcElectrumClient) CloseWallet() {
 // GetRawTransaction(ctx context.Context,txid string) ([]byte, error)
 // GetAddressHistory(ctx context.Context, addr string) (electrumx.HistoryResult, error)
 // GetAddressUnspent(ctx context.Context, addr string) (electrumx.ListUnspentResult, error)
+
+// Interface methods in waiters.go
+//
+// WaitForConfirmations(ctx context.Context, txid string, n int64) error
+// WaitForSpend(ctx context.Context, outpoint *wire.OutPoint) (*wire.MsgTx, error)
 //
 //////////////////////////////////////////////////////////////////////////////
@@ -1,6 +1,7 @@
 package btc
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"errors"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/client"
 )
 
 // syncHeaders uodates the client headers and then subscribes for new update
@@ -30,9 +32,6 @@ func (ec *BtcElectrumClient) syncHeaders(ctx context.Context) error {
 func (ec *BtcElectrumClient) syncClientHeaders(ctx context.Context) error {
 	h := ec.clientHeaders
 
-	// we start from a recent height for testnet/mainnet
-	startPointHeight := h.startPoint
-
 	// 1. Read last stored blockchain_headers file for this network
 
 	b, err := h.ReadAllBytesFromFile()
@@ -47,6 +46,14 @@ func (ec *BtcElectrumClient) syncClientHeaders(ctx context.Context) error {
 	}
 	b = nil // gc
 
+	// we start from a recent height for testnet/mainnet or the wallet
+	// birthday
+	err = h.syncStartPoint(numHeaders)
+	if err != nil {
+		return err
+	}
+	startPointHeight := h.startPoint
+
 	var maybeTip int64 = startPointHeight + numHeaders - 1
 
 	// 2. Gather new block headers we did not have in file up to current tip
@@ -136,20 +143,21 @@ func (ec *BtcElectrumClient) syncClientHeaders(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	h.tip = maybeTip
+	h.SetTip(maybeTip)
 
 	// 5. Verify headers in headers map
-	fmt.Printf("starting verify at height %d\n", h.tip)
+	fmt.Printf("starting verify at height %d\n", h.Tip())
 	err = h.VerifyAll()
 	if err != nil {
 		return err
 	}
 	fmt.Println("header chain verified")
 
-	h.synced = true
-	fmt.Println("headers synced up to tip ", h.tip)
+	h.SetSynced(true)
+	fmt.Println("headers synced up to tip ", h.Tip())
 	ec.updateWalletTip()
-	ec.tipChanged()
+	ec.updateWalletBirthday()
+	ec.tipChanged(false)
 	return nil
 }
 
@@ -167,7 +175,7 @@ func (ec *BtcElectrumClient) headersNotify(ctx context.Context) error {
 	h := ec.clientHeaders
 
 	// local tip for calculation before storage
-	maybeTip := h.tip
+	maybeTip := h.Tip()
 
 	node := ec.GetNode()
 
@@ -211,15 +219,29 @@ func (ec *BtcElectrumClient) headersNotify(ctx context.Context) error {
 				// read whatever is in the queue, usually one header at tip
 				for x := range hdrResNotifyCh {
 					fmt.Printf("new block: height %d %s\n", x.Height, x.Hex)
+					b, err := hex.DecodeString(x.Hex)
+					if err != nil {
+						panic(err)
+					}
+					hdr := &wire.BlockHeader{}
+					err = hdr.Deserialize(bytes.NewReader(b))
+					if err != nil {
+						panic(err)
+					}
+					if h.Reorged(hdr, x.Height) {
+						err = ec.reorgHeaders(ctx, x.Height)
+						if err != nil {
+							fmt.Printf("cannot follow reorg to height %d: %v\n", x.Height, err)
+							continue
+						}
+						maybeTip = h.Tip()
+						continue
+					}
 					if x.Height > maybeTip {
 						n := x.Height - maybeTip
 						if n == 1 {
 							// simple case: just store it
 							fmt.Println("Storing header for height: ", x.Height)
-							b, err := hex.DecodeString(x.Hex)
-							if err != nil {
-								panic(err)
-							}
 							hdrsAppended, err := h.AppendHeaders(b)
 							if err != nil {
 								panic(err)
@@ -233,10 +255,10 @@ func (ec *BtcElectrumClient) headersNotify(ctx context.Context) error {
 							}
 
 							// update tip / local tip / wallet tip /  notify listener
-							h.tip = x.Height
+							h.SetTip(x.Height)
 							maybeTip = x.Height
 							ec.updateWalletTip()
-							ec.tipChanged()
+							ec.tipChanged(false)
 
 							// verify added header back from new tip
 							h.VerifyFromTip(2, false)
@@ -263,6 +285,21 @@ func (ec *BtcElectrumClient) headersNotify(ctx context.Context) error {
 								if err != nil {
 									panic(err)
 								}
+								first := &wire.BlockHeader{}
+								err = first.Deserialize(bytes.NewReader(b))
+								if err != nil {
+									panic(err)
+								}
+								if h.Reorged(first, from) {
+									// the missing headers fork below our tip
+									err = ec.reorgHeaders(ctx, x.Height)
+									if err != nil {
+										fmt.Printf("cannot follow reorg to height %d: %v\n", x.Height, err)
+										continue
+									}
+									maybeTip = h.Tip()
+									continue
+								}
 								hdrsAppended, err := h.AppendHeaders(b)
 								if err != nil {
 									panic(err)
@@ -276,17 +313,17 @@ func (ec *BtcElectrumClient) headersNotify(ctx context.Context) error {
 								}
 
 								// update tip / local tip / wallet tip / notify listener
-								h.tip = x.Height
+								h.SetTip(x.Height)
 								maybeTip = x.Height
 								ec.updateWalletTip()
-								ec.tipChanged()
+								ec.tipChanged(false)
 
 								// verify added headers back from new tip
 								h.VerifyFromTip(int64(count+1), false)
 							}
 						}
 					} else {
-						fmt.Printf("Already got a header for height %d - possible reorg (unhandled)\n", x.Height)
+						fmt.Printf("Already got a header for height %d\n", x.Height)
 					}
 				}
 			}
@@ -297,12 +334,87 @@ func (ec *BtcElectrumClient) headersNotify(ctx context.Context) error {
 	return nil
 }
 
+// maxReorgDepth is the number of headers back from a reorged tip that are
+// fetched from the server to find where the new chain forks from ours
+const maxReorgDepth = 100
+
+// reorgHeaders replaces the stored headers orphaned by a reorg with the
+// server's chain up to newTip. The headers are fetched back to maxReorgDepth
+// and stored from the first that differs from ours. The wallet tip is updated
+// and subscribers are notified of a reorged tip.
+func (ec *BtcElectrumClient) reorgHeaders(ctx context.Context, newTip int64) error {
+	h := ec.clientHeaders
+	from := newTip - maxReorgDepth + 1
+	if from <= h.startPoint {
+		from = h.startPoint + 1
+	}
+	if from > newTip {
+		return errors.New("reorg below the stored headers")
+	}
+	hdrsRes, err := ec.GetNode().BlockHeaders(ctx, from, int(newTip-from+1))
+	if err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(hdrsRes.HexConcat)
+	if err != nil {
+		return err
+	}
+	count, err := h.BytesToNumHdrs(int64(len(b)))
+	if err != nil {
+		return err
+	}
+
+	// find the fork: the first server header that is not ours
+	fork := from + count
+	for i := int64(0); i < count; i++ {
+		hdr := &wire.BlockHeader{}
+		err := hdr.Deserialize(bytes.NewReader(b[i*HEADER_SIZE:]))
+		if err != nil {
+			return err
+		}
+		stored := ec.GetBlockHeader(from + i)
+		if stored == nil || stored.BlockHash() != hdr.BlockHash() {
+			fork = from + i
+			break
+		}
+	}
+	if fork == from && from > h.startPoint+1 {
+		return fmt.Errorf("reorg deeper than %d blocks", maxReorgDepth)
+	}
+	fmt.Printf("reorg from height %d - replacing headers %d..%d with %d..%d\n",
+		fork, fork, h.Tip(), fork, from+count-1)
+
+	err = h.Truncate(fork)
+	if err != nil {
+		return err
+	}
+	b = b[(fork-from)*HEADER_SIZE:]
+	if len(b) > 0 {
+		_, err = h.AppendHeaders(b)
+		if err != nil {
+			return err
+		}
+		err = h.Store(b, fork)
+		if err != nil {
+			return err
+		}
+	}
+	h.SetTip(from + count - 1)
+	err = h.VerifyFromTip(h.Tip()-fork+1, false)
+	if err != nil {
+		return err
+	}
+	ec.updateWalletTip()
+	ec.tipChanged(true)
+	return nil
+}
+
 // ElectrumClient interface
 
 // Tip returns the (local) block headers tip height and client headers sync status.
 func (ec *BtcElectrumClient) Tip() (int64, bool) {
 	h := ec.clientHeaders
-	return h.tip, h.synced
+	return h.Tip(), h.Synced()
 }
 
 // GetBlockHeader returns the client's block header for height. If out of range
@@ -328,11 +440,11 @@ func (ec *BtcElectrumClient) GetBlockHeaders(startHeight, count int64) ([]*wire.
 		// consider making a server call
 		return nil, errors.New("requested start height < start of stored blocks")
 	}
-	if startHeight > h.tip {
+	if startHeight > h.Tip() {
 		return nil, errors.New("requested start height > tip")
 	}
 	blkEndRange := startHeight + count
-	if blkEndRange > h.tip {
+	if blkEndRange > h.Tip() {
 		return nil, errors.New("requested range exceeds the tip")
 	}
 	var headers = make([]*wire.BlockHeader, 0, 3)
@@ -348,26 +460,25 @@ func (ec *BtcElectrumClient) GetHeaderForBlockHash(blkHash *chainhash.Hash) *wir
 	return ec.GetBlockHeader(height)
 }
 
-func (ec *BtcElectrumClient) RegisterTipChangeNotify() (<-chan int64, error) {
-	h := ec.clientHeaders
-	if !h.synced {
-		return nil, errors.New("client's header chain is not synced")
-	}
-	h.tipChange = make(chan int64, 1)
-	return h.tipChange, nil
+// SubscribeTipChanges returns a subscription to changes of the client headers
+// tip with a channel buffer of buffer tip changes. The synced tip is sent when
+// the headers are synced and each new tip as blocks arrive. Tip changes are
+// dropped if the channel buffer is full. Call Unsubscribe when done.
+func (ec *BtcElectrumClient) SubscribeTipChanges(buffer int) *client.TipSubscription {
+	return ec.clientHeaders.tipFeed.Subscribe(buffer)
 }
 
-func (ec *BtcElectrumClient) UnregisterTipChangeNotify() {
+// tipChanged publishes the client headers tip to the tip change subscribers
+func (ec *BtcElectrumClient) tipChanged(reorg bool) {
 	h := ec.clientHeaders
-	if h.tipChange != nil {
-		close(ec.clientHeaders.tipChange)
-		ec.clientHeaders.tipChange = nil
+	tc := &client.TipChange{
+		Height: h.Tip(),
+		Reorg:  reorg,
 	}
-}
-
-func (ec *BtcElectrumClient) tipChanged() {
-	h := ec.clientHeaders
-	if h.tipChange != nil {
-		h.tipChange <- h.tip
+	hdr := ec.GetBlockHeader(h.Tip())
+	if hdr != nil {
+		tc.Header = hdr
+		tc.Hash = hdr.BlockHash()
 	}
+	h.tipFeed.Publish(tc)
 }
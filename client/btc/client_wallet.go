@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -104,6 +105,170 @@ func (ec *BtcElectrumClient) Spend(
 	return changeIndex, rawTxHex, txidHex, nil
 }
 
+// SpendAll tries to create a new transaction to pay all confirmed and unfrozen
+// wallet coins to toAddress less the fee. It returns Tx & Txid as hex strings.
+// The wallet password is required in order to sign the tx.
+func (ec *BtcElectrumClient) SpendAll(
+	pw string,
+	toAddress string,
+	feeLevel wallet.FeeLevel) (string, string, error) {
+
+	address, err := btcutil.DecodeAddress(toAddress, ec.ClientConfig.Params)
+	if err != nil {
+		return "", "", err
+	}
+	info := &wallet.SpendInfo{
+		Outputs: []wallet.TransactionOutput{
+			{Address: address},
+		},
+		FeeLevel: feeLevel,
+		SendAll:  true,
+	}
+	res, err := ec.SpendTx(pw, info)
+	if err != nil {
+		return "", "", err
+	}
+	txidHex := res.Tx.TxHash().String()
+	b, err := serializeWireTx(res.Tx)
+	if err != nil {
+		return "", "", err
+	}
+	rawTxHex := hex.EncodeToString(b)
+	return rawTxHex, txidHex, nil
+}
+
+// PayURI pays a BIP21 payment URI like Spend. The URI must be for the client's
+// network and request an amount. Any label in the URI is set on the address
+// paid and any message on the transaction.
+// The wallet password is required in order to sign the tx.
+func (ec *BtcElectrumClient) PayURI(pw, uri string, feeLevel wallet.FeeLevel) (int, string, string, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return -1, "", "", ErrNoWallet
+	}
+	p, err := wallet.ParsePaymentURI(uri, ec.ClientConfig.Params)
+	if err != nil {
+		return -1, "", "", err
+	}
+	if p.Address == nil {
+		return -1, "", "", fmt.Errorf("%w: lightning payments are not supported", wallet.ErrBadPaymentURI)
+	}
+	if p.Amount <= 0 {
+		return -1, "", "", fmt.Errorf("%w: no amount", wallet.ErrBadPaymentURI)
+	}
+	changeIndex, rawTxHex, txidHex, err := ec.Spend(pw, p.Amount, p.Address.String(), feeLevel)
+	if err != nil {
+		return -1, "", "", err
+	}
+	labels := []wallet.Label{
+		{Type: wallet.LabelAddr, Ref: p.Address.String(), Label: p.Label},
+		{Type: wallet.LabelTx, Ref: txidHex, Label: p.Message},
+	}
+	for _, label := range labels {
+		if label.Label == "" {
+			continue
+		}
+		err = w.SetLabel(label)
+		if err != nil {
+			return -1, "", "", err
+		}
+	}
+	return changeIndex, rawTxHex, txidHex, nil
+}
+
+// SpendCoins is like Spend but with coin control. The outpoints in mustUse are
+// always spent and any other inputs needed are taken only from mayUse. If
+// mayUse is empty any spendable wallet coins may be used. Outpoints are in the
+// form "txid:index". Frozen, unconfirmed or unknown outpoints are rejected.
+// It returns the index of any change output or -1 if none, Tx & Txid as hex
+// strings and the outpoints actually spent.
+// The wallet password is required in order to sign the tx.
+func (ec *BtcElectrumClient) SpendCoins(
+	pw string,
+	amount int64,
+	toAddress string,
+	feeLevel wallet.FeeLevel,
+	mustUse, mayUse []string) (int, string, string, []string, error) {
+
+	address, err := btcutil.DecodeAddress(toAddress, ec.ClientConfig.Params)
+	if err != nil {
+		return -1, "", "", nil, err
+	}
+	info := &wallet.SpendInfo{
+		Outputs: []wallet.TransactionOutput{
+			{Address: address, Value: amount},
+		},
+		FeeLevel: feeLevel,
+	}
+	for _, s := range mustUse {
+		op, err := wallet.NewOutPointFromString(s)
+		if err != nil {
+			return -1, "", "", nil, err
+		}
+		info.MustUse = append(info.MustUse, *op)
+	}
+	for _, s := range mayUse {
+		op, err := wallet.NewOutPointFromString(s)
+		if err != nil {
+			return -1, "", "", nil, err
+		}
+		info.Coins = append(info.Coins, *op)
+	}
+	res, err := ec.SpendTx(pw, info)
+	if err != nil {
+		return -1, "", "", nil, err
+	}
+	txidHex := res.Tx.TxHash().String()
+	b, err := serializeWireTx(res.Tx)
+	if err != nil {
+		return -1, "", "", nil, err
+	}
+	rawTxHex := hex.EncodeToString(b)
+	var inputs []string
+	for _, op := range res.Inputs {
+		inputs = append(inputs, op.String())
+	}
+	return res.ChangeIndex, rawTxHex, txidHex, inputs, nil
+}
+
+// SpendTx tries to create a new transaction as described by info. See
+// wallet.SpendInfo for the spend options.
+// The wallet password is required in order to sign the tx.
+func (ec *BtcElectrumClient) SpendTx(pw string, info *wallet.SpendInfo) (*wallet.SpendResult, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	w.UpdateTip(ec.Tip())
+	return w.SpendTx(pw, info)
+}
+
+// TxHistory returns the wallet transaction history newest first. Each entry
+// has the tx direction, net amount, fee if known, confirmations, status,
+// counterpart addresses and block time. The filter selects by time or height
+// and pages the results; nil returns all.
+func (ec *BtcElectrumClient) TxHistory(filter *wallet.HistoryFilter) ([]wallet.TxHistoryEntry, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	w.UpdateTip(ec.Tip())
+	history, err := w.TxHistory()
+	if err != nil {
+		return nil, err
+	}
+	for i := range history {
+		if history[i].Height <= 0 {
+			continue
+		}
+		hdr := ec.GetBlockHeader(history[i].Height)
+		if hdr != nil {
+			history[i].BlockTime = hdr.Timestamp
+		}
+	}
+	return wallet.FilterHistory(history, filter), nil
+}
+
 // GetPrivKeyForAddress
 func (ec *BtcElectrumClient) GetPrivKeyForAddress(pw, addr string) (string, error) {
 	w := ec.GetWallet()
@@ -117,6 +282,36 @@ func (ec *BtcElectrumClient) GetPrivKeyForAddress(pw, addr string) (string, erro
 	return w.GetPrivKeyForAddress(pw, address)
 }
 
+// SignMessage signs message with the key of a wallet address to prove control
+// of the address without exposing the key. P2PKH, P2SH-P2WPKH & P2WPKH
+// addresses get a BIP137 signature and P2TR addresses a BIP322 simple
+// signature, base64 encoded.
+func (ec *BtcElectrumClient) SignMessage(pw, addr, message string) (string, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return "", ErrNoWallet
+	}
+	address, err := btcutil.DecodeAddress(addr, w.Params())
+	if err != nil {
+		return "", err
+	}
+	return w.SignMessage(pw, address, message, wallet.MessageFormatDefault)
+}
+
+// VerifyMessage returns true if signature is a valid BIP137 or BIP322 simple
+// signature of message by any address, not only a wallet address.
+func (ec *BtcElectrumClient) VerifyMessage(addr, message, signature string) (bool, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return false, ErrNoWallet
+	}
+	address, err := btcutil.DecodeAddress(addr, w.Params())
+	if err != nil {
+		return false, err
+	}
+	return w.VerifyMessage(address, message, signature)
+}
+
 func (ec *BtcElectrumClient) SignTx(pw string, txBytes []byte) ([]byte, error) {
 	w := ec.GetWallet()
 	if w == nil {
@@ -249,6 +444,7 @@ func (ec *BtcElectrumClient) Broadcast(ctx context.Context, rawTx []byte) (strin
 	if err != nil {
 		return "", err
 	}
+	ec.trackSend(txid)
 
 	// Subscribe for address status notification from ElectrumX for addresses
 	// paying back to our wallet. This will also add the containing tx to the
@@ -318,24 +514,47 @@ func (ec *BtcElectrumClient) ListFrozenUnspent() ([]wallet.Utxo, error) {
 	return w.ListFrozenUnspent()
 }
 
+// PaymentURI returns a BIP21 payment URI requesting amount satoshis, if not
+// zero, to a new receive address got as UnusedAddress.
+func (ec *BtcElectrumClient) PaymentURI(ctx context.Context, amount int64, label, message string) (string, error) {
+	addr, _, err := ec.UnusedAddress(ctx)
+	if err != nil {
+		return "", err
+	}
+	address, err := btcutil.DecodeAddress(addr, ec.ClientConfig.Params)
+	if err != nil {
+		return "", err
+	}
+	p := &wallet.PaymentURI{
+		Address: address,
+		Amount:  amount,
+		Label:   label,
+		Message: message,
+	}
+	return p.String(), nil
+}
+
 // UnusedAddress gets a new unused wallet receive address and subscribes for
-// ElectrumX address status notify events on the returned address.
-func (ec *BtcElectrumClient) UnusedAddress(ctx context.Context) (string, error) {
+// ElectrumX address status notify events on the returned address. Each call
+// hands out a different address; when all addresses within the gap limit have
+// been handed out the wallet's gap policy applies. It returns true if the
+// address is past the gap limit.
+func (ec *BtcElectrumClient) UnusedAddress(ctx context.Context) (string, bool, error) {
 	w := ec.GetWallet()
 	if w == nil {
-		return "", ErrNoWallet
+		return "", false, ErrNoWallet
 	}
 	node := ec.GetNode()
 	if node == nil {
-		return "", ErrNoNode
+		return "", false, ErrNoNode
 	}
-	address, err := w.GetUnusedAddress(wallet.RECEIVING)
+	address, pastGap, err := w.NewAddress(wallet.RECEIVING)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	payToAddrScript, err := txscript.PayToAddrScript(address)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	// wallet db
 	newSub := &wallet.Subscription{
@@ -347,19 +566,19 @@ func (ec *BtcElectrumClient) UnusedAddress(ctx context.Context) (string, error)
 	// insert or update
 	err = w.AddSubscription(newSub)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	// request notifications from node
 	res, err := node.SubscribeScripthashNotify(ctx, newSub.ElectrumScripthash)
 	if err != nil {
 		w.RemoveSubscription(newSub.PkScript)
-		return "", err
+		return "", false, err
 	}
 	if res == nil { // network error
 		w.RemoveSubscription(newSub.PkScript)
-		return "", errors.New("network: empty result")
+		return "", false, errors.New("network: empty result")
 	}
-	return address.String(), nil
+	return address.String(), pastGap, nil
 }
 
 // ChangeAddress gets a new unused wallet change address and subscribes for
@@ -457,17 +676,73 @@ func (ec *BtcElectrumClient) UnfreezeUTXO(txid string, out uint32) error {
 	return w.UnFreezeUTXO(op)
 }
 
+// SubscribeWalletEvents returns a subscription to the wallet's typed events:
+// txs received, confirmed, dropped or reorged, balance changes and frozen
+// coins. Events are dropped if the channel buffer is full. Call Unsubscribe
+// when done.
+func (ec *BtcElectrumClient) SubscribeWalletEvents(buffer int) (*wallet.EventSubscription, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	return w.SubscribeEvents(buffer), nil
+}
+
+// SetLabel sets a BIP329 label on a tx, address, output or other reference. An
+// empty label text removes the label.
+func (ec *BtcElectrumClient) SetLabel(label wallet.Label) error {
+	w := ec.GetWallet()
+	if w == nil {
+		return ErrNoWallet
+	}
+	return w.SetLabel(label)
+}
+
+func (ec *BtcElectrumClient) GetLabel(labelType wallet.LabelType, ref string) (*wallet.Label, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	return w.GetLabel(labelType, ref)
+}
+
+// ListLabels lists the labels of a type or all labels if labelType is empty.
+func (ec *BtcElectrumClient) ListLabels(labelType wallet.LabelType) ([]wallet.Label, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	return w.ListLabels(labelType)
+}
+
+// ImportLabels imports BIP329 JSON Lines labels from r.
+func (ec *BtcElectrumClient) ImportLabels(r io.Reader) (int, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return 0, ErrNoWallet
+	}
+	return w.ImportLabels(r)
+}
+
+// ExportLabels exports all labels to wr as BIP329 JSON Lines.
+func (ec *BtcElectrumClient) ExportLabels(wr io.Writer) error {
+	w := ec.GetWallet()
+	if w == nil {
+		return ErrNoWallet
+	}
+	return w.ExportLabels(wr)
+}
+
+// FeeRate returns the fee rate in sats/kB to confirm within confTarget blocks.
+// It uses the ElectrumX server's fee estimate or mempool fee histogram and
+// falls back to static values per network.
 func (ec *BtcElectrumClient) FeeRate(ctx context.Context, confTarget int64) (int64, error) {
-	// Maye we can use oracle like https://blockexplorer.one/bitcoin/mainnet (/testnet)
-	// https://rest.cryptoapis.io/v2/blockchain-data/bitcoin/testnet/mempool/fees
-	// I do not like this!
-	// node := ec.GetNode()
-	// if node != nil {
-	// 	feeRate, _ := node.EstimateFeeRate(ctx, confTarget)
-	// 	if feeRate != -1 {
-	// 		return feeRate, nil
-	// 	}
-	// }
+	if ec.feeEstimator != nil {
+		feeRate, err := ec.feeEstimator.EstimateFeeRate(ctx, confTarget)
+		if err == nil {
+			return feeRate * 1000, nil
+		}
+	}
 
 	// static
 	switch ec.ClientConfig.Params {
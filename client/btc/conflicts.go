@@ -0,0 +1,173 @@
+package btc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+)
+
+// Conflicts
+//
+// A wallet tx spending the same coins as a wallet tx already stored is found
+// by the wallet. A conflicting tx that pays nothing to the wallet, such as the
+// payer double spending an incoming payment back to themselves, never shows in
+// the wallet's address histories. Instead the wallet tx disappears from the
+// server. So when an unconfirmed wallet tx is no longer known to the server,
+// the histories of the scripts its inputs spend are searched for another tx
+// spending the same outpoint. If found the wallet tx is marked replaced by it.
+
+// checkUnconfirmed checks the unconfirmed wallet txs are still known to the
+// server. It is run every RebroadcastInterval.
+func (ec *BtcElectrumClient) checkUnconfirmed(ctx context.Context) {
+	ec.unconfirmedMtx.Lock()
+	defer ec.unconfirmedMtx.Unlock()
+	ec.checkPendingReceives(ctx, nil)
+	ec.checkPendingSends(ctx)
+}
+
+// checkUnconfirmedReceives checks the unconfirmed incoming wallet txs paying
+// scripthash that are not in its history, just fetched, are still known to
+// the server. It is run after each address status change.
+func (ec *BtcElectrumClient) checkUnconfirmedReceives(ctx context.Context, scripthash string, history electrumx.HistoryResult) {
+	inHistory := make(map[string]bool, len(history))
+	for _, h := range history {
+		inHistory[h.TxHash] = true
+	}
+	ec.unconfirmedMtx.Lock()
+	defer ec.unconfirmedMtx.Unlock()
+	ec.checkPendingReceives(ctx, func(tx *wire.MsgTx) bool {
+		if inHistory[tx.TxHash().String()] {
+			return false
+		}
+		for _, out := range tx.TxOut {
+			if pkScriptToElectrumScripthash(out.PkScript) == scripthash {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// checkPendingReceives marks replaced the unconfirmed incoming wallet txs the
+// server no longer knows because a conflicting tx spent their inputs. Only
+// the txs for which check returns true are looked up; a nil check looks up
+// all.
+func (ec *BtcElectrumClient) checkPendingReceives(ctx context.Context, check func(tx *wire.MsgTx) bool) {
+	w := ec.GetWallet()
+	node := ec.GetNode()
+	if w == nil || node == nil {
+		return
+	}
+	txns, err := w.ListTransactions()
+	if err != nil {
+		fmt.Printf("cannot list wallet transactions: %v\n", err)
+		return
+	}
+	for _, txn := range txns {
+		// sends are checked by checkPendingSends
+		if txn.Height != 0 || txn.Value < 0 {
+			continue
+		}
+		if check != nil {
+			tx, err := newWireTx(txn.Bytes, true)
+			if err != nil || !check(tx) {
+				continue
+			}
+		}
+		_, err := node.GetRawTransaction(ctx, txn.Txid)
+		switch {
+		case err == nil:
+		case isTxNotFound(err):
+			_, err = ec.replaceIfConflicted(ctx, txn.Txid, txn.Bytes)
+			if err != nil {
+				fmt.Printf("cannot check unconfirmed receive %s: %v\n", txn.Txid, err)
+				return
+			}
+		default:
+			// network or server - try again next time
+			fmt.Printf("cannot check unconfirmed receive %s: %v\n", txn.Txid, err)
+			return
+		}
+	}
+}
+
+// isTxNotFound returns true if err is the server's error for a tx it does not
+// know. Other server errors say nothing of the tx.
+func isTxNotFound(err error) bool {
+	var rpcErr *electrumx.RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	return strings.Contains(msg, "no such mempool or blockchain transaction") ||
+		strings.Contains(msg, "not found")
+}
+
+// replaceIfConflicted looks for a conflict of the wallet tx txid, raw tx
+// bytes rawTx, that the server no longer knows. If one is found the wallet tx
+// is marked replaced by it and true returned.
+func (ec *BtcElectrumClient) replaceIfConflicted(ctx context.Context, txid string, rawTx []byte) (bool, error) {
+	tx, err := newWireTx(rawTx, true)
+	if err != nil {
+		return false, err
+	}
+	conflict, err := ec.findConflict(ctx, tx)
+	if err != nil || conflict == "" {
+		return false, err
+	}
+	fmt.Printf("unconfirmed transaction %s was replaced by %s\n", txid, conflict)
+	err = ec.GetWallet().SetTxReplaced(txid, conflict)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// findConflict returns the txid of a tx known to the server that spends an
+// input of tx, or "" if there is none. The histories of the scripts of the
+// outputs tx spends are searched.
+func (ec *BtcElectrumClient) findConflict(ctx context.Context, tx *wire.MsgTx) (string, error) {
+	node := ec.GetNode()
+	if node == nil {
+		return "", ErrNoNode
+	}
+	txid := tx.TxHash().String()
+	for _, in := range tx.TxIn {
+		prev := in.PreviousOutPoint
+		parent, _, err := ec.GetRawTransactionFromNode(ctx, prev.Hash.String())
+		if isTxNotFound(err) {
+			// the parent is gone too; its own conflict is not ours
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if int(prev.Index) >= len(parent.TxOut) {
+			continue
+		}
+		scripthash := pkScriptToElectrumScripthash(parent.TxOut[prev.Index].PkScript)
+		history, err := node.GetHistory(ctx, scripthash)
+		if err != nil {
+			return "", err
+		}
+		for _, h := range history {
+			if h.TxHash == txid || h.TxHash == prev.Hash.String() {
+				continue
+			}
+			other, _, err := ec.GetRawTransactionFromNode(ctx, h.TxHash)
+			if err != nil {
+				return "", err
+			}
+			for _, otherIn := range other.TxIn {
+				if otherIn.PreviousOutPoint == prev {
+					return h.TxHash, nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,152 @@
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// historyNode serves the txs and script histories it has
+type historyNode struct {
+	electrumx.ElectrumXNode
+	txs     map[string]string
+	history map[string]electrumx.HistoryResult
+	// returned for all txs if set
+	err error
+}
+
+func (n *historyNode) GetRawTransaction(_ context.Context, txid string) (string, error) {
+	if n.err != nil {
+		return "", n.err
+	}
+	tx, ok := n.txs[txid]
+	if !ok {
+		return "", &electrumx.RPCError{Code: 2, Message: "No such mempool or blockchain transaction"}
+	}
+	return tx, nil
+}
+
+func (n *historyNode) GetHistory(_ context.Context, scripthash string) (electrumx.HistoryResult, error) {
+	return n.history[scripthash], nil
+}
+
+func (n *historyNode) add(t *testing.T, tx *wire.MsgTx, height int64) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	n.txs[tx.TxHash().String()] = hex.EncodeToString(buf.Bytes())
+	for _, out := range tx.TxOut {
+		scripthash := pkScriptToElectrumScripthash(out.PkScript)
+		n.history[scripthash] = append(n.history[scripthash], electrumx.History{TxHash: tx.TxHash().String(), Height: height})
+	}
+}
+
+func TestDoubleSpentReceive(t *testing.T) {
+	cfg, err := makeBitcoinRegtestTestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rmTestDir()
+	cfg.Testing = true
+	ec := NewBtcElectrumClient(cfg).(*BtcElectrumClient)
+	err = ec.CreateWallet("abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := ec.GetWallet()
+	defer w.Close()
+	node := &historyNode{
+		txs:     make(map[string]string),
+		history: make(map[string]electrumx.HistoryResult),
+	}
+	ec.Node = node
+
+	// the payer's coin
+	payerScript, err := hex.DecodeString("0014027211433701197f4b7146f668885312c9a8b53b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := wire.NewMsgTx(wire.TxVersion)
+	parent.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	parent.AddTxOut(wire.NewTxOut(2000000, payerScript))
+	node.add(t, parent, 50)
+	coin := wire.OutPoint{Hash: parent.TxHash()}
+
+	// an unconfirmed payment to the wallet
+	address, err := w.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment := wire.NewMsgTx(wire.TxVersion)
+	payment.AddTxIn(wire.NewTxIn(&coin, nil, nil))
+	payment.AddTxOut(wire.NewTxOut(1000000, script))
+	if err := w.AddTransaction(payment, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	paymentTxid := payment.TxHash().String()
+	node.add(t, payment, 0)
+
+	// still known
+	ec.checkUnconfirmed(context.Background())
+	txn, err := w.GetTransaction(paymentTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusPending {
+		t.Fatalf("expected pending - got %s", txn.Status)
+	}
+
+	// the payer spends the coin back to themselves
+	delete(node.txs, paymentTxid)
+	node.history = make(map[string]electrumx.HistoryResult)
+	node.add(t, parent, 50)
+	conflict := wire.NewMsgTx(wire.TxVersion)
+	conflict.AddTxIn(wire.NewTxIn(&coin, nil, nil))
+	conflict.AddTxOut(wire.NewTxOut(1990000, payerScript))
+	node.add(t, conflict, 0)
+
+	// notifications only check the receives that left the notified history
+	ctx := context.Background()
+	ec.checkUnconfirmedReceives(ctx, pkScriptToElectrumScripthash(payerScript), node.history[pkScriptToElectrumScripthash(payerScript)])
+	ec.checkUnconfirmedReceives(ctx, pkScriptToElectrumScripthash(script), electrumx.HistoryResult{{TxHash: paymentTxid}})
+	// a server error is not a tx the server does not know
+	node.err = &electrumx.RPCError{Code: 1, Message: "excessive resource usage"}
+	ec.checkUnconfirmed(ctx)
+	node.err = nil
+	txn, err = w.GetTransaction(paymentTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusPending {
+		t.Fatalf("expected pending - got %s", txn.Status)
+	}
+
+	ec.checkUnconfirmedReceives(ctx, pkScriptToElectrumScripthash(script), node.history[pkScriptToElectrumScripthash(script)])
+	txn, err = w.GetTransaction(paymentTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusReplaced || txn.ReplacedBy != conflict.TxHash().String() {
+		t.Fatalf("expected replaced by %s - got %s %s", conflict.TxHash(), txn.Status, txn.ReplacedBy)
+	}
+	_, unconfirmed, _, err := w.Balance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unconfirmed != 0 {
+		t.Fatalf("expected no unconfirmed balance - got %d", unconfirmed)
+	}
+}
@@ -0,0 +1,120 @@
+package btc
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// DiscoverAccounts scans the standard derivation paths of the wallet seed for
+// history, following the BIP44 account discovery rules. For each of purposes
+// 44, 49, 84 & 86 successive accounts are scanned until one has no history.
+// The accounts found with history are enabled in the wallet, their txs added
+// and the accounts returned.
+//
+// The wallet's own m/44'/0'/0' p2wpkh chains are scanned by RescanWallet.
+func (ec *BtcElectrumClient) DiscoverAccounts(ctx context.Context, pw string) ([]wallet.Account, error) {
+	w := ec.GetWallet()
+	if w == nil {
+		return nil, ErrNoWallet
+	}
+	node := ec.GetNode()
+	if node == nil {
+		return nil, ErrNoNode
+	}
+	coinType := w.Params().HDCoinType
+
+	var found []wallet.Account
+	var histories []electrumx.HistoryResult
+	for _, purpose := range wallet.DiscoveryPurposes {
+		for index := uint32(0); ; index++ {
+			account, err := wallet.NewAccount(purpose, coinType, index)
+			if err != nil {
+				return nil, err
+			}
+			for _, keyPurpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+				last, chainHistories, err := ec.scanAccountChain(ctx, w, account, keyPurpose)
+				if err != nil {
+					return nil, err
+				}
+				histories = append(histories, chainHistories...)
+				if keyPurpose == wallet.EXTERNAL {
+					account.LastReceive = last
+				} else {
+					account.LastChange = last
+				}
+			}
+			if !account.HasHistory() {
+				// stop at the first account with no history
+				break
+			}
+			fmt.Printf("found history for account %s (%s)\n", account, account.AddressType)
+			found = append(found, *account)
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	err := w.EnableAccounts(pw, found)
+	if err != nil {
+		return nil, err
+	}
+	// the wallet knows the account addresses now
+	for _, history := range histories {
+		ec.addTxHistoryToWallet(ctx, history)
+	}
+	return found, nil
+}
+
+// Addresses in a row with no history ending an account chain scan. This is
+// the BIP44 gap limit, used unless the wallet's gap limit is larger.
+const discoveryGapLimit = 20
+
+// scanAccountChain gets the history of the addresses of an account chain
+// until a gap limit of addresses in a row have no history. Addresses with
+// history are added to the wallet subscriptions. It returns the highest index
+// with history or -1 if none and the histories found.
+func (ec *BtcElectrumClient) scanAccountChain(ctx context.Context, w wallet.ElectrumWallet, account *wallet.Account, purpose wallet.KeyPurpose) (int, []electrumx.HistoryResult, error) {
+	node := ec.GetNode()
+	gapLimit := w.GapLimit()
+	if gapLimit < discoveryGapLimit {
+		gapLimit = discoveryGapLimit
+	}
+	last := -1
+	var histories []electrumx.HistoryResult
+	for index := 0; index <= last+gapLimit; index++ {
+		address, err := w.AccountAddress(account, purpose, index)
+		if err != nil {
+			return -1, nil, err
+		}
+		scripthash, err := addressToElectrumScripthash(address)
+		if err != nil {
+			return -1, nil, err
+		}
+		history, err := node.GetHistory(ctx, scripthash)
+		if err != nil {
+			return -1, nil, err
+		}
+		if len(history) == 0 {
+			continue
+		}
+		last = index
+		histories = append(histories, history)
+		pkScript, err := w.AddressToScript(address)
+		if err != nil {
+			return -1, nil, err
+		}
+		err = w.AddSubscription(&wallet.Subscription{
+			PkScript:           hex.EncodeToString(pkScript),
+			ElectrumScripthash: scripthash,
+			Address:            address.String(),
+		})
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+	return last, histories, nil
+}
@@ -0,0 +1,64 @@
+package btc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestDiscoverAccounts(t *testing.T) {
+	cfg, err := makeBitcoinRegtestTestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rmTestDir()
+	cfg.Testing = true
+	ec := NewBtcElectrumClient(cfg).(*BtcElectrumClient)
+	err = ec.CreateWallet("abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := ec.GetWallet()
+	defer w.Close()
+	node := &historyNode{
+		txs:     make(map[string]string),
+		history: make(map[string]electrumx.HistoryResult),
+	}
+	ec.Node = node
+
+	// a payment to a taproot account address past the wallet's gap limit
+	// but within the discovery gap limit
+	account, err := wallet.NewAccount(wallet.PurposeBIP86, w.Params().HDCoinType, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := w.GapLimit() + 5
+	address, err := w.AccountAddress(account, wallet.EXTERNAL, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000000, script))
+	node.add(t, tx, 50)
+
+	found, err := ec.DiscoverAccounts(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Purpose != wallet.PurposeBIP86 || found[0].LastReceive != index {
+		t.Fatalf("expected the taproot account with receive index %d - got %v", index, found)
+	}
+	if has, _ := w.HasTransaction(tx.TxHash().String()); !has {
+		t.Fatal("expected the account's tx in the wallet")
+	}
+}
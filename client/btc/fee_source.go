@@ -0,0 +1,36 @@
+package btc
+
+import (
+	"context"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// nodeFeeSource satisfies wallet.FeeSource using the client's current
+// ElectrumX node. The node is looked up on each call as it is not running
+// until the client is started and may be replaced.
+type nodeFeeSource struct {
+	ec *BtcElectrumClient
+}
+
+func (s *nodeFeeSource) EstimateFeeRate(ctx context.Context, confTarget int64) (int64, error) {
+	node := s.ec.GetNode()
+	if node == nil {
+		return 0, ErrNoNode
+	}
+	return node.EstimateFeeRate(ctx, confTarget)
+}
+
+func (s *nodeFeeSource) FeeHistogram(ctx context.Context) ([][2]float64, error) {
+	node := s.ec.GetNode()
+	if node == nil {
+		return nil, ErrNoNode
+	}
+	histogram, err := node.GetFeeHistogram(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return histogram, nil
+}
+
+var _ = wallet.FeeSource(&nodeFeeSource{})
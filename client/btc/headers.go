@@ -13,7 +13,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -22,25 +26,31 @@ import (
 )
 
 const (
-	HEADER_SIZE           = 80
-	HEADER_FILE_NAME      = "blockchain_headers"
-	ELECTRUM_MAGIC_NUMHDR = 2016
+	HEADER_SIZE            = 80
+	HEADER_FILE_NAME       = "blockchain_headers"
+	HEADER_START_FILE_NAME = "blockchain_headers_start"
+	ELECTRUM_MAGIC_NUMHDR  = 2016
 )
 
 type Headers struct {
 	// blockchain headers file to persist headers we know
 	hdrFilePath string
-	// chain parameters for genesis and checkpoint. We always use the latest
-	// checkpoint height to start the file. For regtest that is genesis.
+	// height of the first header in the headers file
+	startFilePath string
+	// chain parameters for genesis and checkpoint. We use the latest
+	// checkpoint height to start the file unless a wallet birthday height is
+	// configured. For regtest that is genesis.
 	net *chaincfg.Params
 	// decoded headers stored by height
 	hdrsMtx    sync.RWMutex
 	hdrs       map[int64]*wire.BlockHeader
 	blkHdrs    map[chainhash.Hash]int64
 	startPoint int64
-	tip        int64
-	synced     bool
-	tipChange  chan int64
+	// tip and synced are read by many goroutines
+	tip    atomic.Int64
+	synced atomic.Bool
+	// subscribers to tip changes
+	tipFeed *client.TipFeed
 }
 
 func NewHeaders(cfg *client.ClientConfig) *Headers {
@@ -49,22 +59,50 @@ func NewHeaders(cfg *client.ClientConfig) *Headers {
 	hdrsMap := make(map[int64]*wire.BlockHeader, hdrsMapInitSize)
 	bhdrsMap := make(map[chainhash.Hash]int64, hdrsMapInitSize)
 	hdrs := Headers{
-		hdrFilePath: filePath,
-		net:         cfg.Params,
-		hdrs:        hdrsMap,
-		blkHdrs:     bhdrsMap,
-		startPoint:  getStartPointHeight(cfg),
-		tip:         0,
-		synced:      false,
-		tipChange:   nil,
+		hdrFilePath:   filePath,
+		startFilePath: filepath.Join(cfg.DataDir, HEADER_START_FILE_NAME),
+		net:           cfg.Params,
+		hdrs:          hdrsMap,
+		blkHdrs:       bhdrsMap,
+		startPoint:    getStartPointHeight(cfg),
+		tipFeed:       client.NewTipFeed(),
 	}
 	return &hdrs
 }
 
-// stored Headers start from here
+// Tip returns the height of the stored tip header
+func (h *Headers) Tip() int64 {
+	return h.tip.Load()
+}
+
+// SetTip sets the height of the stored tip header
+func (h *Headers) SetTip(height int64) {
+	h.tip.Store(height)
+}
+
+// Synced returns true once the headers are synced with the server
+func (h *Headers) Synced() bool {
+	return h.synced.Load()
+}
+
+// SetSynced sets the headers sync status
+func (h *Headers) SetSynced(synced bool) {
+	h.synced.Store(synced)
+}
+
+// stored Headers start from here, the start of the retarget period of the
+// wallet birthday if configured
 func getStartPointHeight(cfg *client.ClientConfig) int64 {
+	if cfg.BirthdayHeight > 0 {
+		return cfg.BirthdayHeight - cfg.BirthdayHeight%ELECTRUM_MAGIC_NUMHDR
+	}
+	return getCheckpointHeight(cfg.Params)
+}
+
+// latest checkpoint height
+func getCheckpointHeight(params *chaincfg.Params) int64 {
 	var startAtHeight int64 = 0
-	switch cfg.Params {
+	switch params {
 	case &chaincfg.RegressionNetParams:
 		startAtHeight = 0
 	case &chaincfg.TestNet3Params:
@@ -75,6 +113,48 @@ func getStartPointHeight(cfg *client.ClientConfig) int64 {
 	return startAtHeight
 }
 
+// syncStartPoint keeps the start point of a headers file. If the file has
+// headers the start point is read from the 'blockchain_headers_start' file, or
+// is the checkpoint for a file made before there was one. Otherwise the
+// configured start point is written for a new file.
+func (h *Headers) syncStartPoint(numHeaders int64) error {
+	b, err := os.ReadFile(h.startFilePath)
+	switch {
+	case err == nil && numHeaders > 0:
+		start, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+		if err != nil {
+			return err
+		}
+		h.startPoint = start
+		return nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return err
+	case err != nil && numHeaders > 0:
+		h.startPoint = getCheckpointHeight(h.net)
+	}
+	return os.WriteFile(h.startFilePath, []byte(strconv.FormatInt(h.startPoint, 10)), 0664)
+}
+
+// HeightForTime returns the height of the first stored header with a time
+// stamp at or after t. It returns false if t is before the first stored
+// header. If t is after the tip the tip is returned.
+func (h *Headers) HeightForTime(t time.Time) (int64, bool) {
+	h.hdrsMtx.RLock()
+	defer h.hdrsMtx.RUnlock()
+	first, ok := h.hdrs[h.startPoint]
+	if !ok || t.Before(first.Timestamp) {
+		return 0, false
+	}
+	tip := h.Tip()
+	for height := h.startPoint; height <= tip; height++ {
+		hdr, ok := h.hdrs[height]
+		if ok && !hdr.Timestamp.Before(t) {
+			return height, true
+		}
+	}
+	return tip, true
+}
+
 // Get the 'blockchain_headers' file size. Error is returned unexamined as
 // we assume the file exists and ENOENT will not be valid.
 func (h *Headers) StatFileSize() (int64, error) {
@@ -184,15 +264,62 @@ func (h *Headers) Store(b []byte, startHeight int64) error {
 	return nil
 }
 
+// Truncate removes the headers from height up to the tip from the
+// 'blockchain_headers' file and the headers maps. The headers were orphaned by
+// a reorg. The tip is set to the height below.
+func (h *Headers) Truncate(height int64) error {
+	if height <= h.startPoint {
+		return errors.New("cannot truncate headers at or below the start point")
+	}
+	tip := h.Tip()
+	if height > tip {
+		return nil
+	}
+	err := os.Truncate(h.hdrFilePath, (height-h.startPoint)*HEADER_SIZE)
+	if err != nil {
+		return err
+	}
+	h.hdrsMtx.Lock()
+	defer h.hdrsMtx.Unlock()
+	for at := height; at <= tip; at++ {
+		hdr, ok := h.hdrs[at]
+		if !ok {
+			continue
+		}
+		delete(h.blkHdrs, hdr.BlockHash())
+		delete(h.hdrs, at)
+	}
+	h.SetTip(height - 1)
+	return nil
+}
+
+// Reorged returns true if hdr at height is not on the stored chain: it
+// replaces a stored header or does not connect to the stored tip.
+func (h *Headers) Reorged(hdr *wire.BlockHeader, height int64) bool {
+	h.hdrsMtx.RLock()
+	defer h.hdrsMtx.RUnlock()
+	tip := h.Tip()
+	switch {
+	case height <= tip:
+		stored, ok := h.hdrs[height]
+		return ok && stored.BlockHash() != hdr.BlockHash()
+	case height == tip+1:
+		tipHdr, ok := h.hdrs[tip]
+		return ok && tipHdr.BlockHash() != hdr.PrevBlock
+	}
+	return false
+}
+
 // Verify headers prev hash back from tip. If all is true depth is ignored
 // and the whole chain is verified
 func (h *Headers) VerifyFromTip(depth int64, all bool) error {
-	downTo := h.tip - depth
+	tip := h.Tip()
+	downTo := tip - depth
 	if downTo < 0 || all {
 		downTo = h.startPoint
 	}
 	var height int64
-	for height = h.tip; height > downTo; height-- {
+	for height = tip; height > downTo; height-- {
 		thisHdr := h.hdrs[height]
 		prevHdr := h.hdrs[height-1]
 		prevHdrBlkHash := prevHdr.BlockHash()
@@ -226,8 +353,9 @@ func (h *Headers) DumpAt(height int64) {
 }
 
 func (h *Headers) DumpAll() {
+	tip := h.Tip()
 	var k int64
-	for k = 0; k <= h.tip; k++ {
+	for k = 0; k <= tip; k++ {
 		h.DumpAt(k)
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -106,8 +107,6 @@ func TestAppendHeaders(t *testing.T) {
 		net:         &chaincfg.RegressionNetParams,
 		hdrs:        make(map[int64]*wire.BlockHeader),
 		blkHdrs:     make(map[chainhash.Hash]int64),
-		tip:         0,
-		synced:      false,
 	}
 
 	var totalHdrs int64 = 0
@@ -155,15 +154,15 @@ func TestAppendHeaders(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	h.tip = maybeTip
+	h.SetTip(maybeTip)
 
 	// verify chain
-	fmt.Println("verifying back from tip at height", h.tip)
+	fmt.Println("verifying back from tip at height", h.Tip())
 	err = h.VerifyAll()
 	if err != nil {
 		log.Fatal(err)
 	}
-	h.synced = true
+	h.SetSynced(true)
 }
 
 func TestReadStoreHeaderFile(t *testing.T) {
@@ -196,12 +195,12 @@ func TestReadStoreHeaderFile(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	h.tip = numHdrs - 1
+	h.SetTip(numHdrs - 1)
 	fmt.Printf("stored %d headers into hdrs map at height %d\n", numHdrs, 0)
 
 	// verify chain
 	var height int64
-	for height = h.tip; height > 0; height-- {
+	for height = h.Tip(); height > 0; height-- {
 		thisHdr := h.hdrs[height]
 		prevHdr := h.hdrs[height-1]
 		prevHdrBlkHash := prevHdr.BlockHash()
@@ -308,7 +307,7 @@ func TestMapIter(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	h.tip = numHeaders - 1
+	h.SetTip(numHeaders - 1)
 	h.DumpAll()
 }
 
@@ -324,9 +323,9 @@ func TestStoreHashes(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	h.tip = numHeaders - 1
+	h.SetTip(numHeaders - 1)
 	var i int64
-	for i = 0; i <= h.tip; i++ {
+	for i = 0; i <= h.Tip(); i++ {
 		hdr := h.hdrs[i]
 		if hdr == nil {
 			log.Fatalf("nil header returned from map at %d", i)
@@ -338,3 +337,117 @@ func TestStoreHashes(t *testing.T) {
 		}
 	}
 }
+
+func TestHeadersStartPoint(t *testing.T) {
+	cfg := client.NewDefaultConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.BirthdayHeight = 830000
+	h := NewHeaders(cfg)
+	// start of the birthday's retarget period
+	if h.startPoint != 828576 {
+		t.Fatalf("expected start point 828576 - got %d", h.startPoint)
+	}
+	// a new file keeps the start point
+	if err := h.syncStartPoint(0); err != nil {
+		t.Fatal(err)
+	}
+	cfg.BirthdayHeight = 0
+	h = NewHeaders(cfg)
+	if err := h.syncStartPoint(10); err != nil {
+		t.Fatal(err)
+	}
+	if h.startPoint != 828576 {
+		t.Fatalf("expected stored start point 828576 - got %d", h.startPoint)
+	}
+	// a file from before the start file starts at the checkpoint
+	if err := os.Remove(h.startFilePath); err != nil {
+		t.Fatal(err)
+	}
+	cfg.BirthdayHeight = 830000
+	h = NewHeaders(cfg)
+	if err := h.syncStartPoint(10); err != nil {
+		t.Fatal(err)
+	}
+	if h.startPoint != getCheckpointHeight(cfg.Params) {
+		t.Fatalf("expected checkpoint start point - got %d", h.startPoint)
+	}
+}
+
+func TestHeightForTime(t *testing.T) {
+	cfg := client.NewDefaultConfig()
+	cfg.Params = &chaincfg.RegressionNetParams
+	cfg.DataDir = t.TempDir()
+	h := NewHeaders(cfg)
+	err := h.Store(hdrFileReg, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetTip(int64(len(hdrFileReg)/HEADER_SIZE) - 1)
+
+	genesis := h.hdrs[0].Timestamp
+	if _, ok := h.HeightForTime(genesis.Add(-time.Hour)); ok {
+		t.Fatal("expected no height before the first header")
+	}
+	height, ok := h.HeightForTime(h.hdrs[2].Timestamp)
+	if !ok || height != 2 {
+		t.Fatalf("expected height 2 - got %d", height)
+	}
+	height, ok = h.HeightForTime(h.hdrs[h.Tip()].Timestamp.Add(time.Hour))
+	if !ok || height != h.Tip() {
+		t.Fatalf("expected the tip - got %d", height)
+	}
+}
+
+func TestTruncateReorged(t *testing.T) {
+	cfg := client.NewDefaultConfig()
+	cfg.Params = &chaincfg.RegressionNetParams
+	cfg.DataDir = t.TempDir()
+	h := NewHeaders(cfg)
+	if _, err := h.AppendHeaders(hdrFileReg); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Store(hdrFileReg, 0); err != nil {
+		t.Fatal(err)
+	}
+	h.SetTip(int64(len(hdrFileReg)/HEADER_SIZE) - 1)
+	tip := h.Tip()
+
+	// the next header on our chain
+	next := &wire.BlockHeader{PrevBlock: h.hdrs[tip].BlockHash()}
+	if h.Reorged(next, tip+1) {
+		t.Fatal("expected the next header to connect")
+	}
+	if h.Reorged(h.hdrs[tip], tip) {
+		t.Fatal("expected our tip not to be reorged")
+	}
+	// a competing tip
+	other := &wire.BlockHeader{PrevBlock: h.hdrs[tip-1].BlockHash(), Nonce: 1}
+	if !h.Reorged(other, tip) {
+		t.Fatal("expected a competing tip to be reorged")
+	}
+	// a header not built on our tip
+	if !h.Reorged(&wire.BlockHeader{PrevBlock: other.BlockHash()}, tip+1) {
+		t.Fatal("expected a header on another chain to be reorged")
+	}
+
+	orphan := h.hdrs[tip].BlockHash()
+	if err := h.Truncate(tip); err != nil {
+		t.Fatal(err)
+	}
+	if h.Tip() != tip-1 || h.hdrs[tip] != nil {
+		t.Fatalf("expected tip %d - got %d", tip-1, h.Tip())
+	}
+	if _, ok := h.blkHdrs[orphan]; ok {
+		t.Fatal("expected the orphaned header hash to be removed")
+	}
+	size, err := h.StatFileSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != tip*HEADER_SIZE {
+		t.Fatalf("expected file size %d - got %d", tip*HEADER_SIZE, size)
+	}
+	if err := h.Truncate(0); err == nil {
+		t.Fatal("expected an error truncating the start point")
+	}
+}
@@ -0,0 +1,143 @@
+package btc
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dev-warrior777/go-electrum-client/client"
+)
+
+// Rebroadcast
+//
+// The client tracks the unconfirmed wallet txs that spend its coins. Every
+// RebroadcastInterval each is looked up on ElectrumX with
+// 'blockchain.transaction.get' and one the server does not know, evicted from
+// its mempool, is broadcast again unless a conflicting tx replaced it. A send
+// still unconfirmed StuckBlocks blocks after it was first seen is marked stuck
+// in the wallet so callers can bump its fee.
+
+// pendingSends are the unconfirmed sends of the wallet being tracked
+type pendingSends struct {
+	mtx sync.Mutex
+	// first block height a send could have been mined at keyed on txid
+	seenHeight map[string]int64
+}
+
+// trackSend starts tracking a tx just broadcast. It can be mined from the next
+// block.
+func (ec *BtcElectrumClient) trackSend(txid string) {
+	tip, _ := ec.Tip()
+	ps := &ec.pendingSends
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	if ps.seenHeight == nil {
+		ps.seenHeight = make(map[string]int64)
+	}
+	ps.seenHeight[txid] = tip + 1
+}
+
+// monitorPendingSends checks the unconfirmed sends every RebroadcastInterval
+// until ctx is done
+func (ec *BtcElectrumClient) monitorPendingSends(ctx context.Context) {
+	interval := ec.GetConfig().RebroadcastInterval
+	if interval <= 0 {
+		interval = client.DefaultRebroadcastInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println("monitorPendingSends thread exit")
+				return
+			case <-ticker.C:
+				ec.checkUnconfirmed(ctx)
+			}
+		}
+	}()
+}
+
+// checkPendingSends rebroadcasts the unconfirmed wallet sends that the server
+// no longer knows, or marks them replaced by a conflict, and marks stuck those
+// that waited StuckBlocks blocks. Sends that were mined or dropped are no
+// longer tracked.
+func (ec *BtcElectrumClient) checkPendingSends(ctx context.Context) {
+	w := ec.GetWallet()
+	node := ec.GetNode()
+	if w == nil || node == nil {
+		return
+	}
+	tip, synced := ec.Tip()
+	if !synced {
+		return
+	}
+	stuckBlocks := ec.GetConfig().StuckBlocks
+	if stuckBlocks <= 0 {
+		stuckBlocks = client.DefaultStuckBlocks
+	}
+	txns, err := w.ListTransactions()
+	if err != nil {
+		fmt.Printf("cannot list wallet transactions: %v\n", err)
+		return
+	}
+
+	ps := &ec.pendingSends
+	ps.mtx.Lock()
+	if ps.seenHeight == nil {
+		ps.seenHeight = make(map[string]int64)
+	}
+	pending := make(map[string]int64)
+	rawTxs := make(map[string][]byte)
+	for _, txn := range txns {
+		// a send spends wallet coins
+		if txn.Height != 0 || txn.Value >= 0 || txn.WatchOnly {
+			continue
+		}
+		seen, ok := ps.seenHeight[txn.Txid]
+		if !ok {
+			// first seen before this run of the client
+			seen, ok = ec.clientHeaders.HeightForTime(txn.Timestamp)
+			if !ok {
+				seen = tip
+			}
+		}
+		pending[txn.Txid] = seen
+		rawTxs[txn.Txid] = txn.Bytes
+	}
+	ps.seenHeight = pending
+	ps.mtx.Unlock()
+
+	for txid, seen := range pending {
+		_, err := node.GetRawTransaction(ctx, txid)
+		switch {
+		case err == nil:
+		case isTxNotFound(err):
+			// the server does not have it
+			replaced, err := ec.replaceIfConflicted(ctx, txid, rawTxs[txid])
+			if err != nil {
+				fmt.Printf("cannot check unconfirmed send %s: %v\n", txid, err)
+				return
+			}
+			if replaced {
+				continue
+			}
+			fmt.Printf("unconfirmed send %s was evicted - rebroadcasting\n", txid)
+			_, err = node.Broadcast(ctx, hex.EncodeToString(rawTxs[txid]))
+			if err != nil {
+				fmt.Printf("cannot rebroadcast %s: %v\n", txid, err)
+			}
+		default:
+			// network or server - try again next time
+			fmt.Printf("cannot check unconfirmed send %s: %v\n", txid, err)
+			return
+		}
+		err = w.SetTxStuck(txid, tip-seen+1 >= stuckBlocks)
+		if err != nil {
+			fmt.Printf("cannot set stuck status of %s: %v\n", txid, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package btc
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// mempoolNode knows the txs broadcast to it
+type mempoolNode struct {
+	electrumx.ElectrumXNode
+	mtx        sync.Mutex
+	known      map[string]bool
+	broadcasts int
+	// returned for all txs if set
+	err error
+}
+
+func (n *mempoolNode) GetRawTransaction(_ context.Context, txid string) (string, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.err != nil {
+		return "", n.err
+	}
+	if !n.known[txid] {
+		return "", &electrumx.RPCError{Code: 2, Message: "No such mempool or blockchain transaction"}
+	}
+	return "", nil
+}
+
+func (n *mempoolNode) Broadcast(_ context.Context, rawTx string) (string, error) {
+	b, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return "", err
+	}
+	tx, err := newWireTx(b, true)
+	if err != nil {
+		return "", err
+	}
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	txid := tx.TxHash().String()
+	n.known[txid] = true
+	n.broadcasts++
+	return txid, nil
+}
+
+func TestCheckPendingSends(t *testing.T) {
+	cfg, err := makeBitcoinRegtestTestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rmTestDir()
+	cfg.Testing = true
+	cfg.StuckBlocks = 3
+	ec := NewBtcElectrumClient(cfg).(*BtcElectrumClient)
+	err = ec.CreateWallet("abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := ec.GetWallet()
+	defer w.Close()
+	ec.clientHeaders.SetTip(100)
+	ec.clientHeaders.SetSynced(true)
+	ec.updateWalletTip()
+
+	// fund the wallet and make an unconfirmed send
+	address, err := w.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fund := wire.NewMsgTx(wire.TxVersion)
+	fund.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	fund.AddTxOut(wire.NewTxOut(1000000, script))
+	if err := w.AddTransaction(fund, 90, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	payee, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, send, err := w.Spend("abc", 100000, payee, wallet.NORMAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddTransaction(send, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	txid := send.TxHash().String()
+
+	node := &mempoolNode{known: make(map[string]bool)}
+	ec.Node = node
+	ctx := context.Background()
+
+	// a server error is not an eviction
+	node.err = &electrumx.RPCError{Code: 1, Message: "excessive resource usage"}
+	ec.checkPendingSends(ctx)
+	if node.broadcasts != 0 {
+		t.Fatalf("expected no rebroadcast on a server error - got %d broadcasts", node.broadcasts)
+	}
+	node.err = nil
+
+	// evicted
+	ec.checkPendingSends(ctx)
+	if node.broadcasts != 1 || !node.known[txid] {
+		t.Fatalf("expected the send to be rebroadcast - got %d broadcasts", node.broadcasts)
+	}
+	// known
+	ec.checkPendingSends(ctx)
+	if node.broadcasts != 1 {
+		t.Fatalf("expected no rebroadcast - got %d broadcasts", node.broadcasts)
+	}
+	txn, err := w.GetTransaction(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusPending {
+		t.Fatalf("expected pending - got %s", txn.Status)
+	}
+
+	// stuck after 3 blocks
+	ec.clientHeaders.SetTip(102)
+	ec.checkPendingSends(ctx)
+	txn, err = w.GetTransaction(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusStuck {
+		t.Fatalf("expected stuck - got %s", txn.Status)
+	}
+
+	// mined
+	if err := w.AddTransaction(send, 103, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	ec.clientHeaders.SetTip(103)
+	ec.checkPendingSends(ctx)
+	if _, ok := ec.pendingSends.seenHeight[txid]; ok {
+		t.Fatal("expected a mined send not to be tracked")
+	}
+	txn, err = w.GetTransaction(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusConfirmed {
+		t.Fatalf("expected confirmed - got %s", txn.Status)
+	}
+}
@@ -3,16 +3,130 @@ package btc
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/dev-warrior777/go-electrum-client/client"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
 	"github.com/dev-warrior777/go-electrum-client/wallet"
 )
 
-// RescanWallet asks ElectrumX for info for our wallet keys back to latest
-// checkpoint height.
-// We need to do this for a recreated wallet.
+// Rescan
+//
+// The wallet's receive and change chains are scanned in batches of gap limit
+// addresses, each batch fetching address histories from ElectrumX on a pool
+// of workers. A chain is done when gap limit addresses in a row past the last
+// address with history have none. Progress is checkpointed in the wallet
+// database after each batch so an interrupted rescan resumes where it left
+// off. Finally the transactions found since the wallet birthday are fetched
+// and added to the wallet.
+
+const rescanCheckpointSetting = "rescanCheckpoint"
+
+// number of transactions fetched from ElectrumX before they are added
+const rescanTxBatchSize = 50
+
+// rescanCheckpoint is the state of an unfinished rescan
+type rescanCheckpoint struct {
+	// next key index to scan on each chain
+	Next [2]int `json:"next"`
+	// key indexes with history on each chain in ascending order
+	Hits [2][]int `json:"hits"`
+	// heights of the transactions found keyed on txid
+	Txs map[string]int64 `json:"txs"`
+	// txids of the transactions found before the wallet birthday
+	Skipped map[string]bool `json:"skipped"`
+	Scanned int             `json:"scanned"`
+}
+
+func newRescanCheckpoint() *rescanCheckpoint {
+	return &rescanCheckpoint{
+		Txs:     make(map[string]int64),
+		Skipped: make(map[string]bool),
+	}
+}
+
+// lastHit returns the highest key index with history on a chain or -1
+func (cp *rescanCheckpoint) lastHit(purpose wallet.KeyPurpose) int {
+	hits := cp.Hits[purpose]
+	if len(hits) == 0 {
+		return -1
+	}
+	return hits[len(hits)-1]
+}
+
+// remaining returns the least number of addresses left to scan
+func (cp *rescanCheckpoint) remaining(gapLimit int) int {
+	n := 0
+	for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+		if r := cp.lastHit(purpose) + gapLimit - cp.Next[purpose]; r > 0 {
+			n += r
+		}
+	}
+	return n
+}
+
+func loadRescanCheckpoint(cfg wallet.Cfg) (*rescanCheckpoint, error) {
+	b, err := cfg.GetSetting(rescanCheckpointSetting)
+	if err != nil {
+		return nil, err
+	}
+	cp := newRescanCheckpoint()
+	if len(b) == 0 {
+		return cp, nil
+	}
+	err = json.Unmarshal(b, cp)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Txs == nil {
+		cp.Txs = make(map[string]int64)
+	}
+	if cp.Skipped == nil {
+		cp.Skipped = make(map[string]bool)
+	}
+	return cp, nil
+}
+
+func saveRescanCheckpoint(cfg wallet.Cfg, cp *rescanCheckpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return cfg.PutSetting(rescanCheckpointSetting, b)
+}
+
+func clearRescanCheckpoint(cfg wallet.Cfg) error {
+	return cfg.PutSetting(rescanCheckpointSetting, nil)
+}
+
+// rescanHistory is the history of one wallet address
+type rescanHistory struct {
+	purpose    wallet.KeyPurpose
+	index      int
+	address    btcutil.Address
+	scripthash string
+	history    electrumx.HistoryResult
+}
+
+// RescanWallet asks ElectrumX for the history of our wallet keys and adds the
+// transactions found to the wallet. We need to do this for a recreated wallet.
+// Transactions confirmed before the wallet birthday height are skipped.
 func (ec *BtcElectrumClient) RescanWallet(ctx context.Context) error {
+	return ec.RescanWalletWithProgress(ctx, nil)
+}
+
+// RescanWalletWithProgress rescans the wallet like RescanWallet, reporting
+// progress on the progress channel if not nil. Updates are dropped if the
+// channel is not ready to receive. A rescan that is interrupted, by the
+// context or an ElectrumX error, resumes from its last checkpoint when run
+// again.
+func (ec *BtcElectrumClient) RescanWalletWithProgress(ctx context.Context, progress chan<- *client.RescanProgress) error {
 	w := ec.GetWallet()
 	if w == nil {
 		return ErrNoWallet
@@ -21,74 +135,231 @@ func (ec *BtcElectrumClient) RescanWallet(ctx context.Context) error {
 	if node == nil {
 		return ErrNoNode
 	}
+	cfg := ec.GetConfig().DB.Cfg()
+	workers := ec.GetConfig().RescanWorkers
+	if workers <= 0 {
+		workers = client.DefaultRescanWorkers
+	}
+	gapLimit := w.GapLimit()
+	ec.updateWalletBirthday()
 
-	// highest key index we will try for now
-	highestKeyIndex := 100
-	historyHitIndex := 0
+	cp, err := loadRescanCheckpoint(cfg)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	// work done in this run, to estimate the time left
+	done := 0
+	report := func(txsAdded, remaining int, finished bool) {
+		if progress == nil {
+			return
+		}
+		p := &client.RescanProgress{
+			AddressesScanned: cp.Scanned,
+			TxsFound:         len(cp.Txs),
+			TxsSkipped:       len(cp.Skipped),
+			TxsAdded:         txsAdded,
+			Elapsed:          time.Since(start),
+			Done:             finished,
+		}
+		if done > 0 {
+			p.ETA = p.Elapsed / time.Duration(done) * time.Duration(remaining)
+		}
+		select {
+		case progress <- p:
+		default:
+		}
+	}
 
-	for keyIndex := 0; keyIndex <= highestKeyIndex; keyIndex++ {
-		// flip-flop internal/external to improve locality
-		for purpose := 0; purpose < 2; purpose++ {
-			keyPath := &wallet.KeyPath{
-				Purpose: wallet.KeyPurpose(purpose),
-				Index:   keyIndex,
+	// scan address histories
+	for {
+		var batch []*rescanHistory
+		for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+			for n := 0; n < gapLimit && cp.Next[purpose]+n <= cp.lastHit(purpose)+gapLimit; n++ {
+				batch = append(batch, &rescanHistory{
+					purpose: purpose,
+					index:   cp.Next[purpose] + n,
+				})
 			}
-			address, err := w.GetAddress(keyPath)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		err = parallel(ctx, workers, len(batch), func(ctx context.Context, i int) error {
+			h := batch[i]
+			address, err := w.GetAddress(&wallet.KeyPath{Purpose: h.purpose, Index: h.index})
 			if err != nil {
-				fmt.Printf("bad address for: %d:%d\n", keyIndex, purpose)
-				continue
+				return err
 			}
 			scripthash, err := addressToElectrumScripthash(address)
 			if err != nil {
-				fmt.Printf("cannot make script hash for address: %s\n", address.String())
-				continue
+				return err
 			}
-			fmt.Printf("%s %s  Index:purpose %d:%d\n", address.String(), scripthash, keyIndex, purpose)
-
 			history, err := node.GetHistory(ctx, scripthash)
 			if err != nil {
-				fmt.Printf("error: %v - for scripthash %s\n", scripthash, err)
-				continue
+				return fmt.Errorf("history for %s: %w", address, err)
 			}
-			if len(history) == 0 {
-				// fmt.Printf("No history for script hash from node: %s\n", scripthash)
+			h.address = address
+			h.scripthash = scripthash
+			h.history = history
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, h := range batch {
+			cp.Next[h.purpose] = h.index + 1
+			if len(h.history) == 0 {
 				continue
 			}
-			// got history - update the highest hit index
-			historyHitIndex = keyIndex
-			for _, h := range history {
-				fmt.Println(" Height:", h.Height)
-				fmt.Println(" TxHash: ", h.TxHash)
-				fmt.Println(" Fee: ", h.Fee)
+			cp.Hits[h.purpose] = append(cp.Hits[h.purpose], h.index)
+			for _, tx := range h.history {
+				if beforeBirthday(w, tx) {
+					cp.Skipped[tx.TxHash] = true
+					continue
+				}
+				cp.Txs[tx.TxHash] = tx.Height
 			}
-			pkScriptBytes, err := w.AddressToScript(address)
+			pkScript, err := w.AddressToScript(h.address)
 			if err != nil {
-				fmt.Printf("cannot make pkScript for address: %s\n", address.String())
-				continue
+				return err
 			}
-			hex.EncodeToString(pkScriptBytes)
-			subscription := &wallet.Subscription{
-				PkScript:           hex.EncodeToString(pkScriptBytes),
-				ElectrumScripthash: scripthash,
-				Address:            address.String(),
+			err = w.AddSubscription(&wallet.Subscription{
+				PkScript:           hex.EncodeToString(pkScript),
+				ElectrumScripthash: h.scripthash,
+				Address:            h.address.String(),
+			})
+			if err != nil {
+				return err
 			}
-			err = w.AddSubscription(subscription)
+		}
+		cp.Scanned += len(batch)
+		done += len(batch)
+		err = saveRescanCheckpoint(cfg, cp)
+		if err != nil {
+			return err
+		}
+		report(0, cp.remaining(gapLimit)+len(cp.Txs), false)
+	}
+
+	// mark the addresses with history used in key order so that the wallet's
+	// lookahead window reaches every address before its transactions are
+	// added
+	for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+		for _, index := range cp.Hits[purpose] {
+			address, err := w.GetAddress(&wallet.KeyPath{Purpose: purpose, Index: index})
 			if err != nil {
-				fmt.Printf("cannot add subscritpion for address: %s\n", address.String())
-				// ec.dumpSubscription("failed to add", subscription)
-				continue
+				return err
+			}
+			err = w.MarkAddressUsed(address)
+			if err != nil {
+				return err
 			}
-			fmt.Printf("Added subscritpion for address: %s to wallet subscriptions\n", address.String())
 		}
+	}
 
-		// ** Experimental - if no more history hits for another GAP_LIMIT tries **
-		// consider the job done.
-		if keyIndex > historyHitIndex+client.GAP_LIMIT {
-			fmt.Printf("keyIndex: %d greater than highest history found index %d by GAP_LIMIT %d\n\n",
-				keyIndex, historyHitIndex, client.GAP_LIMIT)
-			break
+	// fetch and add the transactions in block order; unconfirmed last
+	txids := make([]string, 0, len(cp.Txs))
+	for txid := range cp.Txs {
+		txids = append(txids, txid)
+	}
+	sort.Slice(txids, func(i, j int) bool {
+		hi, hj := cp.Txs[txids[i]], cp.Txs[txids[j]]
+		if (hi > 0) != (hj > 0) {
+			return hi > 0
+		}
+		if hi != hj {
+			return hi < hj
+		}
+		return txids[i] < txids[j]
+	})
+	added := 0
+	for len(txids) > 0 {
+		n := rescanTxBatchSize
+		if n > len(txids) {
+			n = len(txids)
+		}
+		batch := txids[:n]
+		txids = txids[n:]
+		msgTxs := make([]*wire.MsgTx, n)
+		err = parallel(ctx, workers, n, func(ctx context.Context, i int) error {
+			if hasTx, txn := w.HasTransaction(batch[i]); hasTx && txn.Height > 0 {
+				return nil
+			}
+			msgTx, _, err := ec.GetRawTransactionFromNode(ctx, batch[i])
+			if err != nil {
+				return fmt.Errorf("transaction %s: %w", batch[i], err)
+			}
+			msgTxs[i] = msgTx
+			return nil
+		})
+		if err != nil {
+			return err
 		}
+		for i, msgTx := range msgTxs {
+			if msgTx != nil {
+				err = w.AddTransaction(msgTx, cp.Txs[batch[i]], time.Now())
+				if err != nil {
+					return err
+				}
+			}
+		}
+		added += n
+		done += n
+		report(added, len(txids), false)
 	}
 
+	err = clearRescanCheckpoint(cfg)
+	if err != nil {
+		return err
+	}
+	report(added, 0, true)
+	return nil
+}
+
+// parallel calls fn for each of 0..n-1 on up to workers goroutines. It returns
+// the first error, after which no more calls are started.
+func parallel(ctx context.Context, workers, n int, fn func(ctx context.Context, i int) error) error {
+	pctx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan int)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+	for j := 0; j < workers; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(ctx, i); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := pctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case err := <-errs:
+		return err
+	default:
+	}
 	return nil
 }
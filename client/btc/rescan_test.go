@@ -0,0 +1,146 @@
+package btc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/client"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// rescanNode serves address histories and transactions for a rescan
+type rescanNode struct {
+	electrumx.ElectrumXNode
+	mtx     sync.Mutex
+	history map[string]electrumx.HistoryResult
+	txs     map[string]string
+	// scripthash that fails once
+	fail  string
+	calls map[string]int
+}
+
+func (n *rescanNode) GetHistory(_ context.Context, scripthash string) (electrumx.HistoryResult, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.calls[scripthash]++
+	if scripthash == n.fail {
+		n.fail = ""
+		return nil, errors.New("connection lost")
+	}
+	return n.history[scripthash], nil
+}
+
+func (n *rescanNode) GetRawTransaction(_ context.Context, txid string) (string, error) {
+	return n.txs[txid], nil
+}
+
+func TestRescanWallet(t *testing.T) {
+	cfg, err := makeBitcoinRegtestTestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rmTestDir()
+	cfg.Testing = true
+	ec := NewBtcElectrumClient(cfg).(*BtcElectrumClient)
+	err = ec.CreateWallet("abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := ec.GetWallet()
+	defer w.Close()
+
+	node := &rescanNode{
+		history: make(map[string]electrumx.HistoryResult),
+		txs:     make(map[string]string),
+		calls:   make(map[string]int),
+	}
+	ec.Node = node
+	scripthashAt := func(index int) string {
+		address, err := w.GetAddress(&wallet.KeyPath{Purpose: wallet.EXTERNAL, Index: index})
+		if err != nil {
+			t.Fatal(err)
+		}
+		scripthash, err := addressToElectrumScripthash(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scripthash
+	}
+	// history at receive indexes 3 & 12, past the first lookahead window
+	for i, index := range []int{3, 12} {
+		address, err := w.GetAddress(&wallet.KeyPath{Purpose: wallet.EXTERNAL, Index: index})
+		if err != nil {
+			t.Fatal(err)
+		}
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{byte(i + 1)}}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(100000, script))
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		txid := tx.TxHash().String()
+		node.txs[txid] = hex.EncodeToString(buf.Bytes())
+		node.history[scripthashAt(index)] = electrumx.HistoryResult{{TxHash: txid, Height: int64(100 + i)}}
+	}
+	// a tx before the wallet birthday at index 3 is skipped
+	err = w.SetBirthdayHeight(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.history[scripthashAt(3)] = append(electrumx.HistoryResult{{TxHash: chainhash.Hash{9}.String(), Height: 40}},
+		node.history[scripthashAt(3)]...)
+	node.fail = scripthashAt(15)
+
+	progress := make(chan *client.RescanProgress, 100)
+	err = ec.RescanWalletWithProgress(context.Background(), progress)
+	if err == nil {
+		t.Fatal("expected the rescan to fail")
+	}
+	// resumes from the checkpoint before the batch that failed
+	err = ec.RescanWalletWithProgress(context.Background(), progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.calls[scripthashAt(0)] != 1 {
+		t.Fatalf("expected index 0 to be scanned once - got %d", node.calls[scripthashAt(0)])
+	}
+	// index 22 may have been scanned before the failed batch was cancelled
+	if node.calls[scripthashAt(22)] < 1 || node.calls[scripthashAt(23)] != 0 {
+		t.Fatal("expected the scan to stop at the gap limit past index 12")
+	}
+
+	var last *client.RescanProgress
+	for len(progress) > 0 {
+		last = <-progress
+	}
+	if last == nil || !last.Done || last.TxsFound != 2 || last.TxsSkipped != 1 || last.TxsAdded != 2 {
+		t.Fatalf("unexpected final progress %+v", last)
+	}
+	utxos, err := w.ListUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 2 {
+		t.Fatalf("expected 2 utxos - got %d", len(utxos))
+	}
+	subs, err := w.ListSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("expected 2 subscriptions - got %d", len(subs))
+	}
+}
@@ -37,7 +37,7 @@ func (e *Ec) RPCEcho(request map[string]string, response *map[string]string) err
 // Get the blockchain tip and sync status
 func (e *Ec) Tip() (int64, bool) {
 	h := e.EleClient.clientHeaders
-	return h.tip, h.synced
+	return h.Tip(), h.Synced()
 }
 func (e *Ec) RPCTip(request map[string]string, response *map[string]string) error {
 	r := *response
@@ -103,11 +103,12 @@ func (e *Ec) RPCListUnspent(request map[string]string, response *map[string]stri
 // Get a new unused wallet receive address
 func (e *Ec) RPCUnusedAddress(request map[string]string, response *map[string]string) error {
 	r := *response
-	address, err := e.EleClient.UnusedAddress(context.TODO())
+	address, pastGap, err := e.EleClient.UnusedAddress(context.TODO())
 	if err != nil {
 		return err
 	}
 	r["address"] = address
+	r["pastGap"] = cast.ToString(pastGap)
 	return nil
 }
 
@@ -0,0 +1,334 @@
+package btc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Waiters
+//
+// WaitForConfirmations and WaitForSpend block until a tx, in the wallet or
+// not, is mined deep enough or an outpoint is spent. A waiter watches the
+// scripthash of an output of the tx it waits on. That scripthash is subscribed
+// on ElectrumX unless the wallet subscribes it already. The waiter checks the
+// script history when the scripthash status changes, when the tip changes and
+// after a network restart, when the watched scripthashes are subscribed again.
+
+// scripthashWaiters are the scripthashes watched by waiters
+type scripthashWaiters struct {
+	mtx sync.Mutex
+	// wake channels of the waiters keyed on electrum scripthash
+	watching map[string]map[chan struct{}]struct{}
+	// scripthashes subscribed for waiters and not the wallet. They are kept
+	// after the waiters are done as notifications may still arrive.
+	subscribed map[string]bool
+}
+
+// waiter is a single call of a wait method
+type waiter struct {
+	ec   *BtcElectrumClient
+	wake chan struct{}
+	// scripthashes watched
+	scripthashes []string
+}
+
+// watch wakes the waiter when the status of scripthash changes
+func (wt *waiter) watch(ctx context.Context, scripthash string) {
+	for _, sh := range wt.scripthashes {
+		if sh == scripthash {
+			return
+		}
+	}
+	wt.scripthashes = append(wt.scripthashes, scripthash)
+	wt.ec.watchScripthash(ctx, scripthash, wt.wake)
+}
+
+// close stops watching the scripthashes
+func (wt *waiter) close() {
+	for _, sh := range wt.scripthashes {
+		wt.ec.unwatchScripthash(sh, wt.wake)
+	}
+	wt.scripthashes = nil
+}
+
+// walletSubscribes returns true if the wallet subscribes scripthash
+func (ec *BtcElectrumClient) walletSubscribes(scripthash string) bool {
+	sub, err := ec.getSubscriptionForScripthash(scripthash)
+	return err == nil && sub != nil
+}
+
+// watchScripthash sends on wake when the status of scripthash changes. The
+// first watcher subscribes scripthash on the node if the wallet does not. A
+// failed subscription is tried again on network restart.
+func (ec *BtcElectrumClient) watchScripthash(ctx context.Context, scripthash string, wake chan struct{}) {
+	sw := &ec.waiters
+	sw.mtx.Lock()
+	if sw.watching == nil {
+		sw.watching = make(map[string]map[chan struct{}]struct{})
+		sw.subscribed = make(map[string]bool)
+	}
+	wakes, ok := sw.watching[scripthash]
+	if !ok {
+		wakes = make(map[chan struct{}]struct{})
+		sw.watching[scripthash] = wakes
+	}
+	wakes[wake] = struct{}{}
+	first := len(wakes) == 1
+	sw.mtx.Unlock()
+
+	if !first || ec.walletSubscribes(scripthash) {
+		return
+	}
+	sw.mtx.Lock()
+	sw.subscribed[scripthash] = true
+	sw.mtx.Unlock()
+	node := ec.GetNode()
+	if node == nil {
+		return
+	}
+	_, err := node.SubscribeScripthashNotify(ctx, scripthash)
+	if err != nil {
+		fmt.Printf("cannot subscribe waiter scripthash %s: %v\n", scripthash, err)
+	}
+}
+
+// unwatchScripthash stops sending on wake for scripthash. The last watcher
+// unsubscribes scripthash on the node if the wallet does not subscribe it.
+func (ec *BtcElectrumClient) unwatchScripthash(scripthash string, wake chan struct{}) {
+	sw := &ec.waiters
+	sw.mtx.Lock()
+	wakes := sw.watching[scripthash]
+	delete(wakes, wake)
+	last := len(wakes) == 0
+	if last {
+		delete(sw.watching, scripthash)
+	}
+	subscribed := sw.subscribed[scripthash]
+	sw.mtx.Unlock()
+
+	if !last || !subscribed || ec.walletSubscribes(scripthash) {
+		return
+	}
+	node := ec.GetNode()
+	if node == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	node.UnsubscribeScripthashNotify(ctx, scripthash)
+}
+
+// wakeWaiters wakes the waiters watching scripthash
+func (ec *BtcElectrumClient) wakeWaiters(scripthash string) {
+	sw := &ec.waiters
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	for wake := range sw.watching[scripthash] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// waiterOnly returns true if scripthash was subscribed for waiters and not the
+// wallet
+func (ec *BtcElectrumClient) waiterOnly(scripthash string) bool {
+	sw := &ec.waiters
+	sw.mtx.Lock()
+	subscribed := sw.subscribed[scripthash]
+	sw.mtx.Unlock()
+	return subscribed && !ec.walletSubscribes(scripthash)
+}
+
+// resubscribeWaiters subscribes the watched scripthashes on the node again
+// after a network restart and wakes all waiters to check again
+func (ec *BtcElectrumClient) resubscribeWaiters(ctx context.Context) {
+	node := ec.GetNode()
+	if node == nil {
+		return
+	}
+	sw := &ec.waiters
+	sw.mtx.Lock()
+	var scripthashes []string
+	for scripthash := range sw.watching {
+		scripthashes = append(scripthashes, scripthash)
+	}
+	sw.mtx.Unlock()
+
+	for _, scripthash := range scripthashes {
+		if ec.waiterOnly(scripthash) {
+			_, err := node.SubscribeScripthashNotify(ctx, scripthash)
+			if err != nil {
+				fmt.Printf("cannot resubscribe waiter scripthash %s: %v\n", scripthash, err)
+			}
+		}
+		ec.wakeWaiters(scripthash)
+	}
+}
+
+// wait calls check and then again each time the tip changes or a scripthash
+// watched by the waiter changes status, until check is done or fails or ctx
+// is done
+func (ec *BtcElectrumClient) wait(ctx context.Context, check func(wt *waiter) (bool, error)) error {
+	if ec.GetWallet() == nil {
+		return ErrNoWallet
+	}
+	if ec.GetNode() == nil {
+		return ErrNoNode
+	}
+	tips := ec.SubscribeTipChanges(1)
+	defer tips.Unsubscribe()
+	wt := &waiter{
+		ec:   ec,
+		wake: make(chan struct{}, 1),
+	}
+	defer wt.close()
+
+	tipCh := tips.C
+	for {
+		if ec.GetWallet() == nil {
+			return ErrNoWallet
+		}
+		done, err := check(wt)
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-tipCh:
+			if !ok {
+				// tip feed closed; wait on the scripthashes only
+				tipCh = nil
+			}
+		case <-wt.wake:
+		}
+	}
+}
+
+// checkLater logs an error checking for a waiter and waits on. The server not
+// knowing a tx is not logged: it may not be broadcast yet.
+func checkLater(what string, err error) (bool, error) {
+	if !isTxNotFound(err) {
+		fmt.Printf("cannot check %s: %v - will check again\n", what, err)
+	}
+	return false, nil
+}
+
+// txScripthash returns the electrum scripthash of the first output of tx that
+// is in ElectrumX script histories
+func txScripthash(tx *wire.MsgTx) (string, error) {
+	for _, out := range tx.TxOut {
+		if txscript.GetScriptClass(out.PkScript) != txscript.NullDataTy {
+			return pkScriptToElectrumScripthash(out.PkScript), nil
+		}
+	}
+	return "", fmt.Errorf("transaction %s has no output to watch", tx.TxHash())
+}
+
+// historyHeight returns the height of txid in the history of scripthash: >0
+// mined, 0 or -1 in the mempool. It returns false if txid is not in it.
+func (ec *BtcElectrumClient) historyHeight(ctx context.Context, scripthash, txid string) (int64, bool, error) {
+	history, err := ec.GetNode().GetHistory(ctx, scripthash)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, h := range history {
+		if h.TxHash == txid {
+			return h.Height, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// WaitForConfirmations blocks until the tx txid has n confirmations at the
+// client's tip. The tx need not be a wallet tx nor be broadcast yet. A reorg
+// that unmines the tx restarts the wait. Returns ctx.Err() if ctx is done
+// first.
+func (ec *BtcElectrumClient) WaitForConfirmations(ctx context.Context, txid string, n int64) error {
+	if n < 1 {
+		return errors.New("confirmations must be at least 1")
+	}
+	_, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return err
+	}
+	var scripthash string
+	return ec.wait(ctx, func(wt *waiter) (bool, error) {
+		if scripthash == "" {
+			tx, _, err := ec.GetRawTransactionFromNode(ctx, txid)
+			if err != nil {
+				return checkLater(txid, err)
+			}
+			scripthash, err = txScripthash(tx)
+			if err != nil {
+				return false, err
+			}
+			wt.watch(ctx, scripthash)
+		}
+		height, ok, err := ec.historyHeight(ctx, scripthash, txid)
+		if err != nil {
+			return checkLater(txid, err)
+		}
+		tip, synced := ec.Tip()
+		return ok && synced && height > 0 && tip-height+1 >= n, nil
+	})
+}
+
+// WaitForSpend blocks until a tx spending outpoint is known to the server and
+// returns it. The spending tx may be unconfirmed; WaitForConfirmations can wait
+// for it to be mined. The outpoint need not be a wallet coin. Returns
+// ctx.Err() if ctx is done first.
+func (ec *BtcElectrumClient) WaitForSpend(ctx context.Context, outpoint *wire.OutPoint) (*wire.MsgTx, error) {
+	fundingTxid := outpoint.Hash.String()
+	var scripthash string
+	var spend *wire.MsgTx
+	// txs in the history known not to spend outpoint
+	checked := make(map[string]bool)
+	err := ec.wait(ctx, func(wt *waiter) (bool, error) {
+		if scripthash == "" {
+			funding, _, err := ec.GetRawTransactionFromNode(ctx, fundingTxid)
+			if err != nil {
+				return checkLater(fundingTxid, err)
+			}
+			if int(outpoint.Index) >= len(funding.TxOut) {
+				return false, fmt.Errorf("transaction %s has no output %d", fundingTxid, outpoint.Index)
+			}
+			scripthash = pkScriptToElectrumScripthash(funding.TxOut[outpoint.Index].PkScript)
+			wt.watch(ctx, scripthash)
+		}
+		history, err := ec.GetNode().GetHistory(ctx, scripthash)
+		if err != nil {
+			return checkLater(outpoint.String(), err)
+		}
+		for _, h := range history {
+			if h.TxHash == fundingTxid || checked[h.TxHash] {
+				continue
+			}
+			tx, _, err := ec.GetRawTransactionFromNode(ctx, h.TxHash)
+			if err != nil {
+				return checkLater(h.TxHash, err)
+			}
+			for _, in := range tx.TxIn {
+				if in.PreviousOutPoint == *outpoint {
+					spend = tx
+					return true, nil
+				}
+			}
+			checked[h.TxHash] = true
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
@@ -0,0 +1,226 @@
+package btc
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/client"
+	"github.com/dev-warrior777/go-electrum-client/electrumx"
+)
+
+// waitNode is a historyNode safe for waiters that records the scripthashes
+// subscribed
+type waitNode struct {
+	historyNode
+	mtx        sync.Mutex
+	subscribed map[string]bool
+}
+
+func newWaitNode() *waitNode {
+	return &waitNode{
+		historyNode: historyNode{
+			txs:     make(map[string]string),
+			history: make(map[string]electrumx.HistoryResult),
+		},
+		subscribed: make(map[string]bool),
+	}
+}
+
+func (n *waitNode) GetRawTransaction(ctx context.Context, txid string) (string, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.historyNode.GetRawTransaction(ctx, txid)
+}
+
+func (n *waitNode) GetHistory(ctx context.Context, scripthash string) (electrumx.HistoryResult, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.historyNode.GetHistory(ctx, scripthash)
+}
+
+func (n *waitNode) SubscribeScripthashNotify(_ context.Context, scripthash string) (*electrumx.ScripthashStatusResult, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.subscribed[scripthash] = true
+	return &electrumx.ScripthashStatusResult{Scripthash: scripthash}, nil
+}
+
+func (n *waitNode) UnsubscribeScripthashNotify(_ context.Context, scripthash string) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	delete(n.subscribed, scripthash)
+}
+
+// set replaces the histories with txs at their heights
+func (n *waitNode) set(t *testing.T, txs map[*wire.MsgTx]int64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.history = make(map[string]electrumx.HistoryResult)
+	for tx, height := range txs {
+		n.add(t, tx, height)
+	}
+}
+
+func (n *waitNode) isSubscribed(scripthash string) bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.subscribed[scripthash]
+}
+
+func waitSubscribed(t *testing.T, n *waitNode, scripthash string) {
+	for i := 0; i < 100; i++ {
+		if n.isSubscribed(scripthash) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("scripthash %s not subscribed", scripthash)
+}
+
+func newWaitTestClient(t *testing.T) (*BtcElectrumClient, *waitNode) {
+	cfg, err := makeBitcoinRegtestTestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Testing = true
+	ec := NewBtcElectrumClient(cfg).(*BtcElectrumClient)
+	err = ec.CreateWallet("abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := newWaitNode()
+	ec.Node = node
+	ec.clientHeaders.SetTip(100)
+	ec.clientHeaders.SetSynced(true)
+	return ec, node
+}
+
+func TestWaitForConfirmations(t *testing.T) {
+	ec, node := newWaitTestClient(t)
+	defer rmTestDir()
+	defer ec.GetWallet().Close()
+
+	script, err := hex.DecodeString("0014027211433701197f4b7146f668885312c9a8b53b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(2000000, script))
+	node.set(t, map[*wire.MsgTx]int64{tx: 0})
+	scripthash := pkScriptToElectrumScripthash(script)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ec.WaitForConfirmations(context.Background(), tx.TxHash().String(), 2)
+	}()
+	waitSubscribed(t, node, scripthash)
+
+	// one confirmation
+	node.set(t, map[*wire.MsgTx]int64{tx: 100})
+	ec.clientHeaders.tipFeed.Publish(&client.TipChange{Height: 100})
+	select {
+	case err := <-errCh:
+		t.Fatalf("expected waiting at 1 confirmation - got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// two confirmations
+	ec.clientHeaders.SetTip(101)
+	ec.clientHeaders.tipFeed.Publish(&client.TipChange{Height: 101})
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for 2 confirmations")
+	}
+	if node.isSubscribed(scripthash) {
+		t.Fatal("expected scripthash unsubscribed after the wait")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ec.WaitForConfirmations(ctx, tx.TxHash().String(), 3)
+	if err != context.Canceled {
+		t.Fatalf("expected context canceled - got %v", err)
+	}
+}
+
+func TestWaitForSpend(t *testing.T) {
+	ec, node := newWaitTestClient(t)
+	defer rmTestDir()
+	defer ec.GetWallet().Close()
+
+	script, err := hex.DecodeString("0014027211433701197f4b7146f668885312c9a8b53b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	funding := wire.NewMsgTx(wire.TxVersion)
+	funding.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	funding.AddTxOut(wire.NewTxOut(2000000, script))
+	node.set(t, map[*wire.MsgTx]int64{funding: 50})
+	scripthash := pkScriptToElectrumScripthash(script)
+	outpoint := wire.OutPoint{Hash: funding.TxHash()}
+
+	_, err = ec.WaitForSpend(context.Background(), &wire.OutPoint{Hash: funding.TxHash(), Index: 1})
+	if err == nil {
+		t.Fatal("expected error for an output the tx does not have")
+	}
+
+	type result struct {
+		tx  *wire.MsgTx
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		tx, err := ec.WaitForSpend(context.Background(), &outpoint)
+		resCh <- result{tx, err}
+	}()
+	waitSubscribed(t, node, scripthash)
+
+	// the server restarts
+	node.UnsubscribeScripthashNotify(context.Background(), scripthash)
+	ec.resubscribeWaiters(context.Background())
+	if !node.isSubscribed(scripthash) {
+		t.Fatal("expected scripthash resubscribed after network restart")
+	}
+
+	// an unrelated payment to the script is not a spend
+	other := wire.NewMsgTx(wire.TxVersion)
+	other.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{2}}, nil, nil))
+	other.AddTxOut(wire.NewTxOut(1000, script))
+	node.set(t, map[*wire.MsgTx]int64{funding: 50, other: 0})
+	ec.wakeWaiters(scripthash)
+	select {
+	case res := <-resCh:
+		t.Fatalf("expected waiting for a spend - got %v", res.err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	spend := wire.NewMsgTx(wire.TxVersion)
+	spend.AddTxIn(wire.NewTxIn(&outpoint, nil, nil))
+	spend.AddTxOut(wire.NewTxOut(1990000, script))
+	node.set(t, map[*wire.MsgTx]int64{funding: 50, other: 0, spend: 0})
+	ec.wakeWaiters(scripthash)
+	select {
+	case res := <-resCh:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if res.tx.TxHash() != spend.TxHash() {
+			t.Fatalf("expected spend %s - got %s", spend.TxHash(), res.tx.TxHash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the spend")
+	}
+	if !ec.waiterOnly(scripthash) {
+		t.Fatal("expected late notifications for the scripthash to be ignored by the wallet")
+	}
+}
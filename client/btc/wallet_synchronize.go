@@ -176,11 +176,18 @@ func (ec *BtcElectrumClient) addressStatusNotify(ctx context.Context) error {
 					return
 				}
 
+				ec.wakeWaiters(status.Scripthash)
+
 				if status.Status == "" {
 					// fmt.Println("status.Status is null no history yet; ignoring...")
 					continue
 				}
 
+				if ec.waiterOnly(status.Scripthash) {
+					// subscribed for a waiter, not the wallet
+					continue
+				}
+
 				// get wallet db subscription details
 				sub, err := ec.getSubscriptionForScripthash(status.Scripthash)
 				if err != nil { // db assert  'no rows in result set'
@@ -199,6 +206,9 @@ func (ec *BtcElectrumClient) addressStatusNotify(ctx context.Context) error {
 
 				// add/update wallet db tx store
 				ec.addTxHistoryToWallet(ctx, history)
+
+				// an unconfirmed wallet receive may have left the history
+				ec.checkUnconfirmedReceives(ctx, status.Scripthash, history)
 			}
 		}
 	}()
@@ -309,9 +319,18 @@ func (ec *BtcElectrumClient) GetRawTransactionFromNode(ctx context.Context, txid
 // addTxHistoryToWallet adds new transaction details for an ElectrumX history list
 func (ec *BtcElectrumClient) addTxHistoryToWallet(ctx context.Context, history electrumx.HistoryResult) {
 	for _, h := range history {
-		// does wallet already has a confirmed transaction?
+		if beforeBirthday(ec.GetWallet(), h) {
+			continue
+		}
+		// ElectrumX gives -1 for a mempool tx with unconfirmed inputs
+		height := h.Height
+		if height < 0 {
+			height = 0
+		}
+		// does wallet already has a confirmed transaction? If it was mined
+		// at another height a reorg moved it.
 		walletHasTx, txn := ec.GetWallet().HasTransaction(h.TxHash)
-		if walletHasTx && txn.Height > 0 {
+		if walletHasTx && txn.Height > 0 && txn.Height == height {
 			// fmt.Println("** already got confirmed tx", h.TxHash)
 			continue
 		}
@@ -320,8 +339,8 @@ func (ec *BtcElectrumClient) addTxHistoryToWallet(ctx context.Context, history e
 		if err != nil {
 			continue
 		}
-		fmt.Printf("adding/updating transaction txid: %s, height: %d, fee %d\n", h.TxHash, h.Height, h.Fee)
-		err = ec.GetWallet().AddTransaction(msgTx, h.Height, txtime)
+		fmt.Printf("adding/updating transaction txid: %s, height: %d, fee %d\n", h.TxHash, height, h.Fee)
+		err = ec.GetWallet().AddTransaction(msgTx, height, txtime)
 		if err != nil {
 			fmt.Println(err)
 			continue
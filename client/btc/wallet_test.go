@@ -1,6 +1,7 @@
 package btc
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -52,7 +53,7 @@ func TestWalletCreation(t *testing.T) {
 	cfg.Testing = true
 	ec := NewBtcElectrumClient(cfg)
 	pw := "abc"
-	err = ec.CreateWallet(pw)
+	err = ec.CreateWallet(pw, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -71,3 +72,37 @@ func TestWalletCreation(t *testing.T) {
 
 	ec.GetWallet().Close()
 }
+
+// Payment URIs are checked before spending
+func TestPayURI(t *testing.T) {
+	cfg, err := makeBitcoinRegtestTestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rmTestDir()
+	cfg.Testing = true
+	ec := NewBtcElectrumClient(cfg)
+	err = ec.CreateWallet("abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ec.GetWallet().Close()
+
+	for _, uri := range []string{
+		// mainnet
+		"bitcoin:bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq?amount=1",
+		// no amount
+		"bitcoin:bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd?label=shop",
+		// lightning only
+		"bitcoin:?lightning=lnbcrt1",
+	} {
+		_, _, _, err := ec.PayURI("abc", uri, wallet.NORMAL)
+		if !errors.Is(err, wallet.ErrBadPaymentURI) {
+			t.Fatalf("%s: expected %v - got %v", uri, wallet.ErrBadPaymentURI, err)
+		}
+	}
+	_, _, _, err = ec.PayURI("abc", "bitcoin:bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd?amount=0.1", wallet.NORMAL)
+	if err != wallet.ErrInsufficientFunds {
+		t.Fatalf("expected %v - got %v", wallet.ErrInsufficientFunds, err)
+	}
+}
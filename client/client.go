@@ -19,6 +19,8 @@ package client
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/btcsuite/btcd/wire"
 	"github.com/dev-warrior777/go-electrum-client/electrumx"
@@ -34,10 +36,33 @@ const (
 )
 
 const (
-	// Electrum Wallet
-	GAP_LIMIT = 10
+	// Electrum Wallet default gap limit
+	GAP_LIMIT = wallet.DefaultGapLimit
+	// Default number of concurrent ElectrumX requests made by a rescan
+	DefaultRescanWorkers = 4
+	// Default interval between checks of unconfirmed sends
+	DefaultRebroadcastInterval = 10 * time.Minute
+	// Default number of blocks before an unconfirmed send is stuck
+	DefaultStuckBlocks = 6
 )
 
+// RescanProgress reports the progress of a wallet rescan
+type RescanProgress struct {
+	// Wallet addresses whose history has been fetched
+	AddressesScanned int
+	// Transactions found in the address histories
+	TxsFound int
+	// Transactions found that were confirmed before the wallet birthday and
+	// not added
+	TxsSkipped int
+	// Transactions fetched and added to the wallet
+	TxsAdded int
+	Elapsed  time.Duration
+	// Estimated time to finish. Zero if not known.
+	ETA  time.Duration
+	Done bool
+}
+
 type ElectrumClient interface {
 	Start(ctx context.Context) error
 	Stop()
@@ -46,15 +71,22 @@ type ElectrumClient interface {
 	GetWallet() wallet.ElectrumWallet
 	GetNode() electrumx.ElectrumXNode
 	//
-	RegisterTipChangeNotify() (<-chan int64, error)
-	UnregisterTipChangeNotify()
+	SubscribeTipChanges(buffer int) *TipSubscription
 	//
-	CreateWallet(pw string) error
+	CreateWallet(pw, passphrase string) error
 	LoadWallet(pw string) error
-	RecreateWallet(ctx context.Context, pw, mnenomic string) error
+	RecreateWallet(ctx context.Context, pw, mnenomic, passphrase string) error
+	CreateElectrumSeedWallet(pw string, seedType wallet.ElectrumSeedType) (string, error)
+	RecreateElectrumSeedWallet(ctx context.Context, pw, mnemonic string) error
+	ImportDescriptors(ctx context.Context, pw, receive, change string) error
+	ExportDescriptors(pw string, private bool) (*wallet.WalletDescriptors, error)
+	MasterFingerprint() (string, error)
+	ChangePassword(oldPw, newPw string) error
 	//
 	SyncWallet(ctx context.Context) error
 	RescanWallet(ctx context.Context) error
+	RescanWalletWithProgress(ctx context.Context, progress chan<- *RescanProgress) error
+	DiscoverAccounts(ctx context.Context, pw string) ([]wallet.Account, error)
 	ImportAndSweep(ctx context.Context, keyPairs []string) error
 	//
 	CloseWallet()
@@ -64,19 +96,35 @@ type ElectrumClient interface {
 	GetBlockHeader(height int64) *wire.BlockHeader
 	GetBlockHeaders(startHeight, count int64) ([]*wire.BlockHeader, error)
 	Spend(pw string, amount int64, toAddress string, feeLevel wallet.FeeLevel) (int, string, string, error)
+	SpendAll(pw string, toAddress string, feeLevel wallet.FeeLevel) (string, string, error)
+	SpendTx(pw string, info *wallet.SpendInfo) (*wallet.SpendResult, error)
+	SpendCoins(pw string, amount int64, toAddress string, feeLevel wallet.FeeLevel, mustUse, mayUse []string) (int, string, string, []string, error)
+	PayURI(pw, uri string, feeLevel wallet.FeeLevel) (int, string, string, error)
 	GetPrivKeyForAddress(pw, addr string) (string, error)
+	SignMessage(pw, addr, message string) (string, error)
+	VerifyMessage(addr, message, signature string) (bool, error)
 	ListUnspent() ([]wallet.Utxo, error)
 	ListConfirmedUnspent() ([]wallet.Utxo, error)
 	ListFrozenUnspent() ([]wallet.Utxo, error)
 	FreezeUTXO(txid string, out uint32) error
 	UnfreezeUTXO(txid string, out uint32) error
-	UnusedAddress(ctx context.Context) (string, error)
+	SetLabel(label wallet.Label) error
+	GetLabel(labelType wallet.LabelType, ref string) (*wallet.Label, error)
+	ListLabels(labelType wallet.LabelType) ([]wallet.Label, error)
+	ImportLabels(r io.Reader) (int, error)
+	ExportLabels(w io.Writer) error
+	UnusedAddress(ctx context.Context) (string, bool, error)
+	PaymentURI(ctx context.Context, amount int64, label, message string) (string, error)
 	ChangeAddress(ctx context.Context) (string, error)
 	ValidateAddress(addr string) (bool, bool, error)
 	SignTx(pw string, txBytes []byte) ([]byte, error)
 	GetWalletTx(txid string) (int, bool, []byte, error)
 	GetWalletSpents() ([]wallet.Stxo, error)
+	TxHistory(filter *wallet.HistoryFilter) ([]wallet.TxHistoryEntry, error)
 	Balance() (int64, int64, int64, error)
+	SubscribeWalletEvents(buffer int) (*wallet.EventSubscription, error)
+	WaitForConfirmations(ctx context.Context, txid string, n int64) error
+	WaitForSpend(ctx context.Context, outpoint *wire.OutPoint) (*wire.MsgTx, error)
 
 	// adapt and pass thru
 	Broadcast(ctx context.Context, rawTx []byte) (string, error)
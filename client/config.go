@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -57,12 +58,61 @@ type ClientConfig struct {
 	// The highest allowable fee-per-byte
 	MaxFee int64
 
+	// The highest allowable total fee for a spend in satoshis. Zero is no
+	// limit.
+	MaxAbsoluteFee int64
+
+	// The highest allowable fee for a spend as a percentage of the amount
+	// paid. Zero is no limit.
+	MaxFeePercent float64
+
 	// External API to query to look up fees. If this field is nil then the default fees will be used.
 	// If the API is unreachable then the default fees will likewise be used. If the API returns a fee
 	// greater than MaxFee then the MaxFee will be used in place. The API response must be formatted as
 	// { "fastestFee": 40, "halfHourFee": 20, "hourFee": 10 }
 	FeeAPI url.URL
 
+	// Confirmation targets in blocks for each fee level used with the
+	// ElectrumX server fee estimates. Missing levels use
+	// wallet.DefaultConfTargets.
+	FeeConfTargets map[wallet.FeeLevel]int64
+
+	// Coin selection strategy for spends. If nil the wallet default, branch
+	// and bound falling back to knapsack, is used.
+	CoinSelector wallet.CoinSelector
+
+	// Number of unused addresses kept past the last used address of each
+	// chain. Zero is the wallet's stored gap limit or wallet.DefaultGapLimit.
+	GapLimit int
+
+	// What UnusedAddress does when all addresses within the gap limit have
+	// been handed out: return an error, hand out an unused address again or
+	// go past the gap limit.
+	GapPolicy wallet.GapPolicy
+
+	// Number of concurrent ElectrumX requests made by a wallet rescan. Zero
+	// is DefaultRescanWorkers.
+	RescanWorkers int
+
+	// Birthday of a wallet to recreate: the date and block height before
+	// which the wallet has no transactions. Zero values are unknown. A
+	// birthday height also sets where a new headers file starts.
+	Birthday       time.Time
+	BirthdayHeight int64
+
+	// Number of confirmations of a wallet transaction notified by wallet
+	// events. Zero is wallet.DefaultEventConfirmations.
+	EventConfirmations int
+
+	// How often the client checks that its unconfirmed sends are still known
+	// to the server and rebroadcasts any that were evicted. Zero is
+	// DefaultRebroadcastInterval.
+	RebroadcastInterval time.Duration
+
+	// Number of blocks an unconfirmed send waits to be mined before it is
+	// marked stuck. Zero is DefaultStuckBlocks.
+	StuckBlocks int64
+
 	// Disable the exchange rate provider
 	DisableExchangeRates bool
 
@@ -87,17 +137,25 @@ func NewDefaultConfig() *ClientConfig {
 }
 func (cc *ClientConfig) MakeWalletConfig() *wallet.WalletConfig {
 	wc := wallet.WalletConfig{
-		Chain:        cc.Chain,
-		Params:       cc.Params,
-		StoreEncSeed: cc.StoreEncSeed,
-		DataDir:      cc.DataDir,
-		DbType:       cc.DbType,
-		DB:           cc.DB,
-		LowFee:       cc.LowFee,
-		MediumFee:    cc.MediumFee,
-		HighFee:      cc.HighFee,
-		MaxFee:       cc.MaxFee,
-		Testing:      cc.Testing,
+		Chain:              cc.Chain,
+		Params:             cc.Params,
+		StoreEncSeed:       cc.StoreEncSeed,
+		DataDir:            cc.DataDir,
+		DbType:             cc.DbType,
+		DB:                 cc.DB,
+		LowFee:             cc.LowFee,
+		MediumFee:          cc.MediumFee,
+		HighFee:            cc.HighFee,
+		MaxFee:             cc.MaxFee,
+		MaxAbsoluteFee:     cc.MaxAbsoluteFee,
+		MaxFeePercent:      cc.MaxFeePercent,
+		CoinSelector:       cc.CoinSelector,
+		GapLimit:           cc.GapLimit,
+		GapPolicy:          cc.GapPolicy,
+		Birthday:           cc.Birthday,
+		BirthdayHeight:     cc.BirthdayHeight,
+		EventConfirmations: cc.EventConfirmations,
+		Testing:            cc.Testing,
 	}
 	return &wc
 }
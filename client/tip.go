@@ -0,0 +1,27 @@
+package client
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// TipChange is a new blockchain tip of the client headers
+type TipChange struct {
+	Height int64
+	Hash   chainhash.Hash
+	Header *wire.BlockHeader
+	// Reorg is true if the new tip replaced headers of the previous chain
+	Reorg bool
+}
+
+// TipFeed publishes tip changes to any number of subscribers
+type TipFeed = wallet.Feed[*TipChange]
+
+// TipSubscription receives tip changes until Unsubscribe is called or the feed
+// is closed
+type TipSubscription = wallet.FeedSubscription[*TipChange]
+
+func NewTipFeed() *TipFeed {
+	return wallet.NewFeed[*TipChange]()
+}
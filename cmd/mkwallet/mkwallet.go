@@ -88,13 +88,15 @@ func makeBasicConfig(coin, net string) (*client.ClientConfig, error) {
 	return cfg, nil
 }
 
-func configure() (string, string, string, *client.ClientConfig, error) {
+func configure() (string, string, string, string, *client.ClientConfig, error) {
 	help := flag.Bool("help", false, "usage help")
 	coin := flag.String("coin", "btc", "coin name")
 	net := flag.String("net", "regtest", "network type; testnet, mainnet, regtest")
 	pass := flag.String("pass", "", "wallet password")
 	action := flag.String("action", "create", "action: 'create'a new wallet or 'recreate' from seed")
 	seed := flag.String("seed", "", "'seed words for recreate' inside ''; example: 'word1 word2 ... word12'")
+	passphrase := flag.String("passphrase", "", "optional BIP39 passphrase for create or recreate")
+	birthday := flag.Int64("birthday", 0, "block height of the wallet birthday for recreate; 0 if unknown")
 	test_wallet := flag.Bool("tw", false, "known test wallets override for regtest/testnet")
 	dbType := flag.String("dbtype", "bbolt", "set database type: 'bbolt' default, 'sqlite'")
 
@@ -117,23 +119,23 @@ func configure() (string, string, string, *client.ClientConfig, error) {
 		case "testnet", "testnet3":
 			*seed = "canyon trip truly ritual lonely quiz romance rose alone journey like bronze"
 		default:
-			return "", "", "", nil, errors.New("no test_wallet for mainnet")
+			return "", "", "", "", nil, errors.New("no test_wallet for mainnet")
 		}
 	}
 	if *action == "create" && *pass == "" {
-		return "", "", "", nil, errors.New("wallet create needs a password")
+		return "", "", "", "", nil, errors.New("wallet create needs a password")
 	} else if *action == "recreate" {
 		if *pass == "" {
-			return "", "", "", nil, errors.New("wallet recreate needs a new password - " +
+			return "", "", "", "", nil, errors.New("wallet recreate needs a new password - " +
 				"can be different to the previous password")
 		}
 		if *seed == "" {
-			return "", "", "", nil, errors.New("wallet recreate needs the old wallet seed")
+			return "", "", "", "", nil, errors.New("wallet recreate needs the old wallet seed")
 		}
 		words := strings.SplitN(*seed, " ", 12)
 		fmt.Printf("%q (len %d)\n", words, len(words))
 		if len(words) != 12 {
-			return "", "", "", nil, errors.New("a seed must have 12 words each separated by a space")
+			return "", "", "", "", nil, errors.New("a seed must have 12 words each separated by a space")
 		}
 		var bad bool
 		for _, word := range words {
@@ -143,14 +145,15 @@ func configure() (string, string, string, *client.ClientConfig, error) {
 			}
 		}
 		if bad {
-			return "", "", "", nil, errors.New("malformed seed -- did you put extra spaces?")
+			return "", "", "", "", nil, errors.New("malformed seed -- did you put extra spaces?")
 		}
 	}
 	cfg, err := makeBasicConfig(*coin, *net)
 	if *dbType == "sqlite" {
 		cfg.DbType = "sqlite"
 	}
-	return *action, *pass, *seed, cfg, err
+	cfg.BirthdayHeight = *birthday
+	return *action, *pass, *seed, *passphrase, cfg, err
 }
 
 func checkSimnetHelp(cfg *client.ClientConfig) string {
@@ -169,7 +172,7 @@ func checkSimnetHelp(cfg *client.ClientConfig) string {
 
 func main() {
 	fmt.Println("Goele", client.GoeleVersion)
-	action, pass, seed, cfg, err := configure()
+	action, pass, seed, passphrase, cfg, err := configure()
 	fmt.Println(action, pass, seed)
 	if err != nil {
 		fmt.Println(err, " - exiting")
@@ -183,10 +186,12 @@ func main() {
 	ec := btc.NewBtcElectrumClient(cfg)
 
 	if action == "create" {
-		err := ec.CreateWallet(pass)
+		err := ec.CreateWallet(pass, passphrase)
 		if err != nil {
 			fmt.Println(err)
+			os.Exit(1)
 		}
+		printFingerprint(ec)
 		os.Exit(1)
 	}
 
@@ -204,23 +209,32 @@ func main() {
 		// for non-mainnet testing recreate a wallet with a known set of keys ..
 		// var mnemonic = "jungle pair grass super coral bubble tomato sheriff pulp cancel luggage wagon"
 		// err := ec.RecreateWallet(pass, mnemonic)
-		err := ec.RecreateWallet(context.TODO(), pass, seed)
+		err := ec.RecreateWallet(context.TODO(), pass, seed, passphrase)
 		if err != nil {
 			fmt.Println(err, " - exiting")
 		}
 	} else if net == "testnet3" {
 		// for non-mainnet testing recreate a wallet with a known set of keys ..
 		// err := ec.RecreateWallet("abc", "canyon trip truly ritual lonely quiz romance rose alone journey like bronze")
-		err := ec.RecreateWallet(context.TODO(), pass, seed)
+		err := ec.RecreateWallet(context.TODO(), pass, seed, passphrase)
 		if err != nil {
 			fmt.Println(err)
 		}
 	} else if net == "mainnet" {
-		err := ec.RecreateWallet(context.TODO(), pass, seed)
+		err := ec.RecreateWallet(context.TODO(), pass, seed, passphrase)
 		if err != nil {
 			fmt.Println(err)
 		}
 	}
+	printFingerprint(ec)
 
 	ec.Stop()
 }
+
+func printFingerprint(ec client.ElectrumClient) {
+	fingerprint, err := ec.MasterFingerprint()
+	if err != nil {
+		return
+	}
+	fmt.Println("master key fingerprint:", fingerprint)
+}
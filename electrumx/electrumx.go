@@ -76,5 +76,6 @@ type ElectrumXNode interface {
 	GetRawTransaction(ctx context.Context, txid string) (string, error)
 	//
 	EstimateFeeRate(ctx context.Context, confTarget int64) (int64, error)
+	GetFeeHistogram(ctx context.Context) (FeeHistogramResult, error)
 	Broadcast(ctx context.Context, rawTx string) (string, error)
 }
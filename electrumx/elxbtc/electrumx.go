@@ -307,6 +307,13 @@ func (s *SingleNode) EstimateFeeRate(ctx context.Context, confTarget int64) (int
 	return s.server.conn.EstimateFee(ctx, confTarget)
 }
 
+func (s *SingleNode) GetFeeHistogram(ctx context.Context) (electrumx.FeeHistogramResult, error) {
+	if !s.serverRunning() {
+		return nil, ErrServerNotRunning
+	}
+	return s.server.conn.GetFeeHistogram(ctx)
+}
+
 // /////////////////////////////////////////////////////////////////////////////
 // MultiNode
 // //////////
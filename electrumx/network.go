@@ -864,3 +864,18 @@ func (sc *ServerConn) EstimateFee(ctx context.Context, confTarget int64) (int64,
 	}
 	return int64(resp * 1e8), nil
 }
+
+// FeeHistogramResult is the mempool fee histogram as a list of [fee rate,
+// vsize] pairs. Fee rates are in satoshis per virtual byte in descending order
+// and vsize is the total virtual size of the mempool txs in each bin.
+type FeeHistogramResult [][2]float64
+
+// GetFeeHistogram returns the server's mempool fee histogram.
+func (sc *ServerConn) GetFeeHistogram(ctx context.Context) (FeeHistogramResult, error) {
+	var resp FeeHistogramResult
+	err := sc.Request(ctx, "mempool.get_fee_histogram", nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -8,6 +8,7 @@ require (
 	github.com/btcsuite/btcd/btcutil v1.1.5
 	github.com/btcsuite/btcd/chaincfg/chainhash v1.1.0
 	github.com/btcsuite/btcwallet/wallet/txauthor v1.3.4
+	github.com/btcsuite/btcwallet/wallet/txsizes v1.2.4
 	github.com/decred/go-socks v1.1.0
 	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/spf13/cast v1.6.0
@@ -20,7 +21,6 @@ replace go.etcd.io/bbolt => github.com/etcd-io/bbolt v1.3.9
 
 require (
 	github.com/aead/siphash v1.0.1 // indirect
-	github.com/btcsuite/btcwallet/wallet/txsizes v1.2.4 // indirect
 	github.com/kkdai/bstream v1.0.0 // indirect
 )
 
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+)
+
+// BIP44 style account discovery. Wallets restored from a seed made by another
+// wallet may have history on any of the standard derivation paths
+//
+//	m / purpose' / coin_type' / account' / change / address_index
+//
+// so discovery scans the receive and change chains of successive accounts
+// for each purpose, stopping at the first account with no history.
+
+// Standard derivation purposes
+const (
+	PurposeBIP44 uint32 = 44 // p2pkh
+	PurposeBIP49 uint32 = 49 // p2sh-p2wpkh
+	PurposeBIP84 uint32 = 84 // p2wpkh
+	PurposeBIP86 uint32 = 86 // p2tr
+)
+
+// DiscoveryPurposes are the purposes scanned by account discovery, in order
+var DiscoveryPurposes = []uint32{PurposeBIP44, PurposeBIP49, PurposeBIP84, PurposeBIP86}
+
+// ErrInvalidAccount is returned for an account with an unknown purpose or an
+// address type that does not match its purpose
+var ErrInvalidAccount = errors.New("invalid account")
+
+// PurposeAddressType returns the address type of a standard purpose
+func PurposeAddressType(purpose uint32) (AddressType, error) {
+	switch purpose {
+	case PurposeBIP44:
+		return AddrP2PKH, nil
+	case PurposeBIP49:
+		return AddrP2SHP2WPKH, nil
+	case PurposeBIP84:
+		return AddrP2WPKH, nil
+	case PurposeBIP86:
+		return AddrP2TR, nil
+	}
+	return 0, fmt.Errorf("%w: unknown purpose %d", ErrInvalidAccount, purpose)
+}
+
+// Account is a standard account m/purpose'/coin_type'/account'
+type Account struct {
+	Purpose     uint32
+	CoinType    uint32
+	Index       uint32
+	AddressType AddressType
+	// Highest receive and change address indexes with history or -1 if none
+	LastReceive int
+	LastChange  int
+}
+
+// NewAccount makes an account with no history for a standard purpose
+func NewAccount(purpose, coinType, index uint32) (*Account, error) {
+	addrType, err := PurposeAddressType(purpose)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		Purpose:     purpose,
+		CoinType:    coinType,
+		Index:       index,
+		AddressType: addrType,
+		LastReceive: -1,
+		LastChange:  -1,
+	}, nil
+}
+
+// Path returns the hardened derivation path of the account
+func (a *Account) Path() []uint32 {
+	return []uint32{
+		hdkeychain.HardenedKeyStart + a.Purpose,
+		hdkeychain.HardenedKeyStart + a.CoinType,
+		hdkeychain.HardenedKeyStart + a.Index,
+	}
+}
+
+// String returns the account path, for example m/84'/0'/0'
+func (a *Account) String() string {
+	return "m/" + FormatPath(a.Path())
+}
+
+// HasHistory returns true if any account address has history
+func (a *Account) HasHistory() bool {
+	return a.LastReceive >= 0 || a.LastChange >= 0
+}
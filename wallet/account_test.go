@@ -0,0 +1,23 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewAccount(t *testing.T) {
+	account, err := NewAccount(PurposeBIP84, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.String() != "m/84'/1'/2'" {
+		t.Fatalf("expected m/84'/1'/2' - got %s", account)
+	}
+	if account.AddressType != AddrP2WPKH || account.HasHistory() {
+		t.Fatalf("unexpected account %+v", account)
+	}
+	_, err = NewAccount(45, 0, 0)
+	if !errors.Is(err, ErrInvalidAccount) {
+		t.Fatalf("expected %v - got %v", ErrInvalidAccount, err)
+	}
+}
@@ -46,3 +46,40 @@ func (c *CfgDB) GetCreationDate() (time.Time, error) {
 	})
 	return t, e
 }
+
+var settingPrefix = []byte("setting.")
+
+func settingKey(key string) []byte {
+	return append(append([]byte{}, settingPrefix...), key...)
+}
+
+func (c *CfgDB) PutSetting(key string, value []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(configBkt)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		return b.Put(settingKey(key), value)
+	})
+}
+
+func (c *CfgDB) GetSetting(key string) ([]byte, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var value []byte
+	e := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(configBkt)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		v := b.Get(settingKey(key))
+		if v != nil {
+			value = make([]byte, len(v))
+			copy(value, v)
+		}
+		return nil
+	})
+	return value, e
+}
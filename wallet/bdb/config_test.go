@@ -53,3 +53,28 @@ func TestConfig(t *testing.T) {
 	}
 	fmt.Println(time2.String())
 }
+
+func TestSetting(t *testing.T) {
+	if err := setupCfg(); err != nil {
+		t.Fatal(err)
+	}
+	defer teardownCfg()
+	value, err := config.GetSetting("gapLimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != nil {
+		t.Fatalf("expected no setting - got %q", value)
+	}
+	err = config.PutSetting("gapLimit", []byte("20"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err = config.GetSetting("gapLimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "20" {
+		t.Fatalf("expected 20 - got %q", value)
+	}
+}
@@ -21,6 +21,7 @@ var (
 	subscriptionsBkt = []byte("subscriptions")
 	configBkt        = []byte("config")
 	encBkt           = []byte("enc")
+	labelsBkt        = []byte("labels")
 )
 
 var ErrBucketNotFound = errors.New("cannot find bucket")
@@ -31,6 +32,7 @@ type BoltDatastore struct {
 	stxos         wallet.Stxos
 	txns          wallet.Txns
 	subscriptions wallet.Subscriptions
+	labels        wallet.Labels
 	cfg           wallet.Cfg
 	enc           wallet.Enc
 	db            *bolt.DB
@@ -85,6 +87,10 @@ func dbSetup(bdb *bolt.DB) (*BoltDatastore, error) {
 			db:   bdb,
 			lock: l,
 		},
+		labels: &LabelsDB{
+			db:   bdb,
+			lock: l,
+		},
 		db:   bdb,
 		lock: l,
 	}
@@ -113,6 +119,9 @@ func (db *BoltDatastore) Txns() wallet.Txns {
 func (db *BoltDatastore) Subscriptions() wallet.Subscriptions {
 	return db.subscriptions
 }
+func (db *BoltDatastore) Labels() wallet.Labels {
+	return db.labels
+}
 
 //		create table if not exists keys (scriptAddress text primary key not null, purpose integer, keyIndex integer, used integer);
 //		create table if not exists utxos (outpoint text primary key not null, value integer, height integer, scriptPubKey text, watchOnly integer, frozen integer);
@@ -121,6 +130,7 @@ func (db *BoltDatastore) Subscriptions() wallet.Subscriptions {
 //		create table if not exists subscriptions (scriptPubKey text primary key not null, electrumScripthash text, address text);
 //		create table if not exists config(key text primary key not null, value blob);
 //		create table if not exists enc(key text primary key not null, value blob);
+//		create table if not exists labels(type text not null, ref text not null, label text, origin text, spendable integer, primary key (type, ref));
 
 func initDatabaseBuckets(db *bolt.DB) error {
 	db.Update(func(tx *bolt.Tx) error {
@@ -172,5 +182,12 @@ func initDatabaseBuckets(db *bolt.DB) error {
 		}
 		return nil
 	})
+	db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(labelsBkt)
+		if err != nil {
+			return fmt.Errorf("create labels bucket: %s", err)
+		}
+		return nil
+	})
 	return nil
 }
@@ -1,33 +1,21 @@
 package bdb
 
 import (
-	"crypto/rand"
+	"bytes"
 	"errors"
 	"fmt"
-	"io"
-	"runtime"
 	"sync"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
 	bolt "go.etcd.io/bbolt"
-	"golang.org/x/crypto/argon2"
-	"golang.org/x/crypto/nacl/secretbox"
 )
 
 const STORAGE = "storage"
 
-var (
-	ErrBadPw = errors.New("bad password")
-	// Argon2 params
-	SALT    = []byte("2977958431d29f2d") // TODO: a good random for dev
-	TIME    = uint32(1)
-	MEM     = uint32(64 * 1024)
-	THREADS = uint8(runtime.NumCPU())
-	THRDMAX = uint8(255)
-	KEYLEN  = uint32(32)
-)
+var ErrBadPw = errors.New("bad password")
 
 type EncDB struct {
 	db   *bolt.DB
@@ -38,7 +26,7 @@ var storageKey = []byte(STORAGE)
 
 func (e *EncDB) PutEncrypted(b []byte, pw string) error {
 	// encrypt
-	eb, err := encryptBytes(b, pw)
+	eb, err := wallet.EncryptBytes(b, pw)
 	if err != nil {
 		return err
 	}
@@ -64,54 +52,86 @@ func (e *EncDB) PutEncrypted(b []byte, pw string) error {
 func (e *EncDB) GetDecrypted(pw string) ([]byte, error) {
 	// retreive from db , if exist
 	e.lock.RLock()
-	defer e.lock.RUnlock()
 	var value []byte
 	err := e.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket(encBkt)
 		if b == nil {
 			return ErrBucketNotFound
 		}
-		value = b.Get(storageKey)
+		value = bytes.Clone(b.Get(storageKey))
 		return nil
 	})
+	e.lock.RUnlock()
 	if err != nil {
 		return nil, err
 	}
-	return decryptBytes(value, pw)
-}
-
-func encryptBytes(unencrypted []byte, password string) ([]byte, error) {
-	secretKey := getEncryptionKey32(password)
-	var nonce [24]byte
-	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+	decrypted, legacy, err := wallet.DecryptBytes(value, pw)
+	if err != nil {
 		return nil, err
 	}
-	encrypted := secretbox.Seal(nonce[:], unencrypted, &nonce, &secretKey)
-	// nonce is the first 24 bytes of encrypted. the rest is the actual
-	// encryption result. [nonce 24][ ...the encryption result...]
-	return encrypted, nil
-}
-
-func decryptBytes(encrypted []byte, password string) ([]byte, error) {
-	secretKey := getEncryptionKey32(password)
-	var decryptNonce [24]byte
-	copy(decryptNonce[:], encrypted[:24])
-	decrypted, ok := secretbox.Open(nil, encrypted[24:], &decryptNonce, &secretKey)
-	if !ok {
-		return nil, errors.New("secretbox decryption error")
+	if legacy {
+		// migrate to a salted envelope; if this fails it is tried again on
+		// the next unlock
+		err = e.migrateLegacy(value, decrypted, pw)
+		if err != nil {
+			fmt.Printf("cannot migrate legacy encrypted storage: %v\n", err)
+		}
 	}
-	// decrypted is the decryption of the encrypted bytes with the pre-pended
-	// plaintext nonce stripped out
 	return decrypted, nil
 }
 
-func getEncryptionKey32(password string) [32]byte {
-	threads := THREADS
-	if threads > THRDMAX {
-		threads = THRDMAX
+// migrateLegacy re-encrypts b, decrypted from the legacy blob legacyBlob,
+// into an envelope. Nothing is done if the stored blob is no longer
+// legacyBlob, as when the password was changed since it was read.
+func (e *EncDB) migrateLegacy(legacyBlob, b []byte, pw string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(encBkt)
+		if bkt == nil {
+			return ErrBucketNotFound
+		}
+		if !bytes.Equal(bkt.Get(storageKey), legacyBlob) {
+			return nil
+		}
+		eb, err := wallet.EncryptBytes(b, pw)
+		if err != nil {
+			return err
+		}
+		if err := wallet.VerifyEncrypted(eb, b, pw); err != nil {
+			return err
+		}
+		return bkt.Put(storageKey, eb)
+	})
+}
+
+func (e *EncDB) ChangePassword(b []byte, oldPw, newPw string) error {
+	if newPw == "" {
+		return ErrBadPw
 	}
-	b := argon2.IDKey([]byte(password), SALT, TIME, MEM, threads, KEYLEN)
-	return ([32]byte)(b)
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(encBkt)
+		if bkt == nil {
+			return ErrBucketNotFound
+		}
+		// the old password must open the current blob
+		old, _, err := wallet.DecryptBytes(bkt.Get(storageKey), oldPw)
+		if err != nil {
+			return ErrBadPw
+		}
+		wallet.ZeroBytes(old)
+		eb, err := wallet.EncryptBytes(b, newPw)
+		if err != nil {
+			return err
+		}
+		if err := wallet.VerifyEncrypted(eb, b, newPw); err != nil {
+			return err
+		}
+		// returning an error before here rolls back the tx
+		return bkt.Put(storageKey, eb)
+	})
 }
 
 /////////////////////////////////
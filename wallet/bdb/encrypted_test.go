@@ -1,6 +1,7 @@
 package bdb
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
@@ -8,7 +9,10 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/dev-warrior777/go-electrum-client/wallet"
 	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
 )
 
 const pw = "abc"
@@ -64,6 +68,116 @@ func TestEncryptDecryptBytes(t *testing.T) {
 	}
 }
 
+func TestChangePassword(t *testing.T) {
+	if err := setupEnc(); err != nil {
+		t.Fatal("cannot create database")
+	}
+	defer teardownEnc()
+	err := enc.PutEncrypted([]byte("secret"), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.ChangePassword([]byte("new secret"), "wrong", "def")
+	if err != ErrBadPw {
+		t.Fatalf("expected %v - got %v", ErrBadPw, err)
+	}
+	// unchanged
+	ret, err := enc.GetDecrypted(pw)
+	if err != nil || string(ret) != "secret" {
+		t.Fatalf("expected the original blob - got %q %v", ret, err)
+	}
+
+	err = enc.ChangePassword([]byte("new secret"), pw, "def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = enc.GetDecrypted(pw)
+	if err == nil {
+		t.Fatal("old password still decrypts")
+	}
+	ret, err = enc.GetDecrypted("def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "new secret" {
+		t.Fatalf("expected the new blob - got %q", ret)
+	}
+}
+
+// encryptLegacy encrypts as before the envelope: [nonce 24][box]
+func encryptLegacy(unencrypted []byte, password string) []byte {
+	key := argon2.IDKey([]byte(password), wallet.Argon2Salt, wallet.Argon2Time, wallet.Argon2Mem, wallet.Argon2Threads, wallet.Argon2KeyLen)
+	secretKey := ([32]byte)(key)
+	var nonce [24]byte
+	rand.Read(nonce[:])
+	return secretbox.Seal(nonce[:], unencrypted, &nonce, &secretKey)
+}
+
+func TestLegacyMigration(t *testing.T) {
+	if err := setupEnc(); err != nil {
+		t.Fatal("cannot create database")
+	}
+	defer teardownEnc()
+	err := enc.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(encBkt).Put(storageKey, encryptLegacy([]byte("secret"), pw))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := enc.GetDecrypted(pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "secret" {
+		t.Fatalf("expected the legacy blob - got %q", ret)
+	}
+	var stored []byte
+	enc.db.View(func(tx *bolt.Tx) error {
+		stored = bytes.Clone(tx.Bucket(encBkt).Get(storageKey))
+		return nil
+	})
+	if _, legacy, err := wallet.DecryptBytes(stored, pw); err != nil || legacy {
+		t.Fatal("legacy blob was not migrated")
+	}
+	ret, err = enc.GetDecrypted(pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "secret" {
+		t.Fatalf("expected the migrated blob - got %q", ret)
+	}
+}
+
+func TestStaleLegacyMigration(t *testing.T) {
+	if err := setupEnc(); err != nil {
+		t.Fatal("cannot create database")
+	}
+	defer teardownEnc()
+	legacyBlob := encryptLegacy([]byte("secret"), pw)
+	err := enc.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(encBkt).Put(storageKey, legacyBlob)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the password changes after the legacy blob was read
+	err = enc.ChangePassword([]byte("secret"), pw, "def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.migrateLegacy(legacyBlob, []byte("secret"), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := enc.GetDecrypted("def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "secret" {
+		t.Fatalf("expected the new password blob - got %q", ret)
+	}
+}
+
 func TestWif(t *testing.T) {
 	err := PrivKeyToWif()
 	if err != nil {
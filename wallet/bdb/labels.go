@@ -0,0 +1,162 @@
+package bdb
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+	bolt "go.etcd.io/bbolt"
+)
+
+type LabelsDB struct {
+	db   *bolt.DB
+	lock *sync.RWMutex
+}
+
+func (l *LabelsDB) Put(label wallet.Label) error {
+	if !label.Type.Valid() {
+		return errors.New("invalid label type")
+	}
+	lrec := &labelRec{
+		Type:      string(label.Type),
+		Ref:       label.Ref,
+		Label:     label.Label,
+		Origin:    label.Origin,
+		Spendable: label.Spendable,
+	}
+	return l.put(lrec)
+}
+
+func (l *LabelsDB) Get(labelType wallet.LabelType, ref string) (wallet.Label, error) {
+	lrec, err := l.get(labelKey(string(labelType), ref))
+	if err != nil {
+		return wallet.Label{}, err
+	}
+	return lrec.label(), nil
+}
+
+func (l *LabelsDB) GetAll() ([]wallet.Label, error) {
+	var labels []wallet.Label
+	lrecList, err := l.getAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, lrec := range lrecList {
+		labels = append(labels, lrec.label())
+	}
+	return labels, nil
+}
+
+func (l *LabelsDB) Delete(labelType wallet.LabelType, ref string) error {
+	return l.delete(labelKey(string(labelType), ref))
+}
+
+// DB access record
+type labelRec struct {
+	// Unique key - type & ref
+	Type      string `json:"type"`
+	Ref       string `json:"ref"`
+	Label     string `json:"label"`
+	Origin    string `json:"origin,omitempty"`
+	Spendable *bool  `json:"spendable,omitempty"`
+}
+
+func (lrec *labelRec) label() wallet.Label {
+	return wallet.Label{
+		Type:      wallet.LabelType(lrec.Type),
+		Ref:       lrec.Ref,
+		Label:     lrec.Label,
+		Origin:    lrec.Origin,
+		Spendable: lrec.Spendable,
+	}
+}
+
+func labelKey(labelType, ref string) []byte {
+	return []byte(labelType + " " + ref)
+}
+
+func (l *LabelsDB) put(lrec *labelRec) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	key := labelKey(lrec.Type, lrec.Ref)
+	value, err := json.Marshal(lrec)
+	if err != nil {
+		return err
+	}
+
+	e := l.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(labelsBkt)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		err := b.Put(key, value)
+		return err
+	})
+
+	return e
+}
+
+func (l *LabelsDB) get(key []byte) (*labelRec, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var lrec labelRec
+	e := l.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(labelsBkt)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		value := b.Get(key)
+		if value == nil {
+			return errors.New("label not found")
+		}
+		err := json.Unmarshal(value, &lrec)
+		return err
+	})
+
+	return &lrec, e
+}
+
+func (l *LabelsDB) getAll() ([]labelRec, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	var lrecList []labelRec
+	e := l.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(labelsBkt)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		c := b.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var lrec labelRec
+			err := json.Unmarshal(v, &lrec)
+			if err != nil {
+				return err
+			}
+			lrecList = append(lrecList, lrec)
+		}
+		return nil
+	})
+
+	return lrecList, e
+}
+
+func (l *LabelsDB) delete(key []byte) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	e := l.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(labelsBkt)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		err := b.Delete(key)
+		return err
+	})
+
+	return e
+}
@@ -0,0 +1,182 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// BIP21 payment URIs: bitcoin:<address>[?amount=<btc>][&label=<label>]
+// [&message=<message>][&<param>=<value>]
+
+// ErrBadPaymentURI is returned for a BIP21 URI that cannot be parsed or paid
+var ErrBadPaymentURI = errors.New("invalid payment URI")
+
+const bip21Scheme = "bitcoin"
+
+// PaymentURI is a BIP21 payment request
+type PaymentURI struct {
+	// Address to pay. Nil for a lightning only request.
+	Address btcutil.Address
+	// Amount requested in satoshis. Zero if not given.
+	Amount int64
+	// Label for the recipient
+	Label string
+	// Message describing the payment
+	Message string
+	// Lightning is a BOLT11 invoice for paying over lightning instead
+	Lightning string
+	// Params holds any other parameters. Unknown parameters are ignored
+	// unless required, prefixed "req-", which fails the parse.
+	Params map[string]string
+}
+
+// ParsePaymentURI parses a BIP21 URI for a payment on the network of params.
+// The scheme and a bech32 address may be upper case, as in QR codes.
+func ParsePaymentURI(uri string, params *chaincfg.Params) (*PaymentURI, error) {
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(uri), ":")
+	if !ok || !strings.EqualFold(scheme, bip21Scheme) {
+		return nil, fmt.Errorf("%w: not a %s: URI", ErrBadPaymentURI, bip21Scheme)
+	}
+	addr, query, _ := strings.Cut(rest, "?")
+	p := &PaymentURI{}
+
+	if query != "" {
+		seen := make(map[string]bool)
+		for _, pair := range strings.Split(query, "&") {
+			if pair == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(pair, "=")
+			key = strings.ToLower(key)
+			value, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter %s: %v", ErrBadPaymentURI, key, err)
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("%w: duplicate parameter %s", ErrBadPaymentURI, key)
+			}
+			seen[key] = true
+			switch key {
+			case "amount":
+				p.Amount, err = parseBTCAmount(value)
+				if err != nil {
+					return nil, fmt.Errorf("%w: amount %q: %v", ErrBadPaymentURI, value, err)
+				}
+			case "label":
+				p.Label = value
+			case "message":
+				p.Message = value
+			case "lightning":
+				p.Lightning = value
+			default:
+				if strings.HasPrefix(key, "req-") {
+					return nil, fmt.Errorf("%w: unsupported required parameter %s", ErrBadPaymentURI, key)
+				}
+				if p.Params == nil {
+					p.Params = make(map[string]string)
+				}
+				p.Params[key] = value
+			}
+		}
+	}
+
+	if addr == "" {
+		if p.Lightning == "" {
+			return nil, fmt.Errorf("%w: no address", ErrBadPaymentURI)
+		}
+		return p, nil
+	}
+	address, err := btcutil.DecodeAddress(addr, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: address %s: %v", ErrBadPaymentURI, addr, err)
+	}
+	if !address.IsForNet(params) {
+		return nil, fmt.Errorf("%w: address %s is not for %s", ErrBadPaymentURI, addr, params.Name)
+	}
+	p.Address = address
+	return p, nil
+}
+
+// String returns the BIP21 URI
+func (p *PaymentURI) String() string {
+	var sb strings.Builder
+	sb.WriteString(bip21Scheme + ":")
+	if p.Address != nil {
+		sb.WriteString(p.Address.String())
+	}
+	var params []string
+	add := func(key, value string) {
+		if value != "" {
+			params = append(params, key+"="+bip21Escape(value))
+		}
+	}
+	if p.Amount > 0 {
+		add("amount", formatBTCAmount(p.Amount))
+	}
+	add("label", p.Label)
+	add("message", p.Message)
+	add("lightning", p.Lightning)
+	keys := make([]string, 0, len(p.Params))
+	for key := range p.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(key, p.Params[key])
+	}
+	if len(params) > 0 {
+		sb.WriteString("?" + strings.Join(params, "&"))
+	}
+	return sb.String()
+}
+
+// bip21Escape percent encodes a parameter value. Spaces are %20 not '+'.
+func bip21Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// parseBTCAmount parses a decimal BTC amount of up to 8 decimal places to
+// satoshis without floating point rounding
+func parseBTCAmount(s string) (int64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, errors.New("empty amount")
+	}
+	if len(frac) > 8 {
+		return 0, errors.New("more than 8 decimal places")
+	}
+	for _, part := range []string{whole, frac} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, errors.New("not a decimal number")
+			}
+		}
+	}
+	frac += strings.Repeat("0", 8-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+	btc, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || btc > btcutil.MaxSatoshi/btcutil.SatoshiPerBitcoin {
+		return 0, errors.New("amount too large")
+	}
+	sats, _ := strconv.ParseInt(frac, 10, 64)
+	amount := btc*btcutil.SatoshiPerBitcoin + sats
+	if amount > btcutil.MaxSatoshi {
+		return 0, errors.New("amount too large")
+	}
+	return amount, nil
+}
+
+// formatBTCAmount formats satoshis as a BTC amount without trailing zeros
+func formatBTCAmount(sats int64) string {
+	s := fmt.Sprintf("%d.%08d", sats/btcutil.SatoshiPerBitcoin, sats%btcutil.SatoshiPerBitcoin)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,83 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestParsePaymentURI(t *testing.T) {
+	const addr = "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"
+	p, err := ParsePaymentURI("BITCOIN:BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ?amount=0.0005&label=Luke-Jr&message=Donation%20for%20project%20xyz&foo=bar&lightning=lnbc1", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Address.String() != addr {
+		t.Fatalf("expected address %s - got %s", addr, p.Address)
+	}
+	if p.Amount != 50000 || p.Label != "Luke-Jr" || p.Message != "Donation for project xyz" ||
+		p.Lightning != "lnbc1" || p.Params["foo"] != "bar" {
+		t.Fatalf("unexpected parse %+v", p)
+	}
+	want := "bitcoin:" + addr + "?amount=0.0005&label=Luke-Jr&message=Donation%20for%20project%20xyz&lightning=lnbc1&foo=bar"
+	if p.String() != want {
+		t.Fatalf("expected %s - got %s", want, p.String())
+	}
+
+	// lightning only
+	p, err = ParsePaymentURI("bitcoin:?lightning=lnbc1", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Address != nil || p.Lightning != "lnbc1" {
+		t.Fatalf("unexpected parse %+v", p)
+	}
+
+	bad := []string{
+		"litecoin:" + addr,
+		"bitcoin:",
+		"bitcoin:" + addr + "?req-somethingyoudontunderstand=50",
+		"bitcoin:" + addr + "?amount=1&amount=2",
+		"bitcoin:" + addr + "?amount=0.000000001",
+		"bitcoin:" + addr + "?amount=1e3",
+		"bitcoin:" + addr + "?amount=-1",
+		"bitcoin:" + addr + "?amount=21000001",
+		"bitcoin:tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx",
+		"bitcoin:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+	}
+	for _, uri := range bad {
+		_, err := ParsePaymentURI(uri, &chaincfg.MainNetParams)
+		if !errors.Is(err, ErrBadPaymentURI) {
+			t.Fatalf("%s: expected %v - got %v", uri, ErrBadPaymentURI, err)
+		}
+	}
+}
+
+func TestBTCAmount(t *testing.T) {
+	tests := []struct {
+		s    string
+		sats int64
+	}{
+		{"1", 100000000},
+		{"20.3", 2030000000},
+		{".5", 50000000},
+		{"0.00000001", 1},
+		{"21000000", 2100000000000000},
+	}
+	for _, test := range tests {
+		sats, err := parseBTCAmount(test.s)
+		if err != nil {
+			t.Fatalf("%s: %v", test.s, err)
+		}
+		if sats != test.sats {
+			t.Fatalf("%s: expected %d - got %d", test.s, test.sats, sats)
+		}
+	}
+	if s := formatBTCAmount(2030000000); s != "20.3" {
+		t.Fatalf("expected 20.3 - got %s", s)
+	}
+	if s := formatBTCAmount(100000000); s != "1" {
+		t.Fatalf("expected 1 - got %s", s)
+	}
+}
@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BIP329 wallet label export format. Labels are exported as JSON Lines: one
+// JSON object per line.
+
+// ErrBadLabel is returned for a BIP329 record that cannot be imported
+var ErrBadLabel = errors.New("invalid label record")
+
+type bip329Record struct {
+	Type      string `json:"type"`
+	Ref       string `json:"ref"`
+	Label     string `json:"label"`
+	Origin    string `json:"origin,omitempty"`
+	Spendable *bool  `json:"spendable,omitempty"`
+}
+
+// ExportBIP329 writes labels to w as BIP329 JSON Lines.
+func ExportBIP329(w io.Writer, labels []Label) error {
+	enc := json.NewEncoder(w)
+	for _, label := range labels {
+		rec := &bip329Record{
+			Type:   string(label.Type),
+			Ref:    label.Ref,
+			Label:  label.Label,
+			Origin: label.Origin,
+		}
+		// spendable is only meaningful for outputs
+		if label.Type == LabelOutput {
+			rec.Spendable = label.Spendable
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportBIP329 reads BIP329 JSON Lines from r. Blank lines are skipped. Any
+// record with an unknown type or without a reference fails the whole import.
+func ImportBIP329(r io.Reader) ([]Label, error) {
+	var labels []Label
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rec bip329Record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w: %v", line, ErrBadLabel, err)
+		}
+		labelType := LabelType(rec.Type)
+		if !labelType.Valid() {
+			return nil, fmt.Errorf("line %d: %w: unknown type %q", line, ErrBadLabel, rec.Type)
+		}
+		if rec.Ref == "" {
+			return nil, fmt.Errorf("line %d: %w: missing ref", line, ErrBadLabel)
+		}
+		label := Label{
+			Type:   labelType,
+			Ref:    rec.Ref,
+			Label:  rec.Label,
+			Origin: rec.Origin,
+		}
+		if labelType == LabelOutput {
+			label.Spendable = rec.Spendable
+		}
+		labels = append(labels, label)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
@@ -0,0 +1,66 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBIP329RoundTrip(t *testing.T) {
+	spendable := false
+	labels := []Label{
+		{Type: LabelTx, Ref: "f91d0a8a78462bc59398f2c5d7a84fcff491c26ba54c4833478b202796c8aafd", Label: "Transaction", Origin: "wpkh([d34db33f/84'/0'/0'])"},
+		{Type: LabelAddr, Ref: "bc1q34aq5drpuwy3wgl9lhup9892qp6svr8ldzyy7c", Label: "Address"},
+		{Type: LabelOutput, Ref: "f91d0a8a78462bc59398f2c5d7a84fcff491c26ba54c4833478b202796c8aafd:0", Label: "Output", Spendable: &spendable},
+	}
+	var buf bytes.Buffer
+	if err := ExportBIP329(&buf, labels); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Fatalf("expected 3 lines - got %d", n)
+	}
+	imported, err := ImportBIP329(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != len(labels) {
+		t.Fatalf("expected %d labels - got %d", len(labels), len(imported))
+	}
+	for i, label := range imported {
+		if label.Type != labels[i].Type || label.Ref != labels[i].Ref ||
+			label.Label != labels[i].Label || label.Origin != labels[i].Origin {
+			t.Fatalf("label %d: expected %v - got %v", i, labels[i], label)
+		}
+	}
+	if imported[2].Spendable == nil || *imported[2].Spendable {
+		t.Fatal("expected output spendable=false")
+	}
+}
+
+func TestImportBIP329(t *testing.T) {
+	in := `{"type":"tx","ref":"abcd","label":"rent"}
+
+{"type":"output","ref":"abcd:1","label":"change","spendable":true}
+`
+	labels, err := ImportBIP329(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 {
+		t.Fatalf("expected 2 labels - got %d", len(labels))
+	}
+
+	bad := []string{
+		`{"type":"car","ref":"abcd","label":"x"}`,
+		`{"type":"tx","label":"x"}`,
+		`{"type":"tx",`,
+	}
+	for _, in := range bad {
+		_, err := ImportBIP329(strings.NewReader(in))
+		if !errors.Is(err, ErrBadLabel) {
+			t.Fatalf("%s: expected %v - got %v", in, ErrBadLabel, err)
+		}
+	}
+}
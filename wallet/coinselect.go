@@ -0,0 +1,490 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/btcsuite/btcwallet/wallet/txsizes"
+)
+
+// CoinSelector chooses which wallet coins to spend to pay for a transaction.
+//
+// Select is given the candidate coins and the selection parameters. It must
+// return all of params.Required plus enough of candidates that the total
+// effective value covers params.Target plus the fee for the tx with no change
+// output. It returns ErrInsufficientFunds if that is not possible.
+type CoinSelector interface {
+	Select(candidates []Utxo, params *SelectionParams) ([]Utxo, error)
+}
+
+// SelectionParams describes the payment a CoinSelector selects coins for.
+type SelectionParams struct {
+	// Total value of the outputs to pay in satoshis
+	Target int64
+	// Fee rate for the tx in sats/vbyte
+	FeeRate int64
+	// Fee rate expected for spending coins in the future in sats/vbyte. This
+	// is used for the waste metric.
+	LongTermFeeRate int64
+	// Virtual size of the tx with no inputs and no change output. This should
+	// include the segwit marker & flag.
+	BaseVSize int
+	// Virtual size added to the tx by a change output
+	ChangeVSize int
+	// Virtual size of an input later spending the change output
+	ChangeSpendVSize int
+	// Smallest change output that will be made
+	MinChange int64
+	// Coins which must be spent
+	Required []Utxo
+}
+
+// Coin selection errors
+var (
+	// ErrNoChangelessSolution is returned by the branch and bound selector if
+	// it has no fallback and there is no selection needing no change.
+	ErrNoChangelessSolution = errors.New("no changeless coin selection found")
+)
+
+// InputVSize returns the virtual size that spending a coin with pkScript adds
+// to a tx.
+func InputVSize(pkScript []byte) int {
+	return txsizes.GetMinInputVirtualSize(pkScript)
+}
+
+// EffectiveValue returns the value of u less the fee to spend it.
+func (p *SelectionParams) EffectiveValue(u Utxo) int64 {
+	return u.Value - p.FeeRate*int64(InputVSize(u.ScriptPubkey))
+}
+
+// SelectionTarget returns the effective value the selected coins must have to
+// pay the outputs and the fee for a tx with no change.
+func (p *SelectionParams) SelectionTarget() int64 {
+	return p.Target + p.FeeRate*int64(p.BaseVSize)
+}
+
+// CostOfChange returns the cost of making a change output now and spending it
+// later.
+func (p *SelectionParams) CostOfChange() int64 {
+	return p.FeeRate*int64(p.ChangeVSize) + p.LongTermFeeRate*int64(p.ChangeSpendVSize)
+}
+
+// SelectionWaste returns the waste metric for selected coins. The waste is
+// the extra fee paid for spending the inputs now rather than at the long term
+// fee rate plus either the cost of change or, if there is no change, the
+// excess given to the miner. Lower is better.
+func SelectionWaste(selected []Utxo, params *SelectionParams, hasChange bool) int64 {
+	var waste, effective int64
+	for _, u := range selected {
+		waste += (params.FeeRate - params.LongTermFeeRate) * int64(InputVSize(u.ScriptPubkey))
+		effective += params.EffectiveValue(u)
+	}
+	if hasChange {
+		waste += params.CostOfChange()
+	} else {
+		waste += effective - params.SelectionTarget()
+	}
+	return waste
+}
+
+// effectiveValues sums the effective value of coins
+func effectiveValues(coins []Utxo, params *SelectionParams) int64 {
+	var total int64
+	for _, u := range coins {
+		total += params.EffectiveValue(u)
+	}
+	return total
+}
+
+// remainingTarget returns the effective value still needed after the
+// required coins.
+func remainingTarget(params *SelectionParams) int64 {
+	return params.SelectionTarget() - effectiveValues(params.Required, params)
+}
+
+// positiveCandidates returns the candidates with a positive effective value.
+// Coins costing more to spend than they are worth are never selected.
+func positiveCandidates(candidates []Utxo, params *SelectionParams) []Utxo {
+	var coins []Utxo
+	for _, u := range candidates {
+		if params.EffectiveValue(u) > 0 {
+			coins = append(coins, u)
+		}
+	}
+	return coins
+}
+
+// accumulate selects coins in order until the target is met.
+func accumulate(coins []Utxo, params *SelectionParams) ([]Utxo, error) {
+	selected := append([]Utxo{}, params.Required...)
+	needed := remainingTarget(params)
+	for _, u := range coins {
+		if needed <= 0 {
+			break
+		}
+		selected = append(selected, u)
+		needed -= params.EffectiveValue(u)
+	}
+	if needed > 0 {
+		return nil, ErrInsufficientFunds
+	}
+	return selected, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// Largest first
+// /////////////
+
+// LargestFirstSelector selects the largest coins first. This uses the least
+// inputs.
+type LargestFirstSelector struct{}
+
+func (s *LargestFirstSelector) Select(candidates []Utxo, params *SelectionParams) ([]Utxo, error) {
+	coins := positiveCandidates(candidates, params)
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].Value > coins[j].Value
+	})
+	return accumulate(coins, params)
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// Oldest first
+// ////////////
+
+// OldestFirstSelector selects the oldest coins first. Unconfirmed coins are
+// selected last.
+type OldestFirstSelector struct{}
+
+func (s *OldestFirstSelector) Select(candidates []Utxo, params *SelectionParams) ([]Utxo, error) {
+	coins := positiveCandidates(candidates, params)
+	sort.SliceStable(coins, func(i, j int) bool {
+		hi, hj := coins[i].AtHeight, coins[j].AtHeight
+		if hi <= 0 {
+			hi = math.MaxInt64
+		}
+		if hj <= 0 {
+			hj = math.MaxInt64
+		}
+		return hi < hj
+	})
+	return accumulate(coins, params)
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// Knapsack
+// ////////
+
+// KnapsackSelector is a port of the original Bitcoin Core selection. It looks
+// for an exact match, then the best of many random subsets of the coins
+// smaller than the target, then the smallest coin larger than the target.
+type KnapsackSelector struct {
+	// Number of random subsets to try. Defaults to 1000.
+	Iterations int
+}
+
+func (s *KnapsackSelector) Select(candidates []Utxo, params *SelectionParams) ([]Utxo, error) {
+	selected := append([]Utxo{}, params.Required...)
+	target := remainingTarget(params)
+	if target <= 0 {
+		return selected, nil
+	}
+	iterations := s.Iterations
+	if iterations <= 0 {
+		iterations = 1000
+	}
+
+	coins := positiveCandidates(candidates, params)
+	var smaller []Utxo
+	var smallerTotal int64
+	var lowestLarger *Utxo
+	for i, u := range coins {
+		v := params.EffectiveValue(u)
+		switch {
+		case v == target:
+			return append(selected, u), nil
+		case v < target+params.MinChange:
+			smaller = append(smaller, u)
+			smallerTotal += v
+		case lowestLarger == nil || v < params.EffectiveValue(*lowestLarger):
+			lowestLarger = &coins[i]
+		}
+	}
+
+	if smallerTotal == target {
+		return append(selected, smaller...), nil
+	}
+	if smallerTotal < target {
+		if lowestLarger == nil {
+			return nil, ErrInsufficientFunds
+		}
+		return append(selected, *lowestLarger), nil
+	}
+
+	sort.SliceStable(smaller, func(i, j int) bool {
+		return params.EffectiveValue(smaller[i]) > params.EffectiveValue(smaller[j])
+	})
+	best, bestValue := approximateBestSubset(smaller, params, target, iterations)
+	if bestValue != target && smallerTotal >= target+params.MinChange {
+		best, bestValue = approximateBestSubset(smaller, params, target+params.MinChange, iterations)
+	}
+
+	// prefer the smallest larger coin if it is closer to the target
+	if lowestLarger != nil &&
+		((bestValue != target && bestValue < target+params.MinChange) ||
+			params.EffectiveValue(*lowestLarger) <= bestValue) {
+		return append(selected, *lowestLarger), nil
+	}
+	return append(selected, best...), nil
+}
+
+// approximateBestSubset tries random subsets of coins, which are sorted by
+// descending value, looking for the smallest total at or above target.
+func approximateBestSubset(coins []Utxo, params *SelectionParams, target int64, iterations int) ([]Utxo, int64) {
+	values := make([]int64, len(coins))
+	var bestValue int64
+	best := make([]bool, len(coins))
+	for i, u := range coins {
+		values[i] = params.EffectiveValue(u)
+		best[i] = true
+		bestValue += values[i]
+	}
+
+	included := make([]bool, len(coins))
+	for rep := 0; rep < iterations && bestValue != target; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+		var total int64
+		reachedTarget := false
+		for pass := 0; pass < 2 && !reachedTarget; pass++ {
+			for i := range coins {
+				// the first pass picks randomly, the second fills in
+				// the coins not picked on the first
+				var pick bool
+				if pass == 0 {
+					pick = rand.Intn(2) == 1
+				} else {
+					pick = !included[i]
+				}
+				if !pick {
+					continue
+				}
+				total += values[i]
+				included[i] = true
+				if total >= target {
+					reachedTarget = true
+					if total < bestValue {
+						bestValue = total
+						copy(best, included)
+					}
+					total -= values[i]
+					included[i] = false
+				}
+			}
+		}
+	}
+
+	var subset []Utxo
+	for i, u := range coins {
+		if best[i] {
+			subset = append(subset, u)
+		}
+	}
+	return subset, bestValue
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// Branch and bound
+// ////////////////
+
+// BranchAndBoundSelector searches for a selection that needs no change output
+// and has the least waste. The selection may exceed the target by no more
+// than the cost of change which is given to the miner.
+type BranchAndBoundSelector struct {
+	// Maximum number of search steps. Defaults to 100000.
+	MaxTries int
+	// Selector used if there is no changeless solution. If nil
+	// ErrNoChangelessSolution is returned.
+	Fallback CoinSelector
+}
+
+func (s *BranchAndBoundSelector) Select(candidates []Utxo, params *SelectionParams) ([]Utxo, error) {
+	selected, err := s.search(candidates, params)
+	if err == nil {
+		return selected, nil
+	}
+	if s.Fallback != nil {
+		return s.Fallback.Select(candidates, params)
+	}
+	return nil, err
+}
+
+func (s *BranchAndBoundSelector) search(candidates []Utxo, params *SelectionParams) ([]Utxo, error) {
+	selected := append([]Utxo{}, params.Required...)
+	target := remainingTarget(params)
+	if target <= 0 {
+		return selected, nil
+	}
+	upper := target + params.CostOfChange()
+	maxTries := s.MaxTries
+	if maxTries <= 0 {
+		maxTries = 100000
+	}
+
+	coins := positiveCandidates(candidates, params)
+	sort.SliceStable(coins, func(i, j int) bool {
+		return params.EffectiveValue(coins[i]) > params.EffectiveValue(coins[j])
+	})
+	values := make([]int64, len(coins))
+	inputWaste := make([]int64, len(coins))
+	var available int64
+	for i, u := range coins {
+		values[i] = params.EffectiveValue(u)
+		inputWaste[i] = (params.FeeRate - params.LongTermFeeRate) * int64(InputVSize(u.ScriptPubkey))
+		available += values[i]
+	}
+	if available < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	var best []bool
+	bestWaste := int64(math.MaxInt64)
+	current := make([]bool, len(coins))
+	tries := 0
+
+	var walk func(i int, value, waste, remaining int64)
+	walk = func(i int, value, waste, remaining int64) {
+		tries++
+		if tries > maxTries {
+			return
+		}
+		if value > upper || value+remaining < target {
+			return
+		}
+		// when fees are above the long term rate adding inputs only adds
+		// waste
+		if params.FeeRate > params.LongTermFeeRate && waste > bestWaste {
+			return
+		}
+		if value >= target {
+			w := waste + value - target
+			if w < bestWaste {
+				bestWaste = w
+				best = append(best[:0], current...)
+			}
+			return
+		}
+		if i == len(coins) {
+			return
+		}
+		current[i] = true
+		walk(i+1, value+values[i], waste+inputWaste[i], remaining-values[i])
+		current[i] = false
+		walk(i+1, value, waste, remaining-values[i])
+	}
+	walk(0, 0, 0, available)
+
+	if best == nil {
+		return nil, ErrNoChangelessSolution
+	}
+	for i, u := range coins {
+		if best[i] {
+			selected = append(selected, u)
+		}
+	}
+	return selected, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// Privacy
+// ///////
+
+// PrivacySelector avoids merging address clusters. Coins paid to the same
+// address are always spent together and the fewest clusters are joined in one
+// tx. Clusters of any required coins are used first as they are already
+// linked by the tx.
+type PrivacySelector struct{}
+
+type coinCluster struct {
+	coins []Utxo
+	value int64
+}
+
+func (s *PrivacySelector) Select(candidates []Utxo, params *SelectionParams) ([]Utxo, error) {
+	selected := append([]Utxo{}, params.Required...)
+	needed := remainingTarget(params)
+
+	clusterIndex := make(map[string]int)
+	var clusters []*coinCluster
+	for _, u := range candidates {
+		key := hex.EncodeToString(u.ScriptPubkey)
+		i, ok := clusterIndex[key]
+		if !ok {
+			i = len(clusters)
+			clusterIndex[key] = i
+			clusters = append(clusters, &coinCluster{})
+		}
+		clusters[i].coins = append(clusters[i].coins, u)
+		clusters[i].value += params.EffectiveValue(u)
+	}
+
+	// clusters already linked by the required coins
+	used := make([]bool, len(clusters))
+	for _, u := range params.Required {
+		i, ok := clusterIndex[hex.EncodeToString(u.ScriptPubkey)]
+		if !ok || used[i] {
+			continue
+		}
+		used[i] = true
+		selected = append(selected, clusters[i].coins...)
+		needed -= clusters[i].value
+	}
+	if needed <= 0 {
+		return selected, nil
+	}
+
+	// a single cluster with the least excess
+	bestSingle := -1
+	for i, c := range clusters {
+		if used[i] || c.value < needed {
+			continue
+		}
+		if bestSingle < 0 || c.value < clusters[bestSingle].value {
+			bestSingle = i
+		}
+	}
+	if bestSingle >= 0 {
+		return append(selected, clusters[bestSingle].coins...), nil
+	}
+
+	// else join the fewest clusters, largest first
+	order := make([]int, 0, len(clusters))
+	for i := range clusters {
+		if !used[i] && clusters[i].value > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return clusters[order[a]].value > clusters[order[b]].value
+	})
+	for _, i := range order {
+		if needed <= 0 {
+			break
+		}
+		selected = append(selected, clusters[i].coins...)
+		needed -= clusters[i].value
+	}
+	if needed > 0 {
+		return nil, ErrInsufficientFunds
+	}
+	return selected, nil
+}
+
+// DefaultCoinSelector returns the selector used when none is configured. It is
+// branch and bound falling back to knapsack.
+func DefaultCoinSelector() CoinSelector {
+	return &BranchAndBoundSelector{Fallback: &KnapsackSelector{}}
+}
@@ -0,0 +1,166 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var p2wpkhA = []byte{0x00, 0x14, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1, 0xa1}
+var p2wpkhB = []byte{0x00, 0x14, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2, 0xb2}
+
+func testUtxo(n byte, value, height int64, script []byte) Utxo {
+	return Utxo{
+		Op:           *wire.NewOutPoint(&chainhash.Hash{n}, uint32(n)),
+		AtHeight:     height,
+		Value:        value,
+		ScriptPubkey: script,
+	}
+}
+
+func testParams(target int64) *SelectionParams {
+	return &SelectionParams{
+		Target:           target,
+		FeeRate:          1,
+		LongTermFeeRate:  1,
+		BaseVSize:        11,
+		ChangeVSize:      31,
+		ChangeSpendVSize: 68,
+		MinChange:        1000,
+	}
+}
+
+func selectedValue(selected []Utxo) int64 {
+	var total int64
+	for _, u := range selected {
+		total += u.Value
+	}
+	return total
+}
+
+func TestLargestFirstSelector(t *testing.T) {
+	coins := []Utxo{
+		testUtxo(1, 10000, 100, p2wpkhA),
+		testUtxo(2, 50000, 200, p2wpkhA),
+		testUtxo(3, 30000, 300, p2wpkhA),
+	}
+	s := &LargestFirstSelector{}
+	selected, err := s.Select(coins, testParams(60000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].Value != 50000 || selected[1].Value != 30000 {
+		t.Fatalf("expected the two largest coins - got %v", selected)
+	}
+	_, err = s.Select(coins, testParams(100000))
+	if err != ErrInsufficientFunds {
+		t.Fatalf("expected %v - got %v", ErrInsufficientFunds, err)
+	}
+}
+
+func TestOldestFirstSelector(t *testing.T) {
+	coins := []Utxo{
+		testUtxo(1, 10000, 0, p2wpkhA),
+		testUtxo(2, 50000, 200, p2wpkhA),
+		testUtxo(3, 30000, 100, p2wpkhA),
+	}
+	s := &OldestFirstSelector{}
+	selected, err := s.Select(coins, testParams(20000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].AtHeight != 100 {
+		t.Fatalf("expected the oldest coin - got %v", selected)
+	}
+}
+
+func TestBranchAndBoundSelector(t *testing.T) {
+	params := testParams(0)
+	coins := []Utxo{
+		testUtxo(1, 100000, 100, p2wpkhA),
+		testUtxo(2, 40000, 100, p2wpkhA),
+		testUtxo(3, 25000, 100, p2wpkhA),
+		testUtxo(4, 15000, 100, p2wpkhA),
+	}
+	// pay exactly the effective value of coins 2 & 4
+	params.Target = params.EffectiveValue(coins[1]) + params.EffectiveValue(coins[3]) - int64(params.BaseVSize)
+	s := &BranchAndBoundSelector{}
+	selected, err := s.Select(coins, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selectedValue(selected) != 55000 {
+		t.Fatalf("expected an exact changeless match - got %v", selected)
+	}
+	if waste := SelectionWaste(selected, params, false); waste != 0 {
+		t.Fatalf("expected zero waste - got %d", waste)
+	}
+
+	// no changeless match
+	params.Target = 1000
+	_, err = s.Select(coins, params)
+	if err != ErrNoChangelessSolution {
+		t.Fatalf("expected %v - got %v", ErrNoChangelessSolution, err)
+	}
+	s.Fallback = &LargestFirstSelector{}
+	selected, err = s.Select(coins, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].Value != 100000 {
+		t.Fatalf("expected the fallback selection - got %v", selected)
+	}
+}
+
+func TestKnapsackSelector(t *testing.T) {
+	params := testParams(0)
+	coins := []Utxo{
+		testUtxo(1, 100000, 100, p2wpkhA),
+		testUtxo(2, 40000, 100, p2wpkhA),
+		testUtxo(3, 25000, 100, p2wpkhA),
+	}
+	// exact match of a single coin
+	params.Target = params.EffectiveValue(coins[2]) - int64(params.BaseVSize)
+	s := &KnapsackSelector{}
+	selected, err := s.Select(coins, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].Value != 25000 {
+		t.Fatalf("expected the exact match coin - got %v", selected)
+	}
+	params.Target = 200000
+	_, err = s.Select(coins, params)
+	if err != ErrInsufficientFunds {
+		t.Fatalf("expected %v - got %v", ErrInsufficientFunds, err)
+	}
+}
+
+func TestPrivacySelector(t *testing.T) {
+	coins := []Utxo{
+		testUtxo(1, 60000, 100, p2wpkhA),
+		testUtxo(2, 30000, 100, p2wpkhB),
+		testUtxo(3, 30000, 100, p2wpkhB),
+	}
+	s := &PrivacySelector{}
+	// address B's cluster pays with the least excess and both of its coins
+	// are spent together
+	selected, err := s.Select(coins, testParams(50000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].Value != 30000 || selected[1].Value != 30000 {
+		t.Fatalf("expected the address B cluster - got %v", selected)
+	}
+	// a required coin from address A brings its cluster
+	params := testParams(50000)
+	params.Required = []Utxo{coins[0]}
+	selected, err = s.Select(coins[1:], params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 {
+		t.Fatalf("expected only the required coin - got %v", selected)
+	}
+}
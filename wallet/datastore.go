@@ -92,16 +92,27 @@ type Datastore interface {
 	Txns() Txns
 	Keys() Keys
 	Subscriptions() Subscriptions
+	Labels() Labels
 }
 
 type Cfg interface {
 	PutCreationDate(date time.Time) error
 	GetCreationDate() (time.Time, error)
+
+	// Put a wallet setting
+	PutSetting(key string, value []byte) error
+
+	// Get a wallet setting. Returns nil if the setting is not stored.
+	GetSetting(key string) ([]byte, error)
 }
 
 type Enc interface {
 	PutEncrypted(b []byte, pw string) error
 	GetDecrypted(pw string) ([]byte, error)
+	// ChangePassword atomically replaces the stored blob, which must decrypt
+	// with oldPw, with b encrypted with newPw. The new blob is verified to
+	// decrypt before it is committed.
+	ChangePassword(b []byte, oldPw, newPw string) error
 }
 
 type Utxos interface {
@@ -205,6 +216,23 @@ type Subscriptions interface {
 	Delete(scriptPubkey string) error
 }
 
+// Labels stores user labels for wallet addresses, transactions, outputs and
+// other references. Labels follow BIP329.
+type Labels interface {
+	// Put a label to the database replacing any existing label for the
+	// same type and reference
+	Put(label Label) error
+
+	// Fetch the label for a type and reference
+	Get(labelType LabelType, ref string) (Label, error)
+
+	// Fetch all labels
+	GetAll() ([]Label, error)
+
+	// Delete the label for a type and reference
+	Delete(labelType LabelType, ref string) error
+}
+
 type Subscription struct {
 	// wallet subscribe watch list public key script; hex string
 	PkScript string
@@ -258,6 +286,10 @@ type Utxo struct {
 	//
 	// It is the outside software's responsibility to set this.
 	Frozen bool
+
+	// The label of the output or else of its address. This is not saved with
+	// the utxo but is filled from the labels store when utxos are listed.
+	Label string
 }
 
 func (utxo *Utxo) IsEqual(alt *Utxo) bool {
@@ -347,6 +379,10 @@ type Txn struct {
 	// If the Status is Error the ErrorMessage should describe the problem
 	ErrorMessage string
 
+	// If the Status is Replaced the txid of the conflicting tx that replaced
+	// it, if known. Like the status this is calculated when read.
+	ReplacedBy string
+
 	// Raw transaction bytes
 	Bytes []byte
 
@@ -364,9 +400,49 @@ const (
 	StatusConfirmed   StatusCode = "CONFIRMED"
 	StatusStuck       StatusCode = "STUCK"
 	StatusDead        StatusCode = "DEAD"
+	StatusReplaced    StatusCode = "REPLACED"
 	StatusError       StatusCode = "ERROR"
 )
 
+// LabelType is the BIP329 type of the reference a label is attached to
+type LabelType string
+
+const (
+	LabelTx     LabelType = "tx"
+	LabelAddr   LabelType = "addr"
+	LabelPubkey LabelType = "pubkey"
+	LabelInput  LabelType = "input"
+	LabelOutput LabelType = "output"
+	LabelXpub   LabelType = "xpub"
+)
+
+// Valid returns true for the BIP329 label types
+func (t LabelType) Valid() bool {
+	switch t {
+	case LabelTx, LabelAddr, LabelPubkey, LabelInput, LabelOutput, LabelXpub:
+		return true
+	}
+	return false
+}
+
+type Label struct {
+	// The type of reference
+	Type LabelType
+
+	// The reference: a txid, address, pubkey, xpub or "txid:index" for
+	// inputs and outputs
+	Ref string
+
+	// The label text
+	Label string
+
+	// Optional origin key path of the reference, BIP329 descriptor style
+	Origin string
+
+	// Optional spendable flag for outputs. Nil if not set.
+	Spendable *bool
+}
+
 type KeyPath struct {
 	Purpose KeyPurpose
 	Index   int
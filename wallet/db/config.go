@@ -48,3 +48,45 @@ func (s *CfgDB) PutCreationDate(creationDate time.Time) error {
 	tx.Commit()
 	return nil
 }
+
+const settingPrefix = "setting."
+
+func (s *CfgDB) PutSetting(key string, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("insert or replace into config(key, value) values(?,?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(settingPrefix+key, value)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *CfgDB) GetSetting(key string) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	stmt, err := s.db.Prepare("select value from config where key=?")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	var value []byte
+	err = stmt.QueryRow(settingPrefix + key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
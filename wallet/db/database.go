@@ -19,6 +19,7 @@ type SQLiteDatastore struct {
 	stxos         wallet.Stxos
 	txns          wallet.Txns
 	subscriptions wallet.Subscriptions
+	labels        wallet.Labels
 	db            *sql.DB
 	lock          *sync.RWMutex
 }
@@ -60,6 +61,10 @@ func Create(repoPath string) (*SQLiteDatastore, error) {
 			db:   conn,
 			lock: l,
 		},
+		labels: &LabelsDB{
+			db:   conn,
+			lock: l,
+		},
 		db:   conn,
 		lock: l,
 	}
@@ -88,6 +93,9 @@ func (db *SQLiteDatastore) Txns() wallet.Txns {
 func (db *SQLiteDatastore) Subscriptions() wallet.Subscriptions {
 	return db.subscriptions
 }
+func (db *SQLiteDatastore) Labels() wallet.Labels {
+	return db.labels
+}
 
 func initDatabaseTables(db *sql.DB) error {
 	var sqlStmt string
@@ -99,6 +107,7 @@ func initDatabaseTables(db *sql.DB) error {
 	create table if not exists subscriptions (scriptPubKey text primary key not null, electrumScripthash text, address text);
 	create table if not exists config(key text primary key not null, value blob);
 	create table if not exists enc(key text primary key not null, value blob);
+	create table if not exists labels (type text not null, ref text not null, label text, origin text, spendable integer, primary key (type, ref));
 	`
 	_, err := db.Exec(sqlStmt)
 	if err != nil {
@@ -1,33 +1,21 @@
 package db
 
 import (
-	"crypto/rand"
+	"bytes"
 	"database/sql"
 	"errors"
 	"fmt"
-	"io"
-	"runtime"
 	"sync"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
-	"golang.org/x/crypto/argon2"
-	"golang.org/x/crypto/nacl/secretbox"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
 )
 
 const STORAGE = "storage"
 
-var (
-	ErrBadPw = errors.New("bad password")
-	// Argon2 params
-	SALT    = []byte("2977958431d29f2d") // TODO: a good random for dev
-	TIME    = uint32(1)
-	MEM     = uint32(64 * 1024)
-	THREADS = uint8(runtime.NumCPU())
-	THRDMAX = uint8(255)
-	KEYLEN  = uint32(32)
-)
+var ErrBadPw = errors.New("bad password")
 
 type EncDB struct {
 	db   *sql.DB
@@ -36,7 +24,7 @@ type EncDB struct {
 
 func (e *EncDB) PutEncrypted(b []byte, pw string) error {
 	// encrypt
-	eb, err := encryptBytes(b, pw)
+	eb, err := wallet.EncryptBytes(b, pw)
 	if err != nil {
 		return err
 	}
@@ -64,53 +52,102 @@ func (e *EncDB) PutEncrypted(b []byte, pw string) error {
 func (e *EncDB) GetDecrypted(pw string) ([]byte, error) {
 	// retreive from db , if exist
 	e.lock.RLock()
-	defer e.lock.RUnlock()
-	stmt, err := e.db.Prepare("select value from enc where key=?")
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
 	var b []byte
-	err = stmt.QueryRow(STORAGE).Scan(&b)
+	err := e.db.QueryRow("select value from enc where key=?", STORAGE).Scan(&b)
+	e.lock.RUnlock()
 	if err != nil {
 		return nil, err
 	}
 	// decrypt
-	return decryptBytes(b, pw)
-}
-
-func encryptBytes(unencrypted []byte, password string) ([]byte, error) {
-	secretKey := getEncryptionKey32(password)
-	var nonce [24]byte
-	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+	decrypted, legacy, err := wallet.DecryptBytes(b, pw)
+	if err != nil {
 		return nil, err
 	}
-	encrypted := secretbox.Seal(nonce[:], unencrypted, &nonce, &secretKey)
-	// nonce is the first 24 bytes of encrypted. the rest is the actual
-	// encryption result. [nonce 24][ ...the encryption result...]
-	return encrypted, nil
+	if legacy {
+		// migrate to a salted envelope; if this fails it is tried again on
+		// the next unlock
+		err = e.migrateLegacy(b, decrypted, pw)
+		if err != nil {
+			fmt.Printf("cannot migrate legacy encrypted storage: %v\n", err)
+		}
+	}
+	return decrypted, nil
 }
 
-func decryptBytes(encrypted []byte, password string) ([]byte, error) {
-	secretKey := getEncryptionKey32(password)
-	var decryptNonce [24]byte
-	copy(decryptNonce[:], encrypted[:24])
-	decrypted, ok := secretbox.Open(nil, encrypted[24:], &decryptNonce, &secretKey)
-	if !ok {
-		return nil, errors.New("secretbox decryption error")
-	}
-	// decrypted is the decryption of the encrypted bytes with the pre-pended
-	// plaintext nonce stripped out
-	return decrypted, nil
+// migrateLegacy re-encrypts b, decrypted from the legacy blob legacyBlob,
+// into an envelope. Nothing is done if the stored blob is no longer
+// legacyBlob, as when the password was changed since it was read.
+func (e *EncDB) migrateLegacy(legacyBlob, b []byte, pw string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	var current []byte
+	err = tx.QueryRow("select value from enc where key=?", STORAGE).Scan(&current)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !bytes.Equal(current, legacyBlob) {
+		return tx.Rollback()
+	}
+	eb, err := wallet.EncryptBytes(b, pw)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := wallet.VerifyEncrypted(eb, b, pw); err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("update enc set value=? where key=? and value=?", eb, STORAGE, legacyBlob)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func getEncryptionKey32(password string) [32]byte {
-	threads := THREADS
-	if threads > THRDMAX {
-		threads = THRDMAX
+func (e *EncDB) ChangePassword(b []byte, oldPw, newPw string) error {
+	if newPw == "" {
+		return ErrBadPw
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	// the old password must open the current blob
+	var current []byte
+	err = tx.QueryRow("select value from enc where key=?", STORAGE).Scan(&current)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	old, _, err := wallet.DecryptBytes(current, oldPw)
+	if err != nil {
+		tx.Rollback()
+		return ErrBadPw
+	}
+	wallet.ZeroBytes(old)
+	eb, err := wallet.EncryptBytes(b, newPw)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := wallet.VerifyEncrypted(eb, b, newPw); err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("update enc set value=? where key=?", eb, STORAGE)
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
-	b := argon2.IDKey([]byte(password), SALT, TIME, MEM, threads, KEYLEN)
-	return ([32]byte)(b)
+	return tx.Commit()
 }
 
 /////////////////////////////////
@@ -7,6 +7,10 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
 )
 
 const pw = "abc"
@@ -42,6 +46,100 @@ func TestEncryptDecryptBytes(t *testing.T) {
 	}
 }
 
+func TestChangePassword(t *testing.T) {
+	err := enc.PutEncrypted([]byte("secret"), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.ChangePassword([]byte("new secret"), "wrong", "def")
+	if err != ErrBadPw {
+		t.Fatalf("expected %v - got %v", ErrBadPw, err)
+	}
+	// unchanged
+	ret, err := enc.GetDecrypted(pw)
+	if err != nil || string(ret) != "secret" {
+		t.Fatalf("expected the original blob - got %q %v", ret, err)
+	}
+
+	err = enc.ChangePassword([]byte("new secret"), pw, "def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = enc.GetDecrypted(pw)
+	if err == nil {
+		t.Fatal("old password still decrypts")
+	}
+	ret, err = enc.GetDecrypted("def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "new secret" {
+		t.Fatalf("expected the new blob - got %q", ret)
+	}
+}
+
+// encryptLegacy encrypts as before the envelope: [nonce 24][box]
+func encryptLegacy(unencrypted []byte, password string) []byte {
+	key := argon2.IDKey([]byte(password), wallet.Argon2Salt, wallet.Argon2Time, wallet.Argon2Mem, wallet.Argon2Threads, wallet.Argon2KeyLen)
+	secretKey := ([32]byte)(key)
+	var nonce [24]byte
+	rand.Read(nonce[:])
+	return secretbox.Seal(nonce[:], unencrypted, &nonce, &secretKey)
+}
+
+func TestLegacyMigration(t *testing.T) {
+	_, err := enc.db.Exec("insert or replace into enc(key, value) values(?,?)", STORAGE, encryptLegacy([]byte("secret"), pw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := enc.GetDecrypted(pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "secret" {
+		t.Fatalf("expected the legacy blob - got %q", ret)
+	}
+	var stored []byte
+	err = enc.db.QueryRow("select value from enc where key=?", STORAGE).Scan(&stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, legacy, err := wallet.DecryptBytes(stored, pw); err != nil || legacy {
+		t.Fatal("legacy blob was not migrated")
+	}
+	ret, err = enc.GetDecrypted(pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "secret" {
+		t.Fatalf("expected the migrated blob - got %q", ret)
+	}
+}
+
+func TestStaleLegacyMigration(t *testing.T) {
+	legacyBlob := encryptLegacy([]byte("secret"), pw)
+	_, err := enc.db.Exec("insert or replace into enc(key, value) values(?,?)", STORAGE, legacyBlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the password changes after the legacy blob was read
+	err = enc.ChangePassword([]byte("secret"), pw, "def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.migrateLegacy(legacyBlob, []byte("secret"), pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := enc.GetDecrypted("def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ret) != "secret" {
+		t.Fatalf("expected the new password blob - got %q", ret)
+	}
+}
+
 func TestWif(t *testing.T) {
 	err := PrivKeyToWif()
 	if err != nil {
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+type LabelsDB struct {
+	db   *sql.DB
+	lock *sync.RWMutex
+}
+
+func (l *LabelsDB) Put(label wallet.Label) error {
+	if !label.Type.Valid() {
+		return errors.New("invalid label type")
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	tx, _ := l.db.Begin()
+	stmt, err := tx.Prepare("insert or replace into labels(type, ref, label, origin, spendable) values(?,?,?,?,?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	var spendable sql.NullBool
+	if label.Spendable != nil {
+		spendable = sql.NullBool{Bool: *label.Spendable, Valid: true}
+	}
+	_, err = stmt.Exec(string(label.Type), label.Ref, label.Label, label.Origin, spendable)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+func (l *LabelsDB) Get(labelType wallet.LabelType, ref string) (wallet.Label, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	stmt, err := l.db.Prepare("select label, origin, spendable from labels where type=? and ref=?")
+	if err != nil {
+		return wallet.Label{}, err
+	}
+	defer stmt.Close()
+	var label string
+	var origin string
+	var spendable sql.NullBool
+	err = stmt.QueryRow(string(labelType), ref).Scan(&label, &origin, &spendable)
+	if err != nil {
+		return wallet.Label{}, err
+	}
+	return makeLabel(string(labelType), ref, label, origin, spendable), nil
+}
+
+func (l *LabelsDB) GetAll() ([]wallet.Label, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	var labels []wallet.Label
+	stm := "select type, ref, label, origin, spendable from labels"
+	rows, err := l.db.Query(stm)
+	if err != nil {
+		return labels, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var labelType string
+		var ref string
+		var label string
+		var origin string
+		var spendable sql.NullBool
+		if err := rows.Scan(&labelType, &ref, &label, &origin, &spendable); err != nil {
+			continue
+		}
+		labels = append(labels, makeLabel(labelType, ref, label, origin, spendable))
+	}
+	return labels, nil
+}
+
+func (l *LabelsDB) Delete(labelType wallet.LabelType, ref string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, err := l.db.Exec("delete from labels where type=? and ref=?", string(labelType), ref)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func makeLabel(labelType, ref, label, origin string, spendable sql.NullBool) wallet.Label {
+	lbl := wallet.Label{
+		Type:   wallet.LabelType(labelType),
+		Ref:    ref,
+		Label:  label,
+		Origin: origin,
+	}
+	if spendable.Valid {
+		s := spendable.Bool
+		lbl.Spendable = &s
+	}
+	return lbl
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+var lbdb LabelsDB
+
+func init() {
+	conn, _ := sql.Open("sqlite3", ":memory:")
+	initDatabaseTables(conn)
+	lbdb = LabelsDB{
+		db:   conn,
+		lock: new(sync.RWMutex),
+	}
+}
+
+func TestLabelsDB_Put(t *testing.T) {
+	spendable := false
+	label := wallet.Label{
+		Type:      wallet.LabelOutput,
+		Ref:       "f91d0a8a78462bc59398f2c5d7a84fcff491c26ba54c4833478b202796c8aafd:0",
+		Label:     "cold storage",
+		Origin:    "wpkh([d34db33f/84'/0'/0'])",
+		Spendable: &spendable,
+	}
+	err := lbdb.Put(label)
+	if err != nil {
+		t.Error(err)
+	}
+	out, err := lbdb.Get(wallet.LabelOutput, label.Ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Label != label.Label || out.Origin != label.Origin {
+		t.Error("Returned incorrect label")
+	}
+	if out.Spendable == nil || *out.Spendable {
+		t.Error("Returned incorrect spendable flag")
+	}
+
+	// replace
+	label.Label = "hot storage"
+	label.Spendable = nil
+	err = lbdb.Put(label)
+	if err != nil {
+		t.Error(err)
+	}
+	out, err = lbdb.Get(wallet.LabelOutput, label.Ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Label != "hot storage" || out.Spendable != nil {
+		t.Error("Failed to replace label")
+	}
+
+	err = lbdb.Put(wallet.Label{Type: "bogus", Ref: "x", Label: "x"})
+	if err == nil {
+		t.Error("Allowed an invalid label type")
+	}
+}
+
+func TestLabelsDB_GetAll(t *testing.T) {
+	for i, ref := range []string{"addr1", "addr2", "addr3"} {
+		err := lbdb.Put(wallet.Label{Type: wallet.LabelAddr, Ref: ref, Label: string(rune('a' + i))})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	labels, err := lbdb.GetAll()
+	if err != nil {
+		t.Error(err)
+	}
+	var n int
+	for _, l := range labels {
+		if l.Type == wallet.LabelAddr {
+			n++
+		}
+	}
+	if n != 3 {
+		t.Error("Failed to get all labels")
+	}
+}
+
+func TestLabelsDB_Delete(t *testing.T) {
+	err := lbdb.Put(wallet.Label{Type: wallet.LabelTx, Ref: "txid", Label: "rent"})
+	if err != nil {
+		t.Error(err)
+	}
+	err = lbdb.Delete(wallet.LabelTx, "txid")
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = lbdb.Get(wallet.LabelTx, "txid")
+	if err == nil {
+		t.Error("Failed to delete label")
+	}
+}
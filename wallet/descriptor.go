@@ -0,0 +1,336 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// BIP380 output script descriptors. Only the single key descriptors used by
+// this wallet are supported: pkh(KEY), wpkh(KEY) and sh(wpkh(KEY)) where KEY is
+// an extended key with optional key origin and derivation path, for example:
+//
+//	wpkh([d34db33f/44'/0'/0']xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL/0/*)#xdwmha79
+
+// ErrInvalidDescriptor is returned for a descriptor that cannot be parsed or
+// is not supported
+var ErrInvalidDescriptor = errors.New("invalid descriptor")
+
+// ErrDescriptorChecksum is returned when a descriptor checksum does not match
+var ErrDescriptorChecksum = errors.New("descriptor checksum mismatch")
+
+// DescriptorType is the script function of a descriptor
+type DescriptorType string
+
+const (
+	DescriptorPkh    DescriptorType = "pkh"
+	DescriptorWpkh   DescriptorType = "wpkh"
+	DescriptorShWpkh DescriptorType = "sh(wpkh)"
+)
+
+// KeyOrigin is the master key fingerprint and derivation path of a
+// descriptor's key
+type KeyOrigin struct {
+	Fingerprint uint32
+	Path        []uint32
+}
+
+// Descriptor is a parsed single key output descriptor
+type Descriptor struct {
+	Type DescriptorType
+	// Origin of Key or nil if not given
+	Origin *KeyOrigin
+	// Extended public or private key
+	Key *hdkeychain.ExtendedKey
+	// Unhardened derivation steps from Key
+	Path []uint32
+	// The descriptor ends in /* and describes a range of scripts
+	Wildcard bool
+}
+
+// WalletDescriptors are the descriptors of a wallet's receive and change
+// address chains
+type WalletDescriptors struct {
+	Receive string
+	Change  string
+}
+
+const (
+	descInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+func descPolymod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	if c0&1 != 0 {
+		c ^= 0xf5dee51989
+	}
+	if c0&2 != 0 {
+		c ^= 0xa9fdca3312
+	}
+	if c0&4 != 0 {
+		c ^= 0x1bab10e32d
+	}
+	if c0&8 != 0 {
+		c ^= 0x3706b1677a
+	}
+	if c0&16 != 0 {
+		c ^= 0x644d626ffd
+	}
+	return c
+}
+
+// DescriptorChecksum returns the 8 character BIP380 checksum of a descriptor
+// without its checksum.
+func DescriptorChecksum(desc string) (string, error) {
+	c := uint64(1)
+	cls := 0
+	clsCount := 0
+	for _, ch := range desc {
+		pos := strings.IndexRune(descInputCharset, ch)
+		if pos < 0 {
+			return "", fmt.Errorf("%w: invalid character %q", ErrInvalidDescriptor, ch)
+		}
+		c = descPolymod(c, pos&31)
+		cls = cls*3 + (pos >> 5)
+		clsCount++
+		if clsCount == 3 {
+			c = descPolymod(c, cls)
+			cls = 0
+			clsCount = 0
+		}
+	}
+	if clsCount > 0 {
+		c = descPolymod(c, cls)
+	}
+	for j := 0; j < 8; j++ {
+		c = descPolymod(c, 0)
+	}
+	c ^= 1
+	sum := make([]byte, 8)
+	for j := 0; j < 8; j++ {
+		sum[j] = descChecksumCharset[(c>>(5*(7-j)))&31]
+	}
+	return string(sum), nil
+}
+
+// ParseDescriptor parses a descriptor. If the descriptor has a checksum it
+// must be valid.
+func ParseDescriptor(desc string) (*Descriptor, error) {
+	desc = strings.TrimSpace(desc)
+	if i := strings.IndexByte(desc, '#'); i >= 0 {
+		body, sum := desc[:i], desc[i+1:]
+		want, err := DescriptorChecksum(body)
+		if err != nil {
+			return nil, err
+		}
+		if sum != want {
+			return nil, ErrDescriptorChecksum
+		}
+		desc = body
+	}
+
+	d := &Descriptor{}
+	var keyExpr string
+	switch {
+	case strings.HasPrefix(desc, "sh(wpkh(") && strings.HasSuffix(desc, "))"):
+		d.Type = DescriptorShWpkh
+		keyExpr = desc[len("sh(wpkh(") : len(desc)-2]
+	case strings.HasPrefix(desc, "wpkh(") && strings.HasSuffix(desc, ")"):
+		d.Type = DescriptorWpkh
+		keyExpr = desc[len("wpkh(") : len(desc)-1]
+	case strings.HasPrefix(desc, "pkh(") && strings.HasSuffix(desc, ")"):
+		d.Type = DescriptorPkh
+		keyExpr = desc[len("pkh(") : len(desc)-1]
+	default:
+		return nil, fmt.Errorf("%w: unsupported script %q", ErrInvalidDescriptor, desc)
+	}
+
+	if strings.HasPrefix(keyExpr, "[") {
+		end := strings.IndexByte(keyExpr, ']')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated key origin", ErrInvalidDescriptor)
+		}
+		origin, err := parseKeyOrigin(keyExpr[1:end])
+		if err != nil {
+			return nil, err
+		}
+		d.Origin = origin
+		keyExpr = keyExpr[end+1:]
+	}
+
+	parts := strings.Split(keyExpr, "/")
+	key, err := hdkeychain.NewKeyFromString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDescriptor, err)
+	}
+	d.Key = key
+	steps := parts[1:]
+	if len(steps) > 0 && steps[len(steps)-1] == "*" {
+		d.Wildcard = true
+		steps = steps[:len(steps)-1]
+	}
+	for _, step := range steps {
+		index, err := parsePathStep(step)
+		if err != nil {
+			return nil, err
+		}
+		if index >= hdkeychain.HardenedKeyStart {
+			return nil, fmt.Errorf("%w: hardened derivation after key", ErrInvalidDescriptor)
+		}
+		d.Path = append(d.Path, index)
+	}
+	return d, nil
+}
+
+func parseKeyOrigin(origin string) (*KeyOrigin, error) {
+	parts := strings.Split(origin, "/")
+	fp, err := hex.DecodeString(parts[0])
+	if err != nil || len(fp) != 4 {
+		return nil, fmt.Errorf("%w: bad key fingerprint %q", ErrInvalidDescriptor, parts[0])
+	}
+	ko := &KeyOrigin{Fingerprint: binary.BigEndian.Uint32(fp)}
+	for _, step := range parts[1:] {
+		index, err := parsePathStep(step)
+		if err != nil {
+			return nil, err
+		}
+		ko.Path = append(ko.Path, index)
+	}
+	return ko, nil
+}
+
+// parsePathStep parses a derivation step. Hardened steps end in ' or h.
+func parsePathStep(step string) (uint32, error) {
+	var hardened bool
+	if strings.HasSuffix(step, "'") || strings.HasSuffix(step, "h") {
+		hardened = true
+		step = step[:len(step)-1]
+	}
+	index, err := strconv.ParseUint(step, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad derivation step %q", ErrInvalidDescriptor, step)
+	}
+	if hardened {
+		index += hdkeychain.HardenedKeyStart
+	}
+	return uint32(index), nil
+}
+
+// FormatPath formats a derivation path as used in descriptors, for example
+// 44'/0'/0'
+func FormatPath(path []uint32) string {
+	steps := make([]string, 0, len(path))
+	for _, index := range path {
+		if index >= hdkeychain.HardenedKeyStart {
+			steps = append(steps, strconv.FormatUint(uint64(index-hdkeychain.HardenedKeyStart), 10)+"'")
+			continue
+		}
+		steps = append(steps, strconv.FormatUint(uint64(index), 10))
+	}
+	return strings.Join(steps, "/")
+}
+
+// String returns the descriptor with its checksum
+func (d *Descriptor) String() string {
+	var b strings.Builder
+	if d.Origin != nil {
+		fp := make([]byte, 4)
+		binary.BigEndian.PutUint32(fp, d.Origin.Fingerprint)
+		b.WriteString("[" + hex.EncodeToString(fp))
+		if len(d.Origin.Path) > 0 {
+			b.WriteString("/" + FormatPath(d.Origin.Path))
+		}
+		b.WriteString("]")
+	}
+	b.WriteString(d.Key.String())
+	if len(d.Path) > 0 {
+		b.WriteString("/" + FormatPath(d.Path))
+	}
+	if d.Wildcard {
+		b.WriteString("/*")
+	}
+	var desc string
+	switch d.Type {
+	case DescriptorShWpkh:
+		desc = "sh(wpkh(" + b.String() + "))"
+	default:
+		desc = string(d.Type) + "(" + b.String() + ")"
+	}
+	// all characters are from the input charset
+	sum, _ := DescriptorChecksum(desc)
+	return desc + "#" + sum
+}
+
+// IsPrivate returns true if the descriptor's key is an extended private key
+func (d *Descriptor) IsPrivate() bool {
+	return d.Key.IsPrivate()
+}
+
+// Neutered returns a copy of the descriptor with the extended public key
+func (d *Descriptor) Neutered() (*Descriptor, error) {
+	pub, err := d.Key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	nd := *d
+	nd.Key = pub
+	return &nd, nil
+}
+
+// DerivedKey returns the key at index of a ranged descriptor or the single
+// key of a non ranged descriptor, in which case index is ignored.
+func (d *Descriptor) DerivedKey(index uint32) (*hdkeychain.ExtendedKey, error) {
+	key := d.Key
+	var err error
+	for _, step := range d.Path {
+		key, err = key.Derive(step)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if d.Wildcard {
+		return key.Derive(index)
+	}
+	return key, nil
+}
+
+// Address returns the address at index of a ranged descriptor.
+func (d *Descriptor) Address(index uint32, params *chaincfg.Params) (btcutil.Address, error) {
+	key, err := d.DerivedKey(index)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	pkHash := btcutil.Hash160(pubKey.SerializeCompressed())
+	switch d.Type {
+	case DescriptorPkh:
+		return btcutil.NewAddressPubKeyHash(pkHash, params)
+	case DescriptorWpkh:
+		return btcutil.NewAddressWitnessPubKeyHash(pkHash, params)
+	case DescriptorShWpkh:
+		witnessProgram := append([]byte{0x00, 0x14}, pkHash...)
+		return btcutil.NewAddressScriptHash(witnessProgram, params)
+	}
+	return nil, fmt.Errorf("%w: unsupported script %q", ErrInvalidDescriptor, d.Type)
+}
+
+// KeyFingerprint returns the BIP32 fingerprint of an extended key
+func KeyFingerprint(key *hdkeychain.ExtendedKey) (uint32, error) {
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(btcutil.Hash160(pubKey.SerializeCompressed())[:4]), nil
+}
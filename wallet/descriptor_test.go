@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestDescriptorChecksum(t *testing.T) {
+	// BIP380 test vector
+	sum, err := DescriptorChecksum("raw(deadbeef)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "89f8spxm" {
+		t.Fatalf("expected checksum 89f8spxm - got %s", sum)
+	}
+	_, err = DescriptorChecksum("raw(deadbeef)é")
+	if !errors.Is(err, ErrInvalidDescriptor) {
+		t.Fatalf("expected %v - got %v", ErrInvalidDescriptor, err)
+	}
+}
+
+func TestParseDescriptor(t *testing.T) {
+	seed := make([]byte, 32)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := master
+	path := []uint32{hdkeychain.HardenedKeyStart + 84, hdkeychain.HardenedKeyStart + 1, hdkeychain.HardenedKeyStart}
+	for _, step := range path {
+		account, err = account.Derive(step)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	accountPub, err := account.Neuter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, err := KeyFingerprint(master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Descriptor{
+		Type:     DescriptorWpkh,
+		Origin:   &KeyOrigin{Fingerprint: fp, Path: path},
+		Key:      accountPub,
+		Path:     []uint32{1},
+		Wildcard: true,
+	}
+	desc := d.String()
+
+	parsed, err := ParseDescriptor(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != desc {
+		t.Fatalf("round trip: expected %s - got %s", desc, parsed.String())
+	}
+	if parsed.IsPrivate() {
+		t.Fatal("expected a public descriptor")
+	}
+
+	// the address matches direct derivation
+	change, _ := account.Derive(1)
+	key, _ := change.Derive(5)
+	want, _ := key.Address(&chaincfg.RegressionNetParams)
+	addr, err := parsed.Address(5, &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(addr.ScriptAddress()) != string(want.ScriptAddress()) {
+		t.Fatalf("expected the address of key %x - got %s", want.ScriptAddress(), addr)
+	}
+
+	// h is accepted for hardened steps and no checksum is required
+	alt := "wpkh([" + desc[6:14] + "/84h/1h/0h]" + accountPub.String() + "/1/*)"
+	parsed, err = ParseDescriptor(alt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != desc {
+		t.Fatalf("expected %s - got %s", desc, parsed.String())
+	}
+
+	bad := map[string]error{
+		desc[:len(desc)-1] + "x":                         ErrDescriptorChecksum,
+		"tr(" + accountPub.String() + ")":                ErrInvalidDescriptor,
+		"wpkh(" + accountPub.String() + "/1h/*)":         ErrInvalidDescriptor,
+		"wpkh([d34db3/0']" + accountPub.String() + "/*)": ErrInvalidDescriptor,
+		"wpkh(notakey/0/*)":                              ErrInvalidDescriptor,
+	}
+	for in, wantErr := range bad {
+		_, err := ParseDescriptor(in)
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("%s: expected %v - got %v", in, wantErr, err)
+		}
+	}
+}
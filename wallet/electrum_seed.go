@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Electrum "new-style" seeds. The seed version is encoded in the hmac of the
+// mnemonic rather than in a checksum as BIP39 does, and the bip32 seed is made
+// with Electrum's own PBKDF2 salt. Electrum's English wordlist is the BIP39
+// English wordlist.
+//
+// Electrum NFKD normalizes mnemonics. Go's standard library has no NFKD so
+// only mnemonics that are already NFKD normalized, such as the English ones
+// Electrum makes, are supported.
+
+// ElectrumSeedType is the version of an Electrum seed
+type ElectrumSeedType string
+
+const (
+	// Standard wallets: p2pkh addresses on the m/0 and m/1 chains
+	ElectrumSeedStandard ElectrumSeedType = "standard"
+	// Segwit wallets: p2wpkh addresses on the m/0'/0 and m/0'/1 chains
+	ElectrumSeedSegwit ElectrumSeedType = "segwit"
+)
+
+// ErrInvalidElectrumSeed is returned for a mnemonic that is not an Electrum
+// seed of a supported version
+var ErrInvalidElectrumSeed = errors.New("not a standard or segwit electrum seed")
+
+// hex prefixes of the hmac of the mnemonic
+var electrumSeedPrefixes = map[ElectrumSeedType]string{
+	ElectrumSeedStandard: "01",
+	ElectrumSeedSegwit:   "100",
+}
+
+const (
+	electrumSeedBits   = 132
+	electrumPbkdf2Iter = 2048
+)
+
+// NormalizeElectrumMnemonic normalizes a mnemonic the way Electrum does
+// before checking its version or making the seed.
+func NormalizeElectrumMnemonic(mnemonic string) string {
+	mnemonic = strings.ToLower(mnemonic)
+	mnemonic = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, mnemonic)
+	words := strings.Fields(mnemonic)
+	if len(words) == 0 {
+		return ""
+	}
+	// no space between CJK words
+	var b strings.Builder
+	b.WriteString(words[0])
+	for i := 1; i < len(words); i++ {
+		prev := []rune(words[i-1])
+		next := []rune(words[i])
+		if !isCJK(prev[len(prev)-1]) || !isCJK(next[0]) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(words[i])
+	}
+	return b.String()
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isElectrumSeedType(normalized string, seedType ElectrumSeedType) bool {
+	prefix, ok := electrumSeedPrefixes[seedType]
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha512.New, []byte("Seed version"))
+	mac.Write([]byte(normalized))
+	return strings.HasPrefix(hex.EncodeToString(mac.Sum(nil)), prefix)
+}
+
+// ElectrumSeedVersion returns the type of an Electrum mnemonic. ok is false
+// if the mnemonic is not a standard or segwit Electrum seed.
+func ElectrumSeedVersion(mnemonic string) (seedType ElectrumSeedType, ok bool) {
+	normalized := NormalizeElectrumMnemonic(mnemonic)
+	// the segwit prefix is longer so check it first
+	for _, t := range []ElectrumSeedType{ElectrumSeedSegwit, ElectrumSeedStandard} {
+		if isElectrumSeedType(normalized, t) {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// ElectrumSeed returns the bip32 seed and the seed type of an Electrum
+// mnemonic with an optional seed extension passphrase.
+func ElectrumSeed(mnemonic, passphrase string) ([]byte, ElectrumSeedType, error) {
+	seedType, ok := ElectrumSeedVersion(mnemonic)
+	if !ok {
+		return nil, "", ErrInvalidElectrumSeed
+	}
+	normalized := NormalizeElectrumMnemonic(mnemonic)
+	salt := "electrum" + NormalizeElectrumMnemonic(passphrase)
+	seed := pbkdf2.Key([]byte(normalized), []byte(salt), electrumPbkdf2Iter, 64, sha512.New)
+	return seed, seedType, nil
+}
+
+// NewElectrumMnemonic makes a new 12 word Electrum mnemonic of seedType.
+// Mnemonics that are also valid BIP39 mnemonics are skipped.
+func NewElectrumMnemonic(seedType ElectrumSeedType) (string, error) {
+	if _, ok := electrumSeedPrefixes[seedType]; !ok {
+		return "", ErrInvalidElectrumSeed
+	}
+	// as Electrum's make_seed, entropy is drawn again until it fills the
+	// 12th word so the mnemonic is never shorter
+	max := new(big.Int).Lsh(big.NewInt(1), electrumSeedBits)
+	min := new(big.Int).Lsh(big.NewInt(1), electrumSeedBits-11)
+	var entropy *big.Int
+	for entropy == nil || entropy.Cmp(min) < 0 {
+		var err error
+		entropy, err = rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+	}
+	one := big.NewInt(1)
+	for {
+		entropy.Add(entropy, one)
+		mnemonic := electrumMnemonicEncode(entropy)
+		if !isElectrumSeedType(mnemonic, seedType) {
+			continue
+		}
+		if bip39.IsMnemonicValid(mnemonic) {
+			continue
+		}
+		return mnemonic, nil
+	}
+}
+
+// electrumMnemonicEncode encodes i as words, least significant first
+func electrumMnemonicEncode(i *big.Int) string {
+	wordList := bip39.GetWordList()
+	n := big.NewInt(int64(len(wordList)))
+	x := new(big.Int).Set(i)
+	rem := new(big.Int)
+	var words []string
+	for x.Sign() > 0 {
+		x.DivMod(x, n, rem)
+		words = append(words, wordList[rem.Int64()])
+	}
+	return strings.Join(words, " ")
+}
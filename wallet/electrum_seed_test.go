@@ -0,0 +1,76 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+func TestElectrumSeed(t *testing.T) {
+	// Electrum test vector
+	mnemonic := "wild father tree among universe such mobile favorite target dynamic credit identify"
+	seed, seedType, err := ElectrumSeed(mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seedType != ElectrumSeedSegwit {
+		t.Fatalf("expected a segwit seed - got %s", seedType)
+	}
+	want := "aac2a6302e48577ab4b46f23dbae0774e2e62c796f797d0a1b5faeb528301e3064342dafb79069e7c4c6b8c38ae11d7a973bec0d4f70626f8cc5184a8d0b0756"
+	if hex.EncodeToString(seed) != want {
+		t.Fatalf("expected seed %s - got %x", want, seed)
+	}
+
+	// normalized before use
+	seed2, _, err := ElectrumSeed("  Wild FATHER tree among universe such mobile\tfavorite target dynamic credit identify ", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(seed2) != want {
+		t.Fatal("expected the same seed after normalization")
+	}
+
+	// a bip39 mnemonic is not usually an electrum seed
+	_, _, err = ElectrumSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	if err != ErrInvalidElectrumSeed {
+		t.Fatalf("expected %v - got %v", ErrInvalidElectrumSeed, err)
+	}
+}
+
+func TestNewElectrumMnemonic(t *testing.T) {
+	for _, seedType := range []ElectrumSeedType{ElectrumSeedStandard, ElectrumSeedSegwit} {
+		mnemonic, err := NewElectrumMnemonic(seedType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := ElectrumSeedVersion(mnemonic)
+		if !ok || got != seedType {
+			t.Fatalf("expected a %s seed - got %s %v", seedType, got, ok)
+		}
+		if bip39.IsMnemonicValid(mnemonic) {
+			t.Fatal("made a valid bip39 mnemonic")
+		}
+		if n := len(strings.Fields(mnemonic)); n != 12 {
+			t.Fatalf("expected 12 words - got %d", n)
+		}
+	}
+	_, err := NewElectrumMnemonic("2fa")
+	if err != ErrInvalidElectrumSeed {
+		t.Fatalf("expected %v - got %v", ErrInvalidElectrumSeed, err)
+	}
+}
+
+func TestNormalizeElectrumMnemonic(t *testing.T) {
+	tests := map[string]string{
+		" Foo  BAR\nbaz ": "foo bar baz",
+		"cafe\u0301":      "cafe",
+		"火 山  灰 x":        "火山灰 x",
+	}
+	for in, want := range tests {
+		if got := NormalizeElectrumMnemonic(in); got != want {
+			t.Fatalf("%q: expected %q - got %q", in, want, got)
+		}
+	}
+}
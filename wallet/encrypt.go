@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// Encrypted storage shared by the wallet databases.
+//
+// Envelope, version 1:
+//
+//	[magic 5]["goele"]
+//	[version 1]
+//	[argon2 time 4][argon2 memory KiB 4][argon2 threads 1]
+//	[salt length 1][salt]
+//	[nonce 24][ ...the encryption result...]
+//
+// Integers are big endian. Blobs without the magic are legacy blobs encrypted
+// with Argon2Salt and the current params and should be re-encrypted into an
+// envelope on the next unlock.
+
+var (
+	// Argon2 params
+	// Legacy fixed salt; each wallet now has a random salt in its envelope
+	Argon2Salt       = []byte("2977958431d29f2d")
+	Argon2Time       = uint32(1)
+	Argon2Mem        = uint32(64 * 1024)
+	Argon2Threads    = uint8(runtime.NumCPU())
+	Argon2ThreadsMax = uint8(255)
+	Argon2KeyLen     = uint32(32)
+)
+
+var envelopeMagic = []byte("goele")
+
+const (
+	envelopeV1 = byte(1)
+	saltLen    = 16
+	// Upper bound on stored argon2 memory so a corrupt header cannot exhaust
+	// memory; 2 GiB
+	maxMem = uint32(2 * 1024 * 1024)
+)
+
+// kdfParams are the argon2 key derivation params of an envelope
+type kdfParams struct {
+	salt    []byte
+	time    uint32
+	mem     uint32
+	threads uint8
+}
+
+// newKdfParams makes params with a new random salt
+func newKdfParams() (*kdfParams, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	threads := Argon2Threads
+	if threads > Argon2ThreadsMax {
+		threads = Argon2ThreadsMax
+	}
+	if threads == 0 {
+		threads = 1
+	}
+	return &kdfParams{
+		salt:    salt,
+		time:    Argon2Time,
+		mem:     Argon2Mem,
+		threads: threads,
+	}, nil
+}
+
+// legacyKdfParams are the params of blobs stored before the envelope
+func legacyKdfParams() *kdfParams {
+	threads := Argon2Threads
+	if threads > Argon2ThreadsMax {
+		threads = Argon2ThreadsMax
+	}
+	return &kdfParams{
+		salt:    Argon2Salt,
+		time:    Argon2Time,
+		mem:     Argon2Mem,
+		threads: threads,
+	}
+}
+
+func (p *kdfParams) key(password string) [32]byte {
+	b := argon2.IDKey([]byte(password), p.salt, p.time, p.mem, p.threads, Argon2KeyLen)
+	return ([32]byte)(b)
+}
+
+// EncryptBytes encrypts unencrypted with password into an envelope with a new
+// random salt
+func EncryptBytes(unencrypted []byte, password string) ([]byte, error) {
+	params, err := newKdfParams()
+	if err != nil {
+		return nil, err
+	}
+	secretKey := params.key(password)
+	defer ZeroBytes(secretKey[:])
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(envelopeMagic)+11+len(params.salt))
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeV1)
+	header = binary.BigEndian.AppendUint32(header, params.time)
+	header = binary.BigEndian.AppendUint32(header, params.mem)
+	header = append(header, params.threads, byte(len(params.salt)))
+	header = append(header, params.salt...)
+	header = append(header, nonce[:]...)
+	encrypted := secretbox.Seal(header, unencrypted, &nonce, &secretKey)
+	return encrypted, nil
+}
+
+// parseEnvelope returns the kdf params and the [nonce][box] of an envelope. A
+// nil params is a legacy blob.
+func parseEnvelope(encrypted []byte) (*kdfParams, []byte, error) {
+	if !bytes.HasPrefix(encrypted, envelopeMagic) {
+		return nil, encrypted, nil
+	}
+	b := encrypted[len(envelopeMagic):]
+	if len(b) < 11 {
+		return nil, nil, errors.New("short encrypted envelope")
+	}
+	if b[0] != envelopeV1 {
+		return nil, nil, fmt.Errorf("unknown encrypted envelope version %d", b[0])
+	}
+	params := &kdfParams{
+		time:    binary.BigEndian.Uint32(b[1:5]),
+		mem:     binary.BigEndian.Uint32(b[5:9]),
+		threads: b[9],
+	}
+	n := int(b[10])
+	b = b[11:]
+	if params.time == 0 || params.threads == 0 || params.mem == 0 || params.mem > maxMem || n == 0 || len(b) < n {
+		return nil, nil, errors.New("bad encrypted envelope params")
+	}
+	params.salt = b[:n]
+	return params, b[n:], nil
+}
+
+// DecryptBytes decrypts an envelope or a legacy blob. legacy is true for a
+// legacy blob that should be re-encrypted.
+func DecryptBytes(encrypted []byte, password string) (decrypted []byte, legacy bool, err error) {
+	params, sealed, err := parseEnvelope(encrypted)
+	if err != nil {
+		return nil, false, err
+	}
+	if params == nil {
+		legacy = true
+		params = legacyKdfParams()
+	}
+	if len(sealed) < 24 {
+		return nil, false, errors.New("no encrypted data")
+	}
+	secretKey := params.key(password)
+	defer ZeroBytes(secretKey[:])
+	var decryptNonce [24]byte
+	copy(decryptNonce[:], sealed[:24])
+	decrypted, ok := secretbox.Open(nil, sealed[24:], &decryptNonce, &secretKey)
+	if !ok {
+		return nil, false, errors.New("secretbox decryption error")
+	}
+	// decrypted is the decryption of the encrypted bytes with the header and
+	// plaintext nonce stripped out
+	return decrypted, legacy, nil
+}
+
+// VerifyEncrypted checks that eb decrypts with pw to b
+func VerifyEncrypted(eb, b []byte, pw string) error {
+	check, _, err := DecryptBytes(eb, pw)
+	if err != nil {
+		return err
+	}
+	defer ZeroBytes(check)
+	if !bytes.Equal(check, b) {
+		return errors.New("re-encryption verify failed")
+	}
+	return nil
+}
+
+// ZeroBytes clears sensitive bytes
+func ZeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
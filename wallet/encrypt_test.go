@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	pw := "abc"
+	b := []byte("secret")
+	eb1, err := EncryptBytes(b, pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eb2, err := EncryptBytes(b, pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _, err := parseEnvelope(eb1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _, err := parseEnvelope(eb2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1 == nil || p2 == nil {
+		t.Fatal("expected envelopes")
+	}
+	if bytes.Equal(p1.salt, p2.salt) || bytes.Equal(p1.salt, Argon2Salt) {
+		t.Fatal("expected a random salt per envelope")
+	}
+	if p1.time != Argon2Time || p1.mem != Argon2Mem || p1.threads == 0 {
+		t.Fatalf("unexpected kdf params %+v", p1)
+	}
+	ret, legacy, err := DecryptBytes(eb1, pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy || !bytes.Equal(ret, b) {
+		t.Fatalf("expected %q - got %q legacy %v", b, ret, legacy)
+	}
+
+	// params are read from the envelope not the package vars
+	Argon2Mem = Argon2Mem / 2
+	defer func() { Argon2Mem = Argon2Mem * 2 }()
+	_, _, err = DecryptBytes(eb1, pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupt header
+	bad := bytes.Clone(eb1)
+	bad[len(envelopeMagic)] = 9
+	_, _, err = DecryptBytes(bad, pw)
+	if err == nil {
+		t.Fatal("expected an unknown version error")
+	}
+}
@@ -0,0 +1,78 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// DefaultEventConfirmations is the default number of confirmations of a wallet
+// transaction notified by EventTxConfirmed events
+const DefaultEventConfirmations = 6
+
+// WalletEventType is the kind of a WalletEvent
+type WalletEventType string
+
+const (
+	// A new wallet transaction was seen; in the mempool if Height is zero
+	EventTxReceived WalletEventType = "tx_received"
+	// A wallet transaction was mined or has another confirmation, up to the
+	// wallet's EventConfirmations
+	EventTxConfirmed WalletEventType = "tx_confirmed"
+	// A wallet transaction was dropped, usually because it was double spent
+	EventTxDropped WalletEventType = "tx_dropped"
+	// A wallet transaction conflicts with another transaction, ConflictTxid,
+	// that spends the same coins. If the other transaction was mined, or the
+	// wallet transaction was dropped by the server, the wallet transaction is
+	// replaced and is dropped too.
+	EventTxConflict WalletEventType = "tx_conflict"
+	// An unconfirmed wallet transaction has waited too many blocks to be
+	// mined. A fee bump may be needed.
+	EventTxStuck WalletEventType = "tx_stuck"
+	// A mined wallet transaction was moved to another block or back to the
+	// mempool by a reorg
+	EventTxReorged WalletEventType = "tx_reorged"
+	// The wallet balance changed
+	EventBalanceChanged WalletEventType = "balance_changed"
+	// A wallet coin was frozen
+	EventUtxoFrozen WalletEventType = "utxo_frozen"
+	// A wallet coin was unfrozen
+	EventUtxoUnfrozen WalletEventType = "utxo_unfrozen"
+)
+
+// WalletEvent is a change to the wallet. Only the fields for its type are set.
+type WalletEvent struct {
+	Type WalletEventType
+	Time time.Time
+
+	// Tx events: the transaction, its block height (0 if unconfirmed or -1 if
+	// dropped) and confirmations at the wallet tip. Value is the net change
+	// to the wallet balance; positive for an incoming payment.
+	Txid          string
+	Height        int64
+	Confirmations int64
+	Value         int64
+	// The height before a reorg
+	PrevHeight int64
+	// The other transaction of a conflict
+	ConflictTxid string
+
+	// Utxo events: the coin
+	OutPoint *wire.OutPoint
+
+	// Balance events: the new wallet balance as returned by Balance
+	Confirmed   int64
+	Unconfirmed int64
+	Locked      int64
+}
+
+// EventFeed publishes wallet events to any number of subscribers
+type EventFeed = Feed[*WalletEvent]
+
+// EventSubscription receives wallet events until Unsubscribe is called or the
+// wallet is closed
+type EventSubscription = FeedSubscription[*WalletEvent]
+
+func NewEventFeed() *EventFeed {
+	return NewFeed[*WalletEvent]()
+}
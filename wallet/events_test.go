@@ -0,0 +1,70 @@
+package wallet
+
+import (
+	"testing"
+)
+
+func TestEventFeed(t *testing.T) {
+	f := NewEventFeed()
+	if f.HasSubscribers() {
+		t.Fatal("expected no subscribers")
+	}
+	a := f.Subscribe(2)
+	b := f.Subscribe(1)
+	if !f.HasSubscribers() {
+		t.Fatal("expected subscribers")
+	}
+
+	f.Publish(&WalletEvent{Type: EventTxReceived, Txid: "1"})
+	f.Publish(&WalletEvent{Type: EventTxConfirmed, Txid: "1"})
+	for _, want := range []WalletEventType{EventTxReceived, EventTxConfirmed} {
+		e := <-a.C
+		if e.Type != want {
+			t.Fatalf("expected %s event - got %+v", want, e)
+		}
+	}
+	if a.Missed() != 0 {
+		t.Fatalf("expected no missed events - got %d", a.Missed())
+	}
+	// b's buffer was full for the second
+	if e := <-b.C; e.Type != EventTxReceived {
+		t.Fatalf("expected %s event - got %s", EventTxReceived, e.Type)
+	}
+	if b.Missed() != 1 {
+		t.Fatalf("expected 1 missed event - got %d", b.Missed())
+	}
+
+	b.Unsubscribe()
+	b.Unsubscribe()
+	if _, ok := <-b.C; ok {
+		t.Fatal("expected closed channel")
+	}
+	f.Publish(&WalletEvent{Type: EventBalanceChanged})
+	if e := <-a.C; e.Type != EventBalanceChanged {
+		t.Fatalf("expected %s event - got %s", EventBalanceChanged, e.Type)
+	}
+
+	f.Close()
+	if _, ok := <-a.C; ok {
+		t.Fatal("expected closed channel")
+	}
+	a.Unsubscribe()
+	c := f.Subscribe(1)
+	if _, ok := <-c.C; ok {
+		t.Fatal("expected closed channel after close")
+	}
+	f.Publish(&WalletEvent{Type: EventBalanceChanged})
+
+	// a nil feed publishes nothing
+	var nilFeed *EventFeed
+	nilFeed.Publish(&WalletEvent{Type: EventBalanceChanged})
+	if nilFeed.HasSubscribers() {
+		t.Fatal("expected no subscribers")
+	}
+	d := nilFeed.Subscribe(1)
+	if _, ok := <-d.C; ok {
+		t.Fatal("expected closed channel from a nil feed")
+	}
+	d.Unsubscribe()
+	nilFeed.Close()
+}
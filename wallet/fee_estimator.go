@@ -0,0 +1,165 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// FeeEstimator gives live network fee rates. FeeProvider uses it, if set, in
+// preference to the fee API and the static default fees.
+type FeeEstimator interface {
+	// FeeRateForLevel returns the fee rate in sats/vbyte for a fee level.
+	FeeRateForLevel(ctx context.Context, feeLevel FeeLevel) (int64, error)
+}
+
+// FeeSource is a source of network fee data; usually an ElectrumX server.
+type FeeSource interface {
+	// EstimateFeeRate returns the fee rate in sats/kB needed to confirm
+	// within confTarget blocks.
+	EstimateFeeRate(ctx context.Context, confTarget int64) (int64, error)
+	// FeeHistogram returns the mempool fee histogram as [fee rate, vsize]
+	// pairs with fee rates in sats/vbyte in descending order.
+	FeeHistogram(ctx context.Context) ([][2]float64, error)
+}
+
+// DefaultConfTargets maps fee levels to confirmation targets in blocks
+var DefaultConfTargets = map[FeeLevel]int64{
+	PRIORITY:       1,
+	NORMAL:         3,
+	ECONOMIC:       6,
+	FEE_BUMP:       1,
+	SUPER_ECONOMIC: 25,
+}
+
+const (
+	// How long a fee estimate is cached
+	defaultFeeCacheTime = time.Minute
+	// Virtual size of a block for mempool histogram estimates
+	blockVSize = 1_000_000
+	// Lowest fee rate in sats/vbyte
+	minRelayFeeRate = 1
+)
+
+// ErrNoFeeEstimate is returned when neither the server fee estimate nor the
+// mempool histogram give a fee rate.
+var ErrNoFeeEstimate = errors.New("no network fee estimate available")
+
+type cachedFeeRate struct {
+	feeRate     int64
+	lastUpdated time.Time
+}
+
+// NetworkFeeEstimator estimates fee rates from a FeeSource. It asks the
+// server's estimatefee for the level's confirmation target and falls back to
+// an estimate from the mempool fee histogram. Estimates are cached per target.
+type NetworkFeeEstimator struct {
+	source FeeSource
+	// ConfTargets maps fee levels to confirmation targets in blocks
+	ConfTargets map[FeeLevel]int64
+	// How long estimates are cached
+	CacheTime time.Duration
+
+	mtx   sync.Mutex
+	cache map[int64]*cachedFeeRate
+}
+
+// NewNetworkFeeEstimator makes a fee estimator for source. Fee levels missing
+// from confTargets use DefaultConfTargets.
+func NewNetworkFeeEstimator(source FeeSource, confTargets map[FeeLevel]int64) *NetworkFeeEstimator {
+	targets := make(map[FeeLevel]int64)
+	for level, target := range DefaultConfTargets {
+		targets[level] = target
+	}
+	for level, target := range confTargets {
+		if target > 0 {
+			targets[level] = target
+		}
+	}
+	return &NetworkFeeEstimator{
+		source:      source,
+		ConfTargets: targets,
+		CacheTime:   defaultFeeCacheTime,
+		cache:       make(map[int64]*cachedFeeRate),
+	}
+}
+
+// FeeRateForLevel returns the fee rate in sats/vbyte for the fee level's
+// confirmation target.
+func (e *NetworkFeeEstimator) FeeRateForLevel(ctx context.Context, feeLevel FeeLevel) (int64, error) {
+	target, ok := e.ConfTargets[feeLevel]
+	if !ok {
+		target = e.ConfTargets[NORMAL]
+	}
+	return e.EstimateFeeRate(ctx, target)
+}
+
+// EstimateFeeRate returns the fee rate in sats/vbyte to confirm within
+// confTarget blocks.
+func (e *NetworkFeeEstimator) EstimateFeeRate(ctx context.Context, confTarget int64) (int64, error) {
+	if confTarget < 1 {
+		confTarget = 1
+	}
+	e.mtx.Lock()
+	cached, ok := e.cache[confTarget]
+	e.mtx.Unlock()
+	if ok && time.Since(cached.lastUpdated) < e.CacheTime {
+		return cached.feeRate, nil
+	}
+
+	// not locked while the server is asked
+	feeRate, err := e.estimate(ctx, confTarget)
+	if err != nil {
+		// a stale estimate is better than none
+		if ok {
+			return cached.feeRate, nil
+		}
+		return 0, err
+	}
+	e.mtx.Lock()
+	e.cache[confTarget] = &cachedFeeRate{
+		feeRate:     feeRate,
+		lastUpdated: time.Now(),
+	}
+	e.mtx.Unlock()
+	return feeRate, nil
+}
+
+func (e *NetworkFeeEstimator) estimate(ctx context.Context, confTarget int64) (int64, error) {
+	feePerKB, err := e.source.EstimateFeeRate(ctx, confTarget)
+	if err == nil && feePerKB > 0 {
+		// round up to whole sats/vbyte
+		feeRate := (feePerKB + 999) / 1000
+		if feeRate < minRelayFeeRate {
+			feeRate = minRelayFeeRate
+		}
+		return feeRate, nil
+	}
+	histogram, err := e.source.FeeHistogram(ctx)
+	if err != nil {
+		return 0, ErrNoFeeEstimate
+	}
+	return HistogramFeeRate(histogram, confTarget), nil
+}
+
+// HistogramFeeRate estimates the fee rate in sats/vbyte to confirm within
+// confTarget blocks from a mempool fee histogram. It is the rate needed to be
+// inside the top confTarget blocks worth of the mempool. If the mempool is
+// smaller than that the minimum relay fee rate is returned.
+func HistogramFeeRate(histogram [][2]float64, confTarget int64) int64 {
+	limit := float64(confTarget * blockVSize)
+	var vsize float64
+	for _, bin := range histogram {
+		vsize += bin[1]
+		if vsize >= limit {
+			// pay just over this bin's rate
+			feeRate := int64(bin[0]) + 1
+			if feeRate < minRelayFeeRate {
+				feeRate = minRelayFeeRate
+			}
+			return feeRate
+		}
+	}
+	return minRelayFeeRate
+}
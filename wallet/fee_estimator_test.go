@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockFeeSource struct {
+	feePerKB  map[int64]int64
+	histogram [][2]float64
+	calls     int
+}
+
+func (m *mockFeeSource) EstimateFeeRate(_ context.Context, confTarget int64) (int64, error) {
+	m.calls++
+	feePerKB, ok := m.feePerKB[confTarget]
+	if !ok {
+		return -1, errors.New("server cannot estimate a feerate")
+	}
+	return feePerKB, nil
+}
+
+func (m *mockFeeSource) FeeHistogram(_ context.Context) ([][2]float64, error) {
+	if m.histogram == nil {
+		return nil, errors.New("no histogram")
+	}
+	return m.histogram, nil
+}
+
+func TestNetworkFeeEstimator(t *testing.T) {
+	source := &mockFeeSource{
+		feePerKB: map[int64]int64{1: 25500, 3: 12000},
+		histogram: [][2]float64{
+			{40, 400_000},
+			{20, 800_000},
+			{10, 900_000},
+			{5, 2_000_000},
+		},
+	}
+	e := NewNetworkFeeEstimator(source, map[FeeLevel]int64{ECONOMIC: 2})
+	ctx := context.Background()
+
+	// server estimates rounded up to sats/vbyte
+	feeRate, err := e.FeeRateForLevel(ctx, PRIORITY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeRate != 26 {
+		t.Fatalf("expected 26 sats/vbyte - got %d", feeRate)
+	}
+	feeRate, _ = e.FeeRateForLevel(ctx, NORMAL)
+	if feeRate != 12 {
+		t.Fatalf("expected 12 sats/vbyte - got %d", feeRate)
+	}
+
+	// configured target 2 has no server estimate so the histogram is used;
+	// 2 blocks worth is reached in the 10 sats/vbyte bin
+	feeRate, _ = e.FeeRateForLevel(ctx, ECONOMIC)
+	if feeRate != 11 {
+		t.Fatalf("expected 11 sats/vbyte - got %d", feeRate)
+	}
+
+	// the mempool is smaller than 25 blocks
+	feeRate, _ = e.FeeRateForLevel(ctx, SUPER_ECONOMIC)
+	if feeRate != minRelayFeeRate {
+		t.Fatalf("expected minimum fee rate - got %d", feeRate)
+	}
+
+	// cached
+	calls := source.calls
+	e.FeeRateForLevel(ctx, PRIORITY)
+	if source.calls != calls {
+		t.Fatal("expected a cached estimate")
+	}
+
+	// no estimate at all
+	source.histogram = nil
+	_, err = e.EstimateFeeRate(ctx, 10)
+	if err != ErrNoFeeEstimate {
+		t.Fatalf("expected %v - got %v", ErrNoFeeEstimate, err)
+	}
+
+	// the fee provider uses the estimator capped at MaxFee and falls back
+	// to the default fees
+	fp := DefaultFeeProvider()
+	fp.Estimator = e
+	if fp.GetFeePerByte(PRIORITY) != 26 {
+		t.Fatal("expected the estimated fee")
+	}
+	fp.MaxFee = 20
+	if fp.GetFeePerByte(PRIORITY) != 20 {
+		t.Fatal("expected the max fee")
+	}
+	e.ConfTargets[NORMAL] = 10
+	if fp.GetFeePerByte(NORMAL) != fp.NormalFee {
+		t.Fatal("expected the default fee")
+	}
+}
@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Feed publishes values to any number of subscribers. A nil feed publishes
+// nothing and closes its subscriptions at once.
+type Feed[T any] struct {
+	mtx    sync.Mutex
+	subs   map[*FeedSubscription[T]]struct{}
+	closed bool
+}
+
+func NewFeed[T any]() *Feed[T] {
+	return &Feed[T]{
+		subs: make(map[*FeedSubscription[T]]struct{}),
+	}
+}
+
+// FeedSubscription receives values on C until Unsubscribe is called or the feed
+// is closed, when C is closed. Values are dropped, and counted by Missed, if
+// C's buffer is full.
+type FeedSubscription[T any] struct {
+	C      <-chan T
+	c      chan T
+	feed   *Feed[T]
+	missed atomic.Int64
+}
+
+// Subscribe returns a new subscription with a channel buffer of buffer values
+func (f *Feed[T]) Subscribe(buffer int) *FeedSubscription[T] {
+	c := make(chan T, buffer)
+	sub := &FeedSubscription[T]{
+		C:    c,
+		c:    c,
+		feed: f,
+	}
+	if f == nil {
+		close(c)
+		return sub
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.closed {
+		close(c)
+		return sub
+	}
+	f.subs[sub] = struct{}{}
+	return sub
+}
+
+// HasSubscribers returns true if any subscriber would receive a value
+func (f *Feed[T]) HasSubscribers() bool {
+	if f == nil {
+		return false
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return len(f.subs) > 0
+}
+
+// Publish sends v to all subscribers without blocking
+func (f *Feed[T]) Publish(v T) {
+	if f == nil {
+		return
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for sub := range f.subs {
+		select {
+		case sub.c <- v:
+		default:
+			sub.missed.Add(1)
+		}
+	}
+}
+
+// Close closes all subscriptions. Later subscriptions are closed at once.
+func (f *Feed[T]) Close() {
+	if f == nil {
+		return
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for sub := range f.subs {
+		close(sub.c)
+	}
+	f.subs = make(map[*FeedSubscription[T]]struct{})
+	f.closed = true
+}
+
+// Unsubscribe stops delivery of values and closes C
+func (s *FeedSubscription[T]) Unsubscribe() {
+	f := s.feed
+	if f == nil {
+		return
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if _, ok := f.subs[s]; ok {
+		delete(f.subs, s)
+		close(s.c)
+	}
+}
+
+// Missed returns the number of values dropped because C was full
+func (s *FeedSubscription[T]) Missed() int64 {
+	return s.missed.Load()
+}
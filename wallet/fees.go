@@ -1,14 +1,19 @@
 package wallet
 
 import (
+	"context"
 	"encoding/json"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"golang.org/x/net/proxy"
 )
 
+// How long GetFeePerByte waits for the network fee estimator
+const estimatorTimeout = 10 * time.Second
+
 type HttpClient interface {
 	Get(string) (*http.Response, error)
 }
@@ -33,7 +38,15 @@ type FeeProvider struct {
 
 	HttpClient HttpClient
 
+	// Estimator, if set, gives live network fees. The fee API and default
+	// fees are used if it fails.
+	Estimator FeeEstimator
+
 	cache *feeCache
+
+	// fee rates last returned by GetFeePerByte
+	lastMtx sync.Mutex
+	last    map[FeeLevel]int64
 }
 
 func NewFeeProvider(maxFee, priorityFee, normalFee, economicFee int64, feeAPI string, proxy proxy.Dialer) *FeeProvider {
@@ -56,6 +69,38 @@ func NewFeeProvider(maxFee, priorityFee, normalFee, economicFee int64, feeAPI st
 }
 
 func (fp *FeeProvider) GetFeePerByte(feeLevel FeeLevel) int64 {
+	fee := fp.feePerByte(feeLevel)
+	fp.lastMtx.Lock()
+	if fp.last == nil {
+		fp.last = make(map[FeeLevel]int64)
+	}
+	fp.last[feeLevel] = fee
+	fp.lastMtx.Unlock()
+	return fee
+}
+
+// LastFeePerByte returns the fee rate GetFeePerByte last returned for
+// feeLevel, or the default fee if it has not been asked. It never waits on
+// the network.
+func (fp *FeeProvider) LastFeePerByte(feeLevel FeeLevel) int64 {
+	fp.lastMtx.Lock()
+	defer fp.lastMtx.Unlock()
+	fee, ok := fp.last[feeLevel]
+	if !ok {
+		return fp.defaultFee(feeLevel)
+	}
+	return fee
+}
+
+func (fp *FeeProvider) feePerByte(feeLevel FeeLevel) int64 {
+	if fp.Estimator != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), estimatorTimeout)
+		feeRate, err := fp.Estimator.FeeRateForLevel(ctx, feeLevel)
+		cancel()
+		if err == nil && feeRate > 0 {
+			return fp.selectFee(feeRate, feeLevel)
+		}
+	}
 	if fp.FeeAPI == "" {
 		return fp.defaultFee(feeLevel)
 	}
@@ -81,9 +126,9 @@ func (fp *FeeProvider) GetFeePerByte(feeLevel FeeLevel) int64 {
 	case PRIORITY:
 		return fp.selectFee(fees.Priority, PRIORITY)
 	case NORMAL:
-		return fp.selectFee(fees.Normal, PRIORITY)
+		return fp.selectFee(fees.Normal, NORMAL)
 	case ECONOMIC:
-		return fp.selectFee(fees.Economic, PRIORITY)
+		return fp.selectFee(fees.Economic, ECONOMIC)
 	case FEE_BUMP:
 		return fp.selectFee(fees.Priority, PRIORITY)
 	default:
@@ -120,3 +165,23 @@ func (fp *FeeProvider) defaultFee(feeLevel FeeLevel) int64 {
 func DefaultFeeProvider() *FeeProvider {
 	return NewFeeProvider(int64(1000), int64(50), int64(30), int64(20), "", nil)
 }
+
+// ConfigFeeProvider returns the default fee provider with any fees set in the
+// wallet config replacing the defaults and using the config's fee estimator.
+func ConfigFeeProvider(config *WalletConfig) *FeeProvider {
+	fp := DefaultFeeProvider()
+	if config.MaxFee > 0 {
+		fp.MaxFee = config.MaxFee
+	}
+	if config.HighFee > 0 {
+		fp.PriorityFee = config.HighFee
+	}
+	if config.MediumFee > 0 {
+		fp.NormalFee = config.MediumFee
+	}
+	if config.LowFee > 0 {
+		fp.EconomicFee = config.LowFee
+	}
+	fp.Estimator = config.FeeEstimator
+	return fp
+}
@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"sort"
+	"time"
+)
+
+// TxDirection is the direction of a wallet transaction's funds
+type TxDirection string
+
+const (
+	// Funds paid into the wallet from outside
+	TxReceive TxDirection = "receive"
+	// Funds paid out of the wallet
+	TxSend TxDirection = "send"
+	// Wallet funds paid only back to the wallet
+	TxSelfTransfer TxDirection = "self"
+)
+
+// TxHistoryEntry describes one wallet transaction for a history listing.
+type TxHistoryEntry struct {
+	Txid      string
+	Direction TxDirection
+	// Net change to the wallet balance in satoshis; negative for a send
+	Amount int64
+	// Fee paid in satoshis. It is only known, and non-zero, when the wallet
+	// funded all of the inputs.
+	Fee int64
+	// Block height or 0 if unconfirmed, -1 if dead
+	Height        int64
+	Confirmations int64
+	// StatusPending, StatusStuck, StatusConfirmed, StatusReplaced or
+	// StatusDead
+	Status StatusCode
+	// Txid of the conflicting tx that replaced a replaced tx, if known
+	ReplacedBy string
+	// Time the tx was first seen by the wallet
+	Timestamp time.Time
+	// Time of the block that mined the tx or zero if unconfirmed or unknown
+	BlockTime time.Time
+	// Addresses of the other party. For a send these are the addresses paid,
+	// for a receive the addresses of the inputs where they can be worked out.
+	Counterparties []string
+	// User label for the tx if any
+	Label string
+}
+
+// HistoryFilter selects and pages through a transaction history. Zero values
+// do not filter.
+type HistoryFilter struct {
+	// Only txs at or after this time. The block time is used if known else the
+	// first seen time.
+	FromTime time.Time
+	// Only txs before this time
+	ToTime time.Time
+	// Only txs mined at or above this height. Excludes unconfirmed txs.
+	FromHeight int64
+	// Only txs mined at or below this height. Excludes unconfirmed txs.
+	ToHeight int64
+	// Number of matching txs to skip
+	Offset int
+	// Maximum number of txs to return
+	Limit int
+}
+
+// SortHistory sorts entries newest first: unconfirmed then by descending
+// height and first seen time. Dead txs are last.
+func SortHistory(entries []TxHistoryEntry) {
+	rank := func(height int64) int64 {
+		switch {
+		case height == 0:
+			return int64(^uint64(0) >> 1)
+		case height < 0:
+			return -1
+		default:
+			return height
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, rj := rank(entries[i].Height), rank(entries[j].Height)
+		if ri != rj {
+			return ri > rj
+		}
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+}
+
+// FilterHistory returns the entries matching filter. Entries should already
+// be sorted.
+func FilterHistory(entries []TxHistoryEntry, filter *HistoryFilter) []TxHistoryEntry {
+	if filter == nil {
+		return entries
+	}
+	var matched []TxHistoryEntry
+	for _, e := range entries {
+		if filter.FromHeight > 0 && e.Height < filter.FromHeight {
+			continue
+		}
+		if filter.ToHeight > 0 && (e.Height <= 0 || e.Height > filter.ToHeight) {
+			continue
+		}
+		t := e.BlockTime
+		if t.IsZero() {
+			t = e.Timestamp
+		}
+		if !filter.FromTime.IsZero() && t.Before(filter.FromTime) {
+			continue
+		}
+		if !filter.ToTime.IsZero() && !t.Before(filter.ToTime) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	if filter.Offset > 0 {
+		if filter.Offset >= len(matched) {
+			return nil
+		}
+		matched = matched[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -39,6 +40,39 @@ type WalletConfig struct {
 	// The highest allowable fee-per-byte
 	MaxFee int64
 
+	// The highest allowable total fee for a spend in satoshis. Zero is no
+	// limit.
+	MaxAbsoluteFee int64
+
+	// The highest allowable fee for a spend as a percentage of the amount
+	// paid. Zero is no limit.
+	MaxFeePercent float64
+
+	// Live network fee estimator. If nil or failing the fee API or the
+	// default fees are used.
+	FeeEstimator FeeEstimator
+
+	// Coin selection strategy for spends. If nil DefaultCoinSelector is used.
+	CoinSelector CoinSelector
+
+	// Number of unused addresses kept past the last used address of each
+	// chain. Zero is the wallet's stored gap limit or DefaultGapLimit.
+	GapLimit int
+
+	// What NewAddress does when all addresses within the gap limit have been
+	// issued
+	GapPolicy GapPolicy
+
+	// Birthday of a recreated wallet: the date and block height before which
+	// the wallet has no transactions. Zero values are unknown. New wallets
+	// are born now at BirthdayHeight, the current tip if known.
+	Birthday       time.Time
+	BirthdayHeight int64
+
+	// The number of confirmations of a wallet transaction notified by
+	// EventTxConfirmed events. Default DefaultEventConfirmations.
+	EventConfirmations int
+
 	// If not testing do not overwrite existing wallet files
 	Testing bool
 }
@@ -48,9 +82,16 @@ type ElectrumWallet interface {
 	// Start the wallet
 	Start()
 
-	// Return the creation date of the wallet
+	// Return the creation date of the wallet, the wallet birthday. Zero if
+	// not known.
 	CreationDate() time.Time
 
+	// Return the block height of the wallet birthday. Zero if not known.
+	BirthdayHeight() int64
+
+	// Set the block height of the wallet birthday when it was not known
+	SetBirthdayHeight(height int64) error
+
 	// Return the network parameters
 	Params() *chaincfg.Params
 
@@ -71,6 +112,16 @@ type ElectrumWallet interface {
 	// unused address.
 	GetUnusedAddress(purpose KeyPurpose) (btcutil.Address, error)
 
+	// NewAddress issues a new address that has not been returned by
+	// NewAddress before, for example for an invoice. When every address
+	// within the gap limit has been issued and none used the wallet's
+	// GapPolicy applies. It returns true for an address past the gap limit,
+	// which a wallet restored from the seed may not find payments to.
+	NewAddress(purpose KeyPurpose) (btcutil.Address, bool, error)
+
+	// The number of unused addresses kept past the last used address
+	GapLimit() int
+
 	// GetUnusedLegacyAddress returns an address suitable for receiving payments
 	// from legacy wallets, exchanges, etc. It will only give out external addr-
 	// esses for receiving funds; not change addresses.
@@ -80,6 +131,14 @@ type ElectrumWallet interface {
 	// wallet address and the wallet password.
 	GetPrivKeyForAddress(pw string, address btcutil.Address) (string, error)
 
+	// SignMessage signs message with the key of a wallet address to prove
+	// control of the address. The signature is base64 encoded.
+	SignMessage(pw string, address btcutil.Address, message string, format MessageFormat) (string, error)
+
+	// VerifyMessage returns true if signature is a BIP137 or BIP322 simple
+	// signature of message by address
+	VerifyMessage(address btcutil.Address, message, signature string) (bool, error)
+
 	// Marks the address as used (involved in at least one transaction)
 	MarkAddressUsed(address btcutil.Address) error
 
@@ -148,9 +207,25 @@ type ElectrumWallet interface {
 	// Get info on a specific transaction - currently unused
 	GetTransaction(txid string) (*Txn, error)
 
+	// Mark an unconfirmed wallet transaction as stuck, or not stuck, so its
+	// status is StatusStuck. Stuck is not persisted; the client works it out
+	// from the blocks mined since the tx was first seen.
+	SetTxStuck(txid string, stuck bool) error
+
+	// Mark an unconfirmed wallet transaction as replaced by a conflicting
+	// transaction, replacedBy, that is not a wallet transaction: usually an
+	// incoming payment double spent by the payer. The wallet transaction is
+	// dropped and the wallet coins it spent are restored.
+	SetTxReplaced(txid, replacedBy string) error
+
 	// Return the calculated confirmed txids and heights for an address - unused
 	GetWalletAddressHistory(address btcutil.Address) ([]AddressHistory, error)
 
+	// Return the wallet transaction history newest first with direction,
+	// amount, fee, confirmations and status for each tx. Block times are not
+	// known to the wallet and are left zero.
+	TxHistory() ([]TxHistoryEntry, error)
+
 	// Add a transaction to the database
 	AddTransaction(tx *wire.MsgTx, height int64, timestamp time.Time) error
 
@@ -173,9 +248,32 @@ type ElectrumWallet interface {
 	// Set the utxo as spendable again
 	UnFreezeUTXO(op *wire.OutPoint) error
 
+	// Set a label on a tx, address, output or other reference. An empty label
+	// text removes the label.
+	SetLabel(label Label) error
+
+	// Get the label for a reference
+	GetLabel(labelType LabelType, ref string) (*Label, error)
+
+	// List the labels of a type or all labels if labelType is empty
+	ListLabels(labelType LabelType) ([]Label, error)
+
+	// Import BIP329 JSON Lines labels returning the number imported. Wallet
+	// outputs are frozen or unfrozen as labelled spendable; outputs not in
+	// the wallet yet are frozen when they arrive.
+	ImportLabels(r io.Reader) (int, error)
+
+	// Export all labels as BIP329 JSON Lines with the frozen state of wallet
+	// outputs
+	ExportLabels(w io.Writer) error
+
 	// Make a new spending transaction
 	Spend(pw string, amount int64, toAddress btcutil.Address, feeLevel FeeLevel) (int, *wire.MsgTx, error)
 
+	// Make a new spending transaction as described by SpendInfo. This allows
+	// send-all (drain wallet) and subtract-fee-from-outputs spends.
+	SpendTx(pw string, info *SpendInfo) (*SpendResult, error)
+
 	// Calculates the estimated size of the transaction and returns the total fee for the given feePerByte
 	EstimateFee(ins []InputInfo, outs []TransactionOutput, feePerByte int64) int64
 
@@ -185,10 +283,39 @@ type ElectrumWallet interface {
 	// CPFP logic; rbf not supported
 	BumpFee(txid string) (*wire.MsgTx, error)
 
+	// Change the wallet password re-encrypting the stored secrets
+	ChangePassword(oldPw, newPw string) error
+
+	// Export BIP380 output descriptors for the receive and change address
+	// chains. Private descriptors need the password and a signing wallet.
+	ExportDescriptors(pw string, private bool) (*WalletDescriptors, error)
+
+	// True if the wallet has no private keys and cannot sign
+	WatchOnly() bool
+
+	// The address at index of the purpose (receive or change) chain of a
+	// standard account. Used for account discovery.
+	AccountAddress(account *Account, purpose KeyPurpose, index int) (btcutil.Address, error)
+
+	// Add accounts found with history by account discovery to the wallet.
+	// Their addresses are then watched and their coins are spendable.
+	EnableAccounts(pw string, accounts []Account) error
+
+	// List the accounts added by EnableAccounts
+	ListAccounts() []Account
+
+	// Hex BIP32 fingerprint of the master key. Users can check it to confirm
+	// a BIP39 passphrase was typed correctly.
+	MasterFingerprint() (string, error)
+
 	// Update the height of the tip from the headers chain & the blockchain sync status.
 	UpdateTip(newTip int64, synced bool)
 
-	// Cleanly disconnect from the wallet
+	// SubscribeEvents returns a subscription to wallet events with a channel
+	// buffer of buffer events
+	SubscribeEvents(buffer int) *EventSubscription
+
+	// Cleanly disconnect from the wallet. Event subscriptions are closed.
 	Close()
 }
 
@@ -205,8 +332,87 @@ var (
 	// This is due to a concrete wallet not implementing the functionality or
 	// temporarily during development.
 	ErrWalletFnNotImplemented = errors.New("wallet function is not implemented")
+
+	// ErrNoOutputs is returned when a spend is requested with no outputs
+	ErrNoOutputs = errors.New("no outputs to pay")
+
+	// ErrSendAllOutputs is returned when a send-all spend does not have
+	// exactly one output to receive the funds
+	ErrSendAllOutputs = errors.New("send all requires exactly one output")
+
+	// ErrBadSubtractFeeIndex is returned when a subtract fee output index
+	// does not reference one of the spend outputs
+	ErrBadSubtractFeeIndex = errors.New("subtract fee output index out of range")
+
+	// ErrDataOutput is returned when a spend has an invalid OP_RETURN data
+	// output
+	ErrDataOutput = errors.New("invalid data output")
+
+	// ErrUnknownCoin is returned when a chosen outpoint is not a wallet utxo
+	ErrUnknownCoin = errors.New("coin is not a wallet utxo")
+
+	// ErrUnknownTx is returned for a txid that is not a wallet transaction
+	ErrUnknownTx = errors.New("not a wallet transaction")
+
+	// ErrFrozenCoin is returned when a chosen outpoint is frozen
+	ErrFrozenCoin = errors.New("coin is frozen")
+
+	// ErrUnconfirmedCoin is returned when a chosen outpoint is unconfirmed and
+	// unconfirmed coins are not allowed for the spend
+	ErrUnconfirmedCoin = errors.New("coin is unconfirmed")
+
+	// ErrWatchOnly is returned when a watch-only wallet is asked to sign or
+	// for private keys
+	ErrWatchOnly = errors.New("wallet is watch-only")
+
+	// ErrRelativeLockNotMet is returned when the BIP68 relative lock of a
+	// spend input is not met at the current tip
+	ErrRelativeLockNotMet = errors.New("relative lock time not met")
+
+	// ErrLockTimeNotEnforced is returned when a spend sets a lock time but
+	// every input has a final sequence number, which disables it
+	ErrLockTimeNotEnforced = errors.New("lock time is not enforced with final input sequences")
 )
 
+// FeeLimit identifies a fee guard
+type FeeLimit int
+
+const (
+	// The MaxFee fee-per-byte ceiling
+	FeeRateLimit FeeLimit = iota
+	// The MaxAbsoluteFee total fee limit
+	AbsoluteFeeLimit
+	// The MaxFeePercent limit
+	FeePercentLimit
+)
+
+func (l FeeLimit) String() string {
+	switch l {
+	case FeeRateLimit:
+		return "fee rate"
+	case AbsoluteFeeLimit:
+		return "absolute fee"
+	case FeePercentLimit:
+		return "fee percent"
+	default:
+		return "unknown"
+	}
+}
+
+// FeeLimitError is returned instead of building a transaction when a spend
+// would pay a fee above one of the configured guards.
+type FeeLimitError struct {
+	Limit FeeLimit
+	// The fee rate, fee or fee percentage of the spend
+	Value float64
+	// The configured maximum
+	Max float64
+}
+
+func (e *FeeLimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded: %v > %v", e.Limit, e.Value, e.Max)
+}
+
 type FeeLevel int
 
 const (
@@ -231,6 +437,100 @@ const (
 	CHANGE    = INTERNAL
 )
 
+// DefaultGapLimit is the default number of unused addresses kept past the
+// last used address of each chain
+const DefaultGapLimit = 10
+
+// ErrGapLimit is returned by NewAddress with GapPolicyError when all of the
+// addresses within the gap limit have been issued
+var ErrGapLimit = errors.New("gap limit reached: all unused addresses have been issued")
+
+// GapPolicy is what NewAddress does when all of the addresses within the gap
+// limit have been issued and none has been used
+type GapPolicy int
+
+const (
+	// Return ErrGapLimit
+	GapPolicyError GapPolicy = iota
+	// Issue the oldest unused address again
+	GapPolicyReuse
+	// Issue addresses past the gap limit, flagged by NewAddress. A wallet
+	// restored from the seed with the same gap limit may not find payments to
+	// them.
+	GapPolicyExtend
+)
+
+func (p GapPolicy) String() string {
+	switch p {
+	case GapPolicyError:
+		return "error"
+	case GapPolicyReuse:
+		return "reuse"
+	case GapPolicyExtend:
+		return "extend"
+	}
+	return "unknown"
+}
+
+// ErrMessageFormat is returned when a message signature format cannot be used
+// with an address
+var ErrMessageFormat = errors.New("message signature format not supported for address")
+
+// MessageFormat is the format of a message signature
+type MessageFormat int
+
+const (
+	// BIP137 for P2PKH, P2SH-P2WPKH & P2WPKH addresses and BIP322 simple
+	// for P2TR addresses
+	MessageFormatDefault MessageFormat = iota
+	// Compact ECDSA signature with the address type in the header byte as
+	// made by Bitcoin Core's signmessage for P2PKH and by Electrum & hardware
+	// wallets for segwit
+	MessageFormatBIP137
+	// Witness of a virtual transaction spending from a P2WPKH or P2TR address
+	MessageFormatBIP322
+)
+
+func (f MessageFormat) String() string {
+	switch f {
+	case MessageFormatDefault:
+		return "default"
+	case MessageFormatBIP137:
+		return "bip137"
+	case MessageFormatBIP322:
+		return "bip322"
+	}
+	return "unknown"
+}
+
+// AddressType is the kind of address the wallet makes from its keys
+type AddressType int
+
+const (
+	// Native segwit v0 pay to witness pubkey hash
+	AddrP2WPKH AddressType = iota
+	// Legacy pay to pubkey hash
+	AddrP2PKH
+	// Segwit v0 pay to witness pubkey hash nested in pay to script hash
+	AddrP2SHP2WPKH
+	// Segwit v1 taproot key path only (BIP86)
+	AddrP2TR
+)
+
+func (t AddressType) String() string {
+	switch t {
+	case AddrP2WPKH:
+		return "p2wpkh"
+	case AddrP2PKH:
+		return "p2pkh"
+	case AddrP2SHP2WPKH:
+		return "p2sh-p2wpkh"
+	case AddrP2TR:
+		return "p2tr"
+	}
+	return "unknown"
+}
+
 type AddressHistory struct {
 	Height int64
 	TxHash chainhash.Hash
@@ -240,13 +540,104 @@ type TransactionOutput struct {
 	Address btcutil.Address
 	Value   int64
 	Index   uint32
+	// Data makes this an unspendable OP_RETURN output carrying up to
+	// MaxDataCarrierSize bytes. A data output has no Address and zero Value.
+	Data []byte
 }
 
+// MaxDataCarrierSize is the most data bytes in an OP_RETURN output relayed
+// by nodes. Only one data output per transaction is relayed.
+const MaxDataCarrierSize = 80
+
 type SigningInfo struct {
 	UnsignedTx *wire.MsgTx
 	VerifyTx   bool
 }
 
+// SpendInfo describes a spend from the wallet for SpendTx.
+type SpendInfo struct {
+	// Outputs to pay. Only Address and Value are used, or Data for an
+	// OP_RETURN output.
+	Outputs []TransactionOutput
+	// Fee level to use for the fee rate
+	FeeLevel FeeLevel
+	// FeeRate is an explicit fee rate in sats/vbyte. If set it is used rather
+	// than FeeLevel.
+	FeeRate int64
+	// AbsoluteFee is an explicit total fee in satoshis. If set it is used
+	// rather than FeeRate or FeeLevel. If there is no change output any
+	// leftover too small for change is added to the fee.
+	AbsoluteFee int64
+	// SendAll spends all spendable coins to the single address output in
+	// Outputs, beside any data output. No change output is made and the fee
+	// is taken from the output. The value of the output is ignored.
+	SendAll bool
+	// SubtractFeeFrom lists indexes into Outputs that pay the fee between
+	// them, split equally, rather than the wallet adding the fee on top.
+	SubtractFeeFrom []int
+	// IncludeUnconfirmed allows unconfirmed wallet coins to be spent. By
+	// default only confirmed coins are spent.
+	IncludeUnconfirmed bool
+	// Coins is the may-use set. It restricts the spend to these wallet
+	// outpoints plus any in MustUse. A send-all spends all of them. If empty
+	// all spendable wallet coins may be used.
+	Coins []wire.OutPoint
+	// MustUse is the must-use set. These wallet outpoints are always spent
+	// whether or not they are needed to pay the outputs.
+	MustUse []wire.OutPoint
+	// CoinSelector chooses the coins to spend. If nil the wallet's configured
+	// selector is used. Not used for a send-all.
+	CoinSelector CoinSelector
+	// LockTime is the transaction nLockTime, a block height if below
+	// txscript.LockTimeThreshold else a unix time. A transaction locked past
+	// the next block cannot be broadcast until then; see SpendResult
+	// BroadcastHeight. If zero the lock time is the current tip to discourage
+	// fee sniping.
+	LockTime uint32
+	// NoAntiFeeSniping leaves the lock time zero when LockTime is not set
+	NoAntiFeeSniping bool
+	// Sequences sets the nSequence of the inputs spending these wallet
+	// outpoints, for example a BIP68 relative lock made by RelativeLockBlocks
+	// or RelativeLockTime. The outpoints are always spent, as in MustUse.
+	// Relative height locks must be met at the current tip.
+	Sequences map[wire.OutPoint]uint32
+}
+
+// RelativeLockBlocks returns the BIP68 nSequence of an input that cannot be
+// mined until its coin has blocks confirmations
+func RelativeLockBlocks(blocks uint16) uint32 {
+	return uint32(blocks)
+}
+
+// RelativeLockTime returns the BIP68 nSequence of an input that cannot be
+// mined until d after its coin was mined. d is rounded up to the 512 second
+// granularity of time locks.
+func RelativeLockTime(d time.Duration) uint32 {
+	units := (int64(d/time.Second) + (1 << wire.SequenceLockTimeGranularity) - 1) >> wire.SequenceLockTimeGranularity
+	if units > wire.SequenceLockTimeMask {
+		units = wire.SequenceLockTimeMask
+	}
+	return wire.SequenceLockTimeIsSeconds | uint32(units)
+}
+
+// SpendResult is the result of a successful SpendTx.
+type SpendResult struct {
+	// The signed transaction
+	Tx *wire.MsgTx
+	// Index of the change output in Tx.TxOut or -1 if there is no change
+	ChangeIndex int
+	// The total fee paid in satoshis
+	Fee int64
+	// The wallet outpoints spent by Tx in input order
+	Inputs []wire.OutPoint
+	// The waste metric of the coin selection in satoshis. See SelectionWaste.
+	Waste int64
+	// The first block height Tx can be mined at if its height lock time is
+	// past the next block, else zero. Tx is rejected as non-final if it is
+	// broadcast before the block below this height is mined.
+	BroadcastHeight int64
+}
+
 type InputInfo struct {
 	Outpoint      *wire.OutPoint
 	Height        int64
@@ -0,0 +1,303 @@
+package wltbtc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	hd "github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Accounts found by account discovery. Their keys are not stored in the keys
+// database; a window of each chain up to the gap limit past the last used key
+// is watched and new receive & change addresses always come from the wallet's
+// own chains.
+
+type accountKeys struct {
+	account  wallet.Account
+	internal *hd.ExtendedKey
+	external *hd.ExtendedKey
+	params   *chaincfg.Params
+	// watched addresses of both chains & their key paths keyed on the hex
+	// script address
+	addresses []btcutil.Address
+	paths     map[string]wallet.KeyPath
+	// number of watched keys on each chain
+	watched  map[wallet.KeyPurpose]int
+	gapLimit int
+}
+
+// deriveAccountKeys returns the chain keys of a standard account
+func deriveAccountKeys(masterPrivKey *hd.ExtendedKey, account *wallet.Account) (internal, external *hd.ExtendedKey, err error) {
+	key := masterPrivKey
+	for _, index := range account.Path() {
+		key, err = key.Derive(index)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	external, err = key.Derive(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	internal, err = key.Derive(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return internal, external, nil
+}
+
+func newAccountKeys(account wallet.Account, internal, external *hd.ExtendedKey, params *chaincfg.Params, gapLimit int) (*accountKeys, error) {
+	ak := &accountKeys{
+		account:  account,
+		internal: internal,
+		external: external,
+		params:   params,
+		paths:    make(map[string]wallet.KeyPath),
+		watched:  make(map[wallet.KeyPurpose]int),
+		gapLimit: gapLimit,
+	}
+	if err := ak.extendAll(); err != nil {
+		return nil, err
+	}
+	return ak, nil
+}
+
+// extendAll watches keys of both chains up to the gap limit past the last
+// used keys
+func (ak *accountKeys) extendAll() error {
+	if err := ak.extend(wallet.EXTERNAL, ak.account.LastReceive); err != nil {
+		return err
+	}
+	return ak.extend(wallet.INTERNAL, ak.account.LastChange)
+}
+
+func (ak *accountKeys) chainKey(purpose wallet.KeyPurpose) *hd.ExtendedKey {
+	if purpose == wallet.INTERNAL {
+		return ak.internal
+	}
+	return ak.external
+}
+
+// extend watches keys of the purpose chain up to the gap limit past lastUsed
+func (ak *accountKeys) extend(purpose wallet.KeyPurpose, lastUsed int) error {
+	for i := ak.watched[purpose]; i <= lastUsed+ak.gapLimit; i++ {
+		key, err := ak.chainKey(purpose).Derive(uint32(i))
+		if err != nil {
+			// invalid bip32 child; skip it
+			ak.watched[purpose] = i + 1
+			continue
+		}
+		address, err := addressForKey(key, ak.account.AddressType, ak.params)
+		key.Zero()
+		if err != nil {
+			return err
+		}
+		ak.addresses = append(ak.addresses, address)
+		ak.paths[string(address.ScriptAddress())] = wallet.KeyPath{Purpose: purpose, Index: i}
+		ak.watched[purpose] = i + 1
+	}
+	return nil
+}
+
+// addAccount watches an account's keys. An account with the same path is
+// replaced.
+func (km *KeyManager) addAccount(account wallet.Account, internal, external *hd.ExtendedKey) error {
+	ak, err := newAccountKeys(account, internal, external, km.params, km.gapLimit)
+	if err != nil {
+		return err
+	}
+	km.accountsMutex.Lock()
+	defer km.accountsMutex.Unlock()
+	for i, existing := range km.accounts {
+		if existing.account.String() == account.String() {
+			km.accounts[i] = ak
+			return nil
+		}
+	}
+	km.accounts = append(km.accounts, ak)
+	return nil
+}
+
+// extendAccounts watches the keys of all accounts up to a new gap limit
+func (km *KeyManager) extendAccounts(gapLimit int) error {
+	km.accountsMutex.Lock()
+	defer km.accountsMutex.Unlock()
+	for _, ak := range km.accounts {
+		ak.gapLimit = gapLimit
+		if err := ak.extendAll(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (km *KeyManager) accountKeyForScript(scriptAddress []byte) (*hd.ExtendedKey, bool) {
+	km.accountsMutex.RLock()
+	defer km.accountsMutex.RUnlock()
+	for _, ak := range km.accounts {
+		if path, ok := ak.paths[string(scriptAddress)]; ok {
+			key, err := ak.chainKey(path.Purpose).Derive(uint32(path.Index))
+			if err != nil {
+				return nil, false
+			}
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// markAccountKeyUsed updates the last used index of an account chain and
+// extends the watched window. It returns false if the key is not an account
+// key.
+func (km *KeyManager) markAccountKeyUsed(scriptAddress []byte) bool {
+	km.accountsMutex.Lock()
+	defer km.accountsMutex.Unlock()
+	for _, ak := range km.accounts {
+		path, ok := ak.paths[string(scriptAddress)]
+		if !ok {
+			continue
+		}
+		last := &ak.account.LastReceive
+		if path.Purpose == wallet.INTERNAL {
+			last = &ak.account.LastChange
+		}
+		if path.Index > *last {
+			*last = path.Index
+			if err := ak.extend(path.Purpose, path.Index); err != nil {
+				fmt.Println(err)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// AccountAddresses returns the watched addresses of all accounts
+func (km *KeyManager) AccountAddresses() []btcutil.Address {
+	km.accountsMutex.RLock()
+	defer km.accountsMutex.RUnlock()
+	var addresses []btcutil.Address
+	for _, ak := range km.accounts {
+		addresses = append(addresses, ak.addresses...)
+	}
+	return addresses
+}
+
+// Accounts returns the accounts with their last used indexes
+func (km *KeyManager) Accounts() []wallet.Account {
+	km.accountsMutex.RLock()
+	defer km.accountsMutex.RUnlock()
+	accounts := make([]wallet.Account, 0, len(km.accounts))
+	for _, ak := range km.accounts {
+		accounts = append(accounts, ak.account)
+	}
+	return accounts
+}
+
+// AccountAddress returns the address at index of the purpose chain of a
+// standard account. The wallet needs its master private key.
+func (w *BtcElectrumWallet) AccountAddress(account *wallet.Account, purpose wallet.KeyPurpose, index int) (btcutil.Address, error) {
+	master, err := w.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	defer master.Zero()
+	internal, external, err := deriveAccountKeys(master, account)
+	if err != nil {
+		return nil, err
+	}
+	chain := external
+	if purpose == wallet.INTERNAL {
+		chain = internal
+	}
+	key, err := chain.Derive(uint32(index))
+	internal.Zero()
+	external.Zero()
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+	return addressForKey(key, account.AddressType, w.params)
+}
+
+// EnableAccounts adds accounts found by account discovery to the wallet and
+// stores them. Accounts already in the wallet are updated.
+func (w *BtcElectrumWallet) EnableAccounts(pw string, accounts []wallet.Account) error {
+	if ok := w.storageManager.IsValidPw(pw); !ok {
+		return errors.New("invalid password")
+	}
+	master, err := w.masterKey()
+	if err != nil {
+		return err
+	}
+	defer master.Zero()
+	for _, account := range accounts {
+		addrType, err := wallet.PurposeAddressType(account.Purpose)
+		if err != nil {
+			return err
+		}
+		if addrType != account.AddressType {
+			return fmt.Errorf("%w: %s is not a %s account", wallet.ErrInvalidAccount, account.String(), account.AddressType)
+		}
+	}
+	for _, account := range accounts {
+		internal, external, err := deriveAccountKeys(master, &account)
+		if err != nil {
+			return err
+		}
+		err = w.keyManager.addAccount(account, internal, external)
+		if err != nil {
+			return err
+		}
+	}
+	w.storageManager.store.Accounts = w.keyManager.Accounts()
+	err = w.storageManager.Put(pw)
+	if err != nil {
+		return err
+	}
+	w.txstore.PopulateAdrs()
+	return nil
+}
+
+// ListAccounts lists the accounts added by account discovery
+func (w *BtcElectrumWallet) ListAccounts() []wallet.Account {
+	return w.keyManager.Accounts()
+}
+
+// loadAccounts watches the stored accounts of a loaded wallet
+func (w *BtcElectrumWallet) loadAccounts() error {
+	accounts := w.storageManager.store.Accounts
+	if len(accounts) == 0 {
+		return nil
+	}
+	master, err := w.masterKey()
+	if err != nil {
+		return err
+	}
+	defer master.Zero()
+	for _, account := range accounts {
+		internal, external, err := deriveAccountKeys(master, &account)
+		if err != nil {
+			return err
+		}
+		err = w.keyManager.addAccount(account, internal, external)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// masterKey returns the master private key of a seeded wallet
+func (w *BtcElectrumWallet) masterKey() (*hd.ExtendedKey, error) {
+	if w.watchOnly {
+		return nil, wallet.ErrWatchOnly
+	}
+	if w.storageManager.store.Xprv == "" {
+		return nil, errors.New("wallet has no master key")
+	}
+	return hd.NewKeyFromString(w.storageManager.store.Xprv)
+}
@@ -0,0 +1,136 @@
+package wltbtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestAccountAddress(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	config := &wallet.WalletConfig{
+		Params: &chaincfg.MainNetParams,
+		DB:     newMockDatastore(),
+	}
+	w, err := RecreateElectrumWallet(config, "abc", mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BIP44/49/84/86 test vectors
+	tests := []struct {
+		purpose uint32
+		chain   wallet.KeyPurpose
+		want    string
+	}{
+		{wallet.PurposeBIP44, wallet.EXTERNAL, "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		{wallet.PurposeBIP49, wallet.EXTERNAL, "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		{wallet.PurposeBIP84, wallet.EXTERNAL, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		{wallet.PurposeBIP84, wallet.INTERNAL, "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el"},
+		{wallet.PurposeBIP86, wallet.EXTERNAL, "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+	}
+	for _, test := range tests {
+		account, err := wallet.NewAccount(test.purpose, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := w.AccountAddress(account, test.chain, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != test.want {
+			t.Fatalf("%s: expected %s - got %s", account, test.want, addr)
+		}
+	}
+}
+
+func TestEnableAccounts(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+
+	var accounts []wallet.Account
+	for _, purpose := range []uint32{wallet.PurposeBIP49, wallet.PurposeBIP86} {
+		account, err := wallet.NewAccount(purpose, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		account.LastReceive = 0
+		accounts = append(accounts, *account)
+	}
+	bad := accounts[0]
+	bad.AddressType = wallet.AddrP2TR
+	if err := w.EnableAccounts("abc", []wallet.Account{bad}); err == nil {
+		t.Fatal("expected an invalid account error")
+	}
+	if err := w.EnableAccounts("abc", accounts); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.ListAccounts()) != 2 {
+		t.Fatalf("expected 2 accounts - got %d", len(w.ListAccounts()))
+	}
+
+	// pay to the first receive address of each account
+	fundTx := wire.NewMsgTx(wire.TxVersion)
+	fundTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	for i := range accounts {
+		addr, err := w.AccountAddress(&accounts[i], wallet.EXTERNAL, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !w.HasAddress(addr) {
+			t.Fatalf("expected wallet to have %s", addr)
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fundTx.AddTxOut(wire.NewTxOut(100000, script))
+	}
+	hits, err := w.txstore.AddTransaction(fundTx, 400, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits == 0 {
+		t.Fatal("expected the account outputs to be found")
+	}
+
+	// both outputs sign & verify
+	spendTx := wire.NewMsgTx(wire.TxVersion)
+	fundHash := fundTx.TxHash()
+	for i := range fundTx.TxOut {
+		spendTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundHash, uint32(i)), nil, nil))
+	}
+	changeAddr, err := w.GetUnusedAddress(wallet.INTERNAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeScript, err := txscript.PayToAddrScript(changeAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spendTx.AddTxOut(wire.NewTxOut(190000, changeScript))
+	_, err = w.SignTx("abc", &wallet.SigningInfo{UnsignedTx: spendTx, VerifyTx: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the receive window moves on and the accounts are kept on load
+	lastUsed := w.ListAccounts()[0].LastReceive
+	if lastUsed != 0 {
+		t.Fatalf("expected last receive index 0 - got %d", lastUsed)
+	}
+	loaded, err := loadBtcElectrumWallet(&wallet.WalletConfig{
+		Params: w.params,
+		DB:     w.txstore.Datastore,
+	}, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.ListAccounts()) != 2 {
+		t.Fatalf("expected 2 loaded accounts - got %d", len(loaded.ListAccounts()))
+	}
+}
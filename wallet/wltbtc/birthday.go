@@ -0,0 +1,51 @@
+package wltbtc
+
+import (
+	"errors"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// The wallet birthday is the creation date, stored with PutCreationDate, and
+// the block height stored as a wallet setting. The wallet has no transactions
+// before it so history and headers older than the birthday are not needed.
+
+const birthdayHeightSetting = "birthdayHeight"
+
+func (w *BtcElectrumWallet) putBirthday(cfg wallet.Cfg) error {
+	err := cfg.PutCreationDate(w.creationDate)
+	if err != nil {
+		return err
+	}
+	return putIntSetting(cfg, birthdayHeightSetting, int(w.birthdayHeight))
+}
+
+func (w *BtcElectrumWallet) getBirthday(cfg wallet.Cfg) error {
+	var err error
+	w.creationDate, err = cfg.GetCreationDate()
+	if err != nil {
+		return err
+	}
+	height, err := getIntSetting(cfg, birthdayHeightSetting, 0)
+	if err != nil {
+		return err
+	}
+	w.birthdayHeight = int64(height)
+	return nil
+}
+
+// BirthdayHeight returns the block height of the wallet birthday or zero if
+// not known
+func (w *BtcElectrumWallet) BirthdayHeight() int64 {
+	return w.birthdayHeight
+}
+
+// SetBirthdayHeight sets the block height of the wallet birthday, for example
+// when found from the headers for the birthday date
+func (w *BtcElectrumWallet) SetBirthdayHeight(height int64) error {
+	if height < 0 {
+		return errors.New("invalid birthday height")
+	}
+	w.birthdayHeight = height
+	return putIntSetting(w.txstore.Cfg(), birthdayHeightSetting, int(height))
+}
@@ -0,0 +1,51 @@
+package wltbtc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWalletBirthday(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	birthday := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	config := newMockConfig()
+	config.Birthday = birthday
+	config.BirthdayHeight = 833000
+	w, err := RecreateElectrumWallet(config, "abc", mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.CreationDate().Equal(birthday) || w.BirthdayHeight() != 833000 {
+		t.Fatalf("unexpected birthday %v at %d", w.CreationDate(), w.BirthdayHeight())
+	}
+
+	// unknown birthday
+	w, err = RecreateElectrumWallet(newMockConfig(), "abc", mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.CreationDate().IsZero() || w.BirthdayHeight() != 0 {
+		t.Fatalf("expected an unknown birthday - got %v at %d", w.CreationDate(), w.BirthdayHeight())
+	}
+
+	// new wallets are born now; the height is kept on load
+	config = newMockConfig()
+	w, err = NewBtcElectrumWallet(config, "abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(w.CreationDate()) > time.Minute {
+		t.Fatalf("expected a new wallet birthday - got %v", w.CreationDate())
+	}
+	err = w.SetBirthdayHeight(840000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadBtcElectrumWallet(config, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.BirthdayHeight() != 840000 {
+		t.Fatalf("expected birthday height 840000 - got %d", loaded.BirthdayHeight())
+	}
+}
@@ -0,0 +1,92 @@
+package wltbtc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Conflicts
+//
+// A tx that spends the same coins as a wallet tx conflicts with it. While
+// both are unconfirmed the first seen is kept and subscribers are told of
+// the conflict. When the other tx is mined, or the client finds the server
+// dropped the wallet tx for it, the wallet tx is replaced: it is marked dead,
+// the coins it spent are restored and the replacing txid is stored as a
+// wallet setting.
+
+const replacedBySetting = "replacedBy"
+
+func replacedBySettingKey(txid string) string {
+	return fmt.Sprintf("%s.%s", replacedBySetting, txid)
+}
+
+// notifyConflict publishes a conflict of wallet tx txid with the tx
+// conflictTxid at height. An unconfirmed conflict is published once.
+func (ts *TxStore) notifyConflict(txid, conflictTxid chainhash.Hash, height int64) {
+	if height == 0 {
+		ts.conflictsMutex.Lock()
+		key := [2]chainhash.Hash{txid, conflictTxid}
+		seen := ts.conflicts[key]
+		ts.conflicts[key] = true
+		ts.conflictsMutex.Unlock()
+		if seen {
+			return
+		}
+	}
+	fmt.Printf("transaction %s conflicts with %s at height %d\n", txid, conflictTxid, height)
+	ts.publish(&wallet.WalletEvent{
+		Type:         wallet.EventTxConflict,
+		Txid:         txid.String(),
+		ConflictTxid: conflictTxid.String(),
+		Height:       height,
+	})
+}
+
+// replaceTransaction marks the wallet tx txid dead, replaced by the conflicting
+// tx replacedBy at height
+func (ts *TxStore) replaceTransaction(txid, replacedBy chainhash.Hash, height int64) error {
+	err := ts.Cfg().PutSetting(replacedBySettingKey(txid.String()), []byte(replacedBy.String()))
+	if err != nil {
+		return err
+	}
+	ts.notifyConflict(txid, replacedBy, height)
+	return ts.markAsDead(txid)
+}
+
+// replacedBy returns the txid of the tx that replaced txid or "" if not known
+func (ts *TxStore) replacedBy(txid string) string {
+	b, err := ts.Cfg().GetSetting(replacedBySettingKey(txid))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// SetTxReplaced marks the unconfirmed wallet tx txid dead, replaced by the
+// conflicting tx replacedBy that the wallet does not know.
+func (w *BtcElectrumWallet) SetTxReplaced(txid, replacedBy string) error {
+	has, txn := w.HasTransaction(txid)
+	if !has {
+		return fmt.Errorf("%w: %s", wallet.ErrUnknownTx, txid)
+	}
+	if txn.Height != 0 {
+		return errors.New("only an unconfirmed transaction can be replaced")
+	}
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return err
+	}
+	by, err := chainhash.NewHashFromStr(replacedBy)
+	if err != nil {
+		return err
+	}
+	err = w.txstore.replaceTransaction(*hash, *by, 0)
+	if err != nil {
+		return err
+	}
+	w.notifyBalance()
+	return nil
+}
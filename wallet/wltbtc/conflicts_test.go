@@ -0,0 +1,169 @@
+package wltbtc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// conflictWallet returns a wallet with one confirmed coin and an unconfirmed
+// send of it
+func conflictWallet(t *testing.T) (*BtcElectrumWallet, *wire.MsgTx, *wire.MsgTx) {
+	t.Helper()
+	w := MockWallet("abc")
+	w.UpdateTip(100, true)
+	address, err := w.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fund := wire.NewMsgTx(wire.TxVersion)
+	fund.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	fund.AddTxOut(wire.NewTxOut(1000000, script))
+	if err := w.AddTransaction(fund, 90, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	payee, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, send, err := w.Spend("abc", 100000, payee, wallet.NORMAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddTransaction(send, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return w, fund, send
+}
+
+func TestMinedConflict(t *testing.T) {
+	w, fund, send := conflictWallet(t)
+	sub := w.SubscribeEvents(16)
+	sendTxid := send.TxHash().String()
+
+	// spends the same coin to someone else
+	conflict := wire.NewMsgTx(wire.TxVersion)
+	conflict.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: fund.TxHash()}, nil, nil))
+	conflict.AddTxOut(wire.NewTxOut(990000, send.TxOut[0].PkScript))
+	conflictTxid := conflict.TxHash().String()
+
+	// first seen is kept
+	for i := 0; i < 2; i++ {
+		if err := w.AddTransaction(conflict, 0, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := nextEvent(t, sub, wallet.EventTxConflict)
+	if e.Txid != sendTxid || e.ConflictTxid != conflictTxid || e.Height != 0 {
+		t.Fatalf("unexpected conflict event %+v", e)
+	}
+	select {
+	case e := <-sub.C:
+		t.Fatalf("expected one conflict event - got %+v", e)
+	default:
+	}
+	txn, err := w.GetTransaction(sendTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusPending {
+		t.Fatalf("expected pending - got %s", txn.Status)
+	}
+
+	// the conflict is mined
+	if err := w.AddTransaction(conflict, 101, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	e = nextEvent(t, sub, wallet.EventTxConflict)
+	if e.Txid != sendTxid || e.ConflictTxid != conflictTxid || e.Height != 101 {
+		t.Fatalf("unexpected conflict event %+v", e)
+	}
+	e = nextEvent(t, sub, wallet.EventTxDropped)
+	if e.Txid != sendTxid {
+		t.Fatalf("unexpected dropped event %+v", e)
+	}
+	txn, err = w.GetTransaction(sendTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusReplaced || txn.ReplacedBy != conflictTxid {
+		t.Fatalf("expected replaced by %s - got %s %s", conflictTxid, txn.Status, txn.ReplacedBy)
+	}
+	history, err := w.TxHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range history {
+		if h.Txid == sendTxid && (h.Status != wallet.StatusReplaced || h.ReplacedBy != conflictTxid) {
+			t.Fatalf("expected replaced history entry - got %+v", h)
+		}
+	}
+	// the coin is spent by the conflict and the send's change is gone
+	utxos, err := w.ListUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 0 {
+		t.Fatalf("expected no utxos - got %d", len(utxos))
+	}
+}
+
+func TestSetTxReplaced(t *testing.T) {
+	w, fund, send := conflictWallet(t)
+	sub := w.SubscribeEvents(16)
+	sendTxid := send.TxHash().String()
+	const by = "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a"
+
+	if err := w.SetTxReplaced(sendTxid, by); err != nil {
+		t.Fatal(err)
+	}
+	e := nextEvent(t, sub, wallet.EventTxConflict)
+	if e.Txid != sendTxid || e.ConflictTxid != by {
+		t.Fatalf("unexpected conflict event %+v", e)
+	}
+	nextEvent(t, sub, wallet.EventTxDropped)
+	nextEvent(t, sub, wallet.EventBalanceChanged)
+
+	// the coin the send spent is restored
+	utxos, err := w.ListUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 1 || utxos[0].Op.Hash != fund.TxHash() || utxos[0].Value != 1000000 || utxos[0].AtHeight != 90 {
+		t.Fatalf("expected the funding coin to be restored - got %+v", utxos)
+	}
+	confirmed, unconfirmed, _, err := w.Balance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed != 1000000 || unconfirmed != 0 {
+		t.Fatalf("expected balance 1000000/0 - got %d/%d", confirmed, unconfirmed)
+	}
+	txn, err := w.GetTransaction(sendTxid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusReplaced || txn.ReplacedBy != by || txn.Height != -1 {
+		t.Fatalf("expected replaced by %s - got %s %s", by, txn.Status, txn.ReplacedBy)
+	}
+
+	// only unconfirmed wallet txs
+	if err := w.SetTxReplaced(sendTxid, by); err == nil {
+		t.Fatal("expected an error replacing a dead tx")
+	}
+	err = w.SetTxReplaced(by, sendTxid)
+	if !errors.Is(err, wallet.ErrUnknownTx) {
+		t.Fatalf("expected %v - got %v", wallet.ErrUnknownTx, err)
+	}
+}
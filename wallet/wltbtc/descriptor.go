@@ -0,0 +1,255 @@
+package wltbtc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	hd "github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// The account BIP39 seeded wallet keys are derived from: m/44'/0'/0'. The
+// wallet pays to p2wpkh addresses so the descriptors are wpkh.
+var accountPath = []uint32{hd.HardenedKeyStart + 44, hd.HardenedKeyStart + 0, hd.HardenedKeyStart + 0}
+
+// ImportDescriptorWallet makes a new wallet from the BIP380 descriptors of an
+// account's receive and change chains. Public descriptors make a watch-only
+// wallet; private descriptors a signing wallet.
+func ImportDescriptorWallet(config *wallet.WalletConfig, pw, receive, change string) (*BtcElectrumWallet, error) {
+	if pw == "" {
+		return nil, ErrEmptyPassword
+	}
+	receiveDesc, changeDesc, err := parseWalletDescriptors(receive, change, config.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &BtcElectrumWallet{
+		repoPath:           config.DataDir,
+		params:             config.Params,
+		creationDate:       config.Birthday,
+		birthdayHeight:     config.BirthdayHeight,
+		feeProvider:        wallet.ConfigFeeProvider(config),
+		coinSelector:       config.CoinSelector,
+		maxAbsoluteFee:     config.MaxAbsoluteFee,
+		maxFeePercent:      config.MaxFeePercent,
+		eventConfirmations: config.EventConfirmations,
+		watchOnly:          !receiveDesc.IsPrivate(),
+		mutex:              new(sync.RWMutex),
+	}
+
+	accountPub, err := receiveDesc.Key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	sm := NewStorageManager(config.DB.Enc(), config.Params)
+	sm.store.Version = "0.1"
+	sm.store.Xpub = accountPub.String()
+	sm.store.ShaPw = chainhash.HashB([]byte(pw))
+	sm.store.Descriptors = []string{receiveDesc.String(), changeDesc.String()}
+	err = sm.Put(pw)
+	if err != nil {
+		return nil, err
+	}
+	w.storageManager = sm
+
+	w.keyManager, err = newDescriptorKeyManager(config.DB.Keys(), w.params, receiveDesc, changeDesc)
+	if err != nil {
+		return nil, err
+	}
+	err = w.setGapLimit(config)
+	if err != nil {
+		return nil, err
+	}
+
+	w.txstore, err = NewTxStore(w.params, config.DB, w.keyManager)
+	if err != nil {
+		return nil, err
+	}
+
+	w.subscriptionManager = NewSubscriptionManager(config.DB.Subscriptions(), w.params)
+
+	err = w.putBirthday(config.DB.Cfg())
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// parseWalletDescriptors parses and checks a receive and change descriptor
+// pair. Both must be wpkh, or both pkh, descriptors of the same account key
+// ranging over the /0/* and /1/* chains.
+func parseWalletDescriptors(receive, change string, params *chaincfg.Params) (*wallet.Descriptor, *wallet.Descriptor, error) {
+	receiveDesc, err := wallet.ParseDescriptor(receive)
+	if err != nil {
+		return nil, nil, err
+	}
+	changeDesc, err := wallet.ParseDescriptor(change)
+	if err != nil {
+		return nil, nil, err
+	}
+	isChain := func(d *wallet.Descriptor, chain uint32) bool {
+		return d.Wildcard && len(d.Path) == 1 && d.Path[0] == chain
+	}
+	switch {
+	case receiveDesc.Type != wallet.DescriptorWpkh && receiveDesc.Type != wallet.DescriptorPkh:
+		return nil, nil, fmt.Errorf("%w: wallet descriptors must be wpkh or pkh", wallet.ErrInvalidDescriptor)
+	case receiveDesc.Type != changeDesc.Type:
+		return nil, nil, fmt.Errorf("%w: receive and change script types differ", wallet.ErrInvalidDescriptor)
+	case !receiveDesc.Key.IsForNet(params):
+		return nil, nil, fmt.Errorf("%w: key is not for %s", wallet.ErrInvalidDescriptor, params.Name)
+	case receiveDesc.Key.String() != changeDesc.Key.String():
+		return nil, nil, fmt.Errorf("%w: receive and change keys differ", wallet.ErrInvalidDescriptor)
+	case !sameOrigin(receiveDesc.Origin, changeDesc.Origin):
+		return nil, nil, fmt.Errorf("%w: receive and change key origins differ", wallet.ErrInvalidDescriptor)
+	case !isChain(receiveDesc, 0):
+		return nil, nil, fmt.Errorf("%w: receive descriptor must end /0/*", wallet.ErrInvalidDescriptor)
+	case !isChain(changeDesc, 1):
+		return nil, nil, fmt.Errorf("%w: change descriptor must end /1/*", wallet.ErrInvalidDescriptor)
+	}
+	return receiveDesc, changeDesc, nil
+}
+
+func sameOrigin(a, b *wallet.KeyOrigin) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Fingerprint == b.Fingerprint && wallet.FormatPath(a.Path) == wallet.FormatPath(b.Path)
+}
+
+func newDescriptorKeyManager(db wallet.Keys, params *chaincfg.Params, receive, change *wallet.Descriptor) (*KeyManager, error) {
+	external, err := receive.Key.Derive(0)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := change.Key.Derive(1)
+	if err != nil {
+		return nil, err
+	}
+	addrType := wallet.AddrP2WPKH
+	if receive.Type == wallet.DescriptorPkh {
+		addrType = wallet.AddrP2PKH
+	}
+	return NewKeyManagerFromChainKeys(db, params, internal, external, addrType)
+}
+
+// ExportDescriptors returns the wallet's receive and change descriptors with
+// key origin and checksum. Private descriptors need the wallet password.
+func (w *BtcElectrumWallet) ExportDescriptors(pw string, private bool) (*wallet.WalletDescriptors, error) {
+	if private {
+		if w.watchOnly {
+			return nil, wallet.ErrWatchOnly
+		}
+		if ok := w.storageManager.IsValidPw(pw); !ok {
+			return nil, errors.New("invalid password")
+		}
+	}
+	receive, change, err := w.descriptors()
+	if err != nil {
+		return nil, err
+	}
+	if !private {
+		if receive, err = receive.Neutered(); err != nil {
+			return nil, err
+		}
+		if change, err = change.Neutered(); err != nil {
+			return nil, err
+		}
+	}
+	return &wallet.WalletDescriptors{
+		Receive: receive.String(),
+		Change:  change.String(),
+	}, nil
+}
+
+// WatchOnly returns true if the wallet was imported from public descriptors
+// and has no private keys.
+func (w *BtcElectrumWallet) WatchOnly() bool {
+	return w.watchOnly
+}
+
+// MasterFingerprint returns the hex BIP32 fingerprint of the wallet's master
+// key. It depends on the BIP39 passphrase so users can check that they typed
+// the passphrase correctly. Wallets imported from descriptors return the key
+// origin fingerprint.
+func (w *BtcElectrumWallet) MasterFingerprint() (string, error) {
+	receive, _, err := w.descriptors()
+	if err != nil {
+		return "", err
+	}
+	fingerprint := make([]byte, 4)
+	if receive.Origin != nil {
+		binary.BigEndian.PutUint32(fingerprint, receive.Origin.Fingerprint)
+		return hex.EncodeToString(fingerprint), nil
+	}
+	fp, err := wallet.KeyFingerprint(receive.Key)
+	if err != nil {
+		return "", err
+	}
+	binary.BigEndian.PutUint32(fingerprint, fp)
+	return hex.EncodeToString(fingerprint), nil
+}
+
+// descriptors returns the stored descriptors of an imported wallet or makes
+// them from the master key of a seeded wallet.
+func (w *BtcElectrumWallet) descriptors() (*wallet.Descriptor, *wallet.Descriptor, error) {
+	store := w.storageManager.store
+	if len(store.Descriptors) == 2 {
+		return parseWalletDescriptors(store.Descriptors[0], store.Descriptors[1], w.params)
+	}
+	master, err := hd.NewKeyFromString(store.Xprv)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer master.Zero()
+	fingerprint, err := wallet.KeyFingerprint(master)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Electrum seeds: standard wallets are pkh of m/0 & m/1 and segwit
+	// wallets wpkh of m/0'/0 & m/0'/1
+	descType := wallet.DescriptorWpkh
+	path := accountPath
+	switch store.SeedType {
+	case wallet.ElectrumSeedStandard:
+		descType = wallet.DescriptorPkh
+		path = nil
+	case wallet.ElectrumSeedSegwit:
+		path = []uint32{hd.HardenedKeyStart + 0}
+	}
+	// a separate copy of master as the account key as master is zeroed
+	account, err := hd.NewKeyFromString(store.Xprv)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, index := range path {
+		account, err = account.Derive(index)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	origin := &wallet.KeyOrigin{
+		Fingerprint: fingerprint,
+		Path:        path,
+	}
+	receive := &wallet.Descriptor{
+		Type:     descType,
+		Origin:   origin,
+		Key:      account,
+		Path:     []uint32{0},
+		Wildcard: true,
+	}
+	change := &wallet.Descriptor{
+		Type:     descType,
+		Origin:   origin,
+		Key:      account,
+		Path:     []uint32{1},
+		Wildcard: true,
+	}
+	return receive, change, nil
+}
@@ -0,0 +1,119 @@
+package wltbtc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestExportDescriptors(t *testing.T) {
+	w := MockWallet("abc")
+	descs, err := w.ExportDescriptors("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(descs.Receive, "wpkh([") || !strings.Contains(descs.Receive, "/44'/0'/0']tpub") ||
+		!strings.Contains(descs.Receive, "/0/*)#") || !strings.Contains(descs.Change, "/1/*)#") {
+		t.Fatalf("unexpected descriptors %v", descs)
+	}
+
+	// the descriptors describe the wallet's addresses
+	for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+		desc := descs.Receive
+		if purpose == wallet.INTERNAL {
+			desc = descs.Change
+		}
+		d, err := wallet.ParseDescriptor(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := w.GetAddress(&wallet.KeyPath{Purpose: purpose, Index: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := d.Address(3, &chaincfg.RegressionNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != want.String() {
+			t.Fatalf("expected %s - got %s", want, addr)
+		}
+	}
+
+	_, err = w.ExportDescriptors("bad", true)
+	if err == nil {
+		t.Fatal("expected an invalid password error")
+	}
+	private, err := w.ExportDescriptors("abc", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(private.Receive, "]tprv") {
+		t.Fatalf("expected a private descriptor - got %s", private.Receive)
+	}
+}
+
+func TestImportDescriptorWallet(t *testing.T) {
+	seeded := MockWallet("abc")
+	descs, err := seeded.ExportDescriptors("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := newMockDatastore()
+	config := &wallet.WalletConfig{
+		Params: &chaincfg.RegressionNetParams,
+		DB:     db,
+	}
+	_, err = ImportDescriptorWallet(config, "abc", descs.Change, descs.Receive)
+	if !errors.Is(err, wallet.ErrInvalidDescriptor) {
+		t.Fatalf("expected %v - got %v", wallet.ErrInvalidDescriptor, err)
+	}
+
+	w, err := ImportDescriptorWallet(config, "abc", descs.Receive, descs.Change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.WatchOnly() {
+		t.Fatal("expected a watch-only wallet")
+	}
+	if len(w.txstore.adrs) != len(seeded.txstore.adrs) {
+		t.Fatalf("expected %d addresses - got %d", len(seeded.txstore.adrs), len(w.txstore.adrs))
+	}
+	want, _ := seeded.GetUnusedAddress(wallet.RECEIVING)
+	got, _ := w.GetUnusedAddress(wallet.RECEIVING)
+	if want.String() != got.String() {
+		t.Fatalf("expected address %s - got %s", want, got)
+	}
+	exported, err := w.ExportDescriptors("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *exported != *descs {
+		t.Fatalf("expected %v - got %v", descs, exported)
+	}
+	_, err = w.ExportDescriptors("abc", true)
+	if !errors.Is(err, wallet.ErrWatchOnly) {
+		t.Fatalf("expected %v - got %v", wallet.ErrWatchOnly, err)
+	}
+	_, err = w.SpendTx("abc", &wallet.SpendInfo{})
+	if !errors.Is(err, wallet.ErrWatchOnly) {
+		t.Fatalf("expected %v - got %v", wallet.ErrWatchOnly, err)
+	}
+
+	// reload
+	w, err = LoadBtcElectrumWallet(config, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.WatchOnly() {
+		t.Fatal("expected a watch-only wallet after load")
+	}
+	got, _ = w.GetUnusedAddress(wallet.RECEIVING)
+	if want.String() != got.String() {
+		t.Fatalf("expected address %s after load - got %s", want, got)
+	}
+}
@@ -0,0 +1,160 @@
+package wltbtc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	hd "github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func newMockConfig() *wallet.WalletConfig {
+	return &wallet.WalletConfig{
+		Params: &chaincfg.RegressionNetParams,
+		DB:     newMockDatastore(),
+	}
+}
+
+func TestRecreateElectrumSeedWallet(t *testing.T) {
+	// segwit seed from the electrum test vectors
+	mnemonic := "wild father tree among universe such mobile favorite target dynamic credit identify"
+	config := newMockConfig()
+	w, err := RecreateElectrumSeedWallet(config, "abc", mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// segwit wallets use m/0'/0/i and m/0'/1/i
+	seed, _, err := wallet.ElectrumSeed(mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := hd.NewMaster(seed, config.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+		key := master
+		for _, index := range []uint32{hd.HardenedKeyStart + 0, uint32(purpose), 2} {
+			key, err = key.Derive(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), config.Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := w.GetAddress(&wallet.KeyPath{Purpose: purpose, Index: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != want.String() {
+			t.Fatalf("expected %s - got %s", want, addr)
+		}
+	}
+
+	descs, err := w.ExportDescriptors("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(descs.Receive, "wpkh([") || !strings.Contains(descs.Receive, "/0']tpub") {
+		t.Fatalf("unexpected descriptor %s", descs.Receive)
+	}
+
+	_, err = RecreateElectrumSeedWallet(newMockConfig(), "abc",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about")
+	if !errors.Is(err, wallet.ErrInvalidElectrumSeed) {
+		t.Fatalf("expected %v - got %v", wallet.ErrInvalidElectrumSeed, err)
+	}
+}
+
+func TestElectrumSeedWalletAddresses(t *testing.T) {
+	// segwit seed and first addresses from Electrum's wallet tests
+	mnemonic := "bitter grass shiver impose acquire brush forget axis eager alone wine silver"
+	config := newMockConfig()
+	config.Params = &chaincfg.MainNetParams
+	w, err := RecreateElectrumSeedWallet(config, "abc", mnemonic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		purpose wallet.KeyPurpose
+		want    string
+	}{
+		{wallet.EXTERNAL, "bc1q3g5tmkmlvxryhh843v4dz026avatc0zzr6h3af"},
+		{wallet.INTERNAL, "bc1qdy94n2q5qcp0kg7v9yzwe6wvfkhnvyzje7nx2p"},
+	}
+	for _, test := range tests {
+		addr, err := w.GetAddress(&wallet.KeyPath{Purpose: test.purpose, Index: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != test.want {
+			t.Fatalf("expected %s - got %s", test.want, addr)
+		}
+	}
+}
+
+func TestNewElectrumSeedWalletStandard(t *testing.T) {
+	config := newMockConfig()
+	w, mnemonic, err := NewElectrumSeedWallet(config, "abc", wallet.ElectrumSeedStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seedType, ok := wallet.ElectrumSeedVersion(mnemonic); !ok || seedType != wallet.ElectrumSeedStandard {
+		t.Fatalf("expected a standard seed - got %q", mnemonic)
+	}
+
+	addr, err := w.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := addr.(*btcutil.AddressPubKeyHash); !ok {
+		t.Fatalf("expected a p2pkh address - got %s", addr)
+	}
+
+	// the pkh descriptors describe the wallet's addresses
+	descs, err := w.ExportDescriptors("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := wallet.ParseDescriptor(descs.Change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Type != wallet.DescriptorPkh {
+		t.Fatalf("expected a pkh descriptor - got %s", descs.Change)
+	}
+	want, err := w.GetAddress(&wallet.KeyPath{Purpose: wallet.INTERNAL, Index: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Address(4, config.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Fatalf("expected %s - got %s", want, got)
+	}
+
+	// the seed type is kept on load
+	loaded, err := loadBtcElectrumWallet(config, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loaded.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.String() != addr.String() {
+		t.Fatalf("expected %s - got %s", addr, reloaded)
+	}
+}
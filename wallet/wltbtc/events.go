@@ -0,0 +1,173 @@
+package wltbtc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Wallet events
+//
+// The tx store publishes dropped txs as it marks them dead. The wallet
+// publishes new, mined and reorged txs as they are added, confirmations as
+// the tip moves, frozen coins and any balance change that results. Work is
+// only done for events when there are subscribers.
+
+// SubscribeEvents returns a subscription to wallet events with a channel
+// buffer of buffer events
+func (w *BtcElectrumWallet) SubscribeEvents(buffer int) *wallet.EventSubscription {
+	sub := w.txstore.events.Subscribe(buffer)
+	// balance changes are from now
+	w.balanceMutex.Lock()
+	if w.balance == nil {
+		confirmed, unconfirmed, locked, err := w.Balance()
+		if err == nil {
+			w.balance = &[3]int64{confirmed, unconfirmed, locked}
+		}
+	}
+	w.balanceMutex.Unlock()
+	return sub
+}
+
+// publish stamps e with the time now if it has none and publishes it
+func (ts *TxStore) publish(e *wallet.WalletEvent) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	ts.events.Publish(e)
+}
+
+// confirmationEvents returns the number of confirmations notified by tx
+// confirmed events
+func (w *BtcElectrumWallet) confirmationEvents() int64 {
+	if w.eventConfirmations <= 0 {
+		return wallet.DefaultEventConfirmations
+	}
+	return int64(w.eventConfirmations)
+}
+
+// notifyTransaction publishes the events for a tx just added to the tx store.
+// before is the wallet tx before it was added or nil if new.
+func (w *BtcElectrumWallet) notifyTransaction(txid string, before *wallet.Txn) {
+	if !w.txstore.events.HasSubscribers() {
+		return
+	}
+	has, after := w.HasTransaction(txid)
+	if !has {
+		// not a wallet tx
+		return
+	}
+	confirmations := w.confirmations(after.Height)
+	switch {
+	case before == nil:
+		w.txstore.publish(&wallet.WalletEvent{
+			Type:          wallet.EventTxReceived,
+			Txid:          txid,
+			Height:        after.Height,
+			Confirmations: confirmations,
+			Value:         after.Value,
+		})
+	case before.Height <= 0 && after.Height > 0:
+		// later confirmations are notified as the tip moves
+		if confirmations > 0 && confirmations <= w.confirmationEvents() {
+			w.txstore.publish(&wallet.WalletEvent{
+				Type:          wallet.EventTxConfirmed,
+				Txid:          txid,
+				Height:        after.Height,
+				Confirmations: confirmations,
+				Value:         after.Value,
+			})
+		}
+	}
+	w.notifyBalance()
+}
+
+// reorgTransaction moves a mined wallet tx to height, which a reorg changed
+func (w *BtcElectrumWallet) reorgTransaction(tx *wire.MsgTx, before *wallet.Txn, height int64) error {
+	err := w.txstore.reorgTransaction(tx, height)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("reorg moved transaction %s from height %d to %d\n", before.Txid, before.Height, height)
+	w.txstore.publish(&wallet.WalletEvent{
+		Type:          wallet.EventTxReorged,
+		Txid:          before.Txid,
+		Height:        height,
+		PrevHeight:    before.Height,
+		Confirmations: w.confirmations(height),
+		Value:         before.Value,
+	})
+	w.notifyBalance()
+	return nil
+}
+
+// notifyConfirmations publishes confirmed events for the wallet txs that got
+// confirmations when the tip moved from oldTip to newTip. When blocks arrive
+// together some confirmation counts are skipped.
+func (w *BtcElectrumWallet) notifyConfirmations(oldTip, newTip int64) {
+	if !w.txstore.events.HasSubscribers() {
+		return
+	}
+	txns, err := w.txstore.Txns().GetAll(false)
+	if err != nil {
+		return
+	}
+	max := w.confirmationEvents()
+	for _, txn := range txns {
+		if txn.Height <= 0 {
+			continue
+		}
+		prev := oldTip - txn.Height + 1
+		confirmations := newTip - txn.Height + 1
+		if confirmations < 1 || confirmations <= prev || prev >= max {
+			continue
+		}
+		w.txstore.publish(&wallet.WalletEvent{
+			Type:          wallet.EventTxConfirmed,
+			Txid:          txn.Txid,
+			Height:        txn.Height,
+			Confirmations: confirmations,
+			Value:         txn.Value,
+		})
+	}
+}
+
+// notifyUtxo publishes a utxo event and any balance change
+func (w *BtcElectrumWallet) notifyUtxo(eventType wallet.WalletEventType, op wire.OutPoint) {
+	if !w.txstore.events.HasSubscribers() {
+		return
+	}
+	w.txstore.publish(&wallet.WalletEvent{
+		Type:     eventType,
+		OutPoint: &op,
+	})
+	w.notifyBalance()
+}
+
+// notifyBalance publishes a balance event if the balance has changed since
+// last notified
+func (w *BtcElectrumWallet) notifyBalance() {
+	w.balanceMutex.Lock()
+	defer w.balanceMutex.Unlock()
+	if !w.txstore.events.HasSubscribers() {
+		w.balance = nil
+		return
+	}
+	confirmed, unconfirmed, locked, err := w.Balance()
+	if err != nil {
+		return
+	}
+	balance := [3]int64{confirmed, unconfirmed, locked}
+	if w.balance != nil && *w.balance == balance {
+		return
+	}
+	w.balance = &balance
+	w.txstore.publish(&wallet.WalletEvent{
+		Type:        wallet.EventBalanceChanged,
+		Confirmed:   confirmed,
+		Unconfirmed: unconfirmed,
+		Locked:      locked,
+	})
+}
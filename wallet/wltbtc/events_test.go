@@ -0,0 +1,188 @@
+package wltbtc
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// nextEvent returns the next event of type eventType, skipping others
+func nextEvent(t *testing.T, sub *wallet.EventSubscription, eventType wallet.WalletEventType) *wallet.WalletEvent {
+	t.Helper()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				t.Fatalf("subscription closed waiting for %s", eventType)
+			}
+			if e.Time.IsZero() {
+				t.Fatalf("%s event has no time", e.Type)
+			}
+			if e.Type == eventType {
+				return e
+			}
+		default:
+			t.Fatalf("no %s event", eventType)
+		}
+	}
+}
+
+func TestWalletEvents(t *testing.T) {
+	w := MockWallet("abc")
+	w.UpdateTip(100, true)
+	sub := w.SubscribeEvents(32)
+
+	raw := makeTxList()[0]
+	b, err := hex.DecodeString(raw.tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newWireTx(b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// seen in the mempool
+	if err := w.AddTransaction(tx, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	e := nextEvent(t, sub, wallet.EventTxReceived)
+	if e.Txid != raw.txid || e.Height != 0 || e.Value <= 0 {
+		t.Fatalf("unexpected received event %+v", e)
+	}
+	e = nextEvent(t, sub, wallet.EventBalanceChanged)
+	if e.Unconfirmed <= 0 {
+		t.Fatalf("unexpected balance event %+v", e)
+	}
+
+	// mined in the tip block
+	if err := w.AddTransaction(tx, 100, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	e = nextEvent(t, sub, wallet.EventTxConfirmed)
+	if e.Txid != raw.txid || e.Height != 100 || e.Confirmations != 1 {
+		t.Fatalf("unexpected confirmed event %+v", e)
+	}
+	// already known
+	if err := w.AddTransaction(tx, 100, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// more confirmations
+	w.UpdateTip(102, true)
+	e = nextEvent(t, sub, wallet.EventTxConfirmed)
+	if e.Confirmations != 3 {
+		t.Fatalf("expected 3 confirmations - got %d", e.Confirmations)
+	}
+	// blocks arriving together skip to the last count
+	w.UpdateTip(110, true)
+	e = nextEvent(t, sub, wallet.EventTxConfirmed)
+	if e.Confirmations != 11 {
+		t.Fatalf("expected 11 confirmations - got %d", e.Confirmations)
+	}
+	w.UpdateTip(111, true)
+	select {
+	case e := <-sub.C:
+		t.Fatalf("unexpected event past the notified confirmations %+v", e)
+	default:
+	}
+
+	// reorged into the next block
+	if err := w.AddTransaction(tx, 101, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	e = nextEvent(t, sub, wallet.EventTxReorged)
+	if e.Height != 101 || e.PrevHeight != 100 || e.Confirmations != 11 {
+		t.Fatalf("unexpected reorg event %+v", e)
+	}
+	txn, err := w.GetTransaction(raw.txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Height != 101 {
+		t.Fatalf("expected tx height 101 - got %d", txn.Height)
+	}
+
+	// frozen
+	utxos, err := w.ListUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 1 {
+		t.Fatalf("expected 1 utxo - got %d", len(utxos))
+	}
+	if err := w.FreezeUTXO(&utxos[0].Op); err != nil {
+		t.Fatal(err)
+	}
+	e = nextEvent(t, sub, wallet.EventUtxoFrozen)
+	if *e.OutPoint != utxos[0].Op {
+		t.Fatalf("expected frozen %s - got %s", utxos[0].Op, e.OutPoint)
+	}
+
+	sub.Unsubscribe()
+	for range sub.C {
+	}
+	if w.txstore.events.HasSubscribers() {
+		t.Fatal("expected no subscribers")
+	}
+}
+
+func TestTxStuckEvent(t *testing.T) {
+	w := MockWallet("abc")
+	w.UpdateTip(100, true)
+	raw := makeTxList()[0]
+	b, err := hex.DecodeString(raw.tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newWireTx(b, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddTransaction(tx, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	sub := w.SubscribeEvents(8)
+
+	if err := w.SetTxStuck(raw.txid, true); err != nil {
+		t.Fatal(err)
+	}
+	e := nextEvent(t, sub, wallet.EventTxStuck)
+	if e.Txid != raw.txid {
+		t.Fatalf("unexpected stuck event %+v", e)
+	}
+	// notified once
+	if err := w.SetTxStuck(raw.txid, true); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-sub.C:
+		t.Fatalf("unexpected event %+v", e)
+	default:
+	}
+	history, err := w.TxHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history[0].Status != wallet.StatusStuck {
+		t.Fatalf("expected stuck - got %s", history[0].Status)
+	}
+	if err := w.SetTxStuck(raw.txid, false); err != nil {
+		t.Fatal(err)
+	}
+	txn, err := w.GetTransaction(raw.txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.Status != wallet.StatusPending {
+		t.Fatalf("expected pending - got %s", txn.Status)
+	}
+
+	err = w.SetTxStuck("00", true)
+	if !errors.Is(err, wallet.ErrUnknownTx) {
+		t.Fatalf("expected %v - got %v", wallet.ErrUnknownTx, err)
+	}
+}
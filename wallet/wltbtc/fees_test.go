@@ -32,6 +32,11 @@ func TestFeeProvider_GetFeePerByte(t *testing.T) {
 	// fp := wallet.NewFeeProvider(2000, 360, 320, 280, "https://mempool.space/testnet/api/v1/fees/recommended", nil)
 	fp.HttpClient = new(mockHttpClient)
 
+	// Test last fee before any fetch is the default
+	if fp.LastFeePerByte(wallet.ECONOMIC) != 280 {
+		t.Error("Returned incorrect last fee per byte")
+	}
+
 	// Test fetch from API
 	if fp.GetFeePerByte(wallet.PRIORITY) != 450 {
 		t.Error("Returned incorrect fee per byte")
@@ -45,6 +50,9 @@ func TestFeeProvider_GetFeePerByte(t *testing.T) {
 	if fp.GetFeePerByte(wallet.FEE_BUMP) != 450 {
 		t.Error("Returned incorrect fee per byte")
 	}
+	if fp.LastFeePerByte(wallet.ECONOMIC) != 390 {
+		t.Error("Returned incorrect last fee per byte")
+	}
 
 	// Test return over max
 	fp.MaxFee = 100
@@ -76,3 +84,27 @@ func TestFeeProvider_GetFeePerByte(t *testing.T) {
 		t.Error("Returned incorrect fee per byte")
 	}
 }
+
+type mockZeroFeeHttpClient struct{}
+
+func (m *mockZeroFeeHttpClient) Get(url string) (*http.Response, error) {
+	data := `{"priority":450,"normal":0,"economic":0}`
+	cb := &ClosingBuffer{bytes.NewBufferString(data)}
+	resp := &http.Response{
+		Body: cb,
+	}
+	return resp, nil
+}
+
+func TestFeeProvider_GetFeePerByteDefaults(t *testing.T) {
+	fp := wallet.NewFeeProvider(2000, 360, 320, 280, "https://mempool.space/api/v1/fees/recommended", nil)
+	fp.HttpClient = new(mockZeroFeeHttpClient)
+
+	// Zero API fees fall back to the default for the same level
+	if fp.GetFeePerByte(wallet.NORMAL) != 320 {
+		t.Error("Returned incorrect fee per byte")
+	}
+	if fp.GetFeePerByte(wallet.ECONOMIC) != 280 {
+		t.Error("Returned incorrect fee per byte")
+	}
+}
@@ -0,0 +1,93 @@
+package wltbtc
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Wallet settings stored in the config database
+const (
+	gapLimitSetting = "gapLimit"
+	issuedSetting   = "issued"
+)
+
+func issuedSettingKey(purpose wallet.KeyPurpose) string {
+	return fmt.Sprintf("%s.%d", issuedSetting, purpose)
+}
+
+// getIntSetting returns a stored integer setting or def if not stored
+func getIntSetting(cfg wallet.Cfg, key string, def int) (int, error) {
+	b, err := cfg.GetSetting(key)
+	if err != nil {
+		return def, err
+	}
+	if b == nil {
+		return def, nil
+	}
+	return strconv.Atoi(string(b))
+}
+
+func putIntSetting(cfg wallet.Cfg, key string, value int) error {
+	return cfg.PutSetting(key, []byte(strconv.Itoa(value)))
+}
+
+// setGapLimit sets the key manager's gap limit & policy from config and the
+// issued key indexes from the database. A config with no gap limit uses the
+// stored gap limit or the default. The gap limit in use is stored.
+func (w *BtcElectrumWallet) setGapLimit(config *wallet.WalletConfig) error {
+	cfg := config.DB.Cfg()
+	gapLimit := config.GapLimit
+	if gapLimit <= 0 {
+		var err error
+		gapLimit, err = getIntSetting(cfg, gapLimitSetting, wallet.DefaultGapLimit)
+		if err != nil {
+			return err
+		}
+	}
+	err := putIntSetting(cfg, gapLimitSetting, gapLimit)
+	if err != nil {
+		return err
+	}
+	issued := make(map[wallet.KeyPurpose]int)
+	for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+		issued[purpose], err = getIntSetting(cfg, issuedSettingKey(purpose), -1)
+		if err != nil {
+			return err
+		}
+	}
+	return w.keyManager.SetGapLimit(gapLimit, config.GapPolicy, issued)
+}
+
+// GapLimit returns the number of unused addresses kept past the last used
+// address of each chain
+func (w *BtcElectrumWallet) GapLimit() int {
+	return w.keyManager.GapLimit()
+}
+
+// NewAddress issues an address for 'purpose' that has not been issued before.
+// With GapPolicyError ErrGapLimit is returned when every address within the
+// gap limit has been issued and none has been used. It returns true if the
+// address is past the gap limit, as issued by GapPolicyExtend.
+func (w *BtcElectrumWallet) NewAddress(purpose wallet.KeyPurpose) (btcutil.Address, bool, error) {
+	key, pastGap, err := w.keyManager.IssueKey(purpose)
+	if err != nil {
+		return nil, false, err
+	}
+	address, err := w.keyManager.Address(key)
+	key.Zero()
+	if err != nil {
+		return nil, false, err
+	}
+	err = putIntSetting(w.txstore.Cfg(), issuedSettingKey(purpose), w.keyManager.Issued(purpose))
+	if err != nil {
+		return nil, false, err
+	}
+	if pastGap {
+		// watch the new address
+		w.txstore.PopulateAdrs()
+	}
+	return address, pastGap, nil
+}
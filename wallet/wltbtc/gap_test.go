@@ -0,0 +1,133 @@
+package wltbtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func newGapWallet(t *testing.T, gapLimit int, policy wallet.GapPolicy) (*BtcElectrumWallet, *wallet.WalletConfig) {
+	config := newMockConfig()
+	config.GapLimit = gapLimit
+	config.GapPolicy = policy
+	w, err := NewBtcElectrumWallet(config, "abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, config
+}
+
+func issueAddresses(t *testing.T, w *BtcElectrumWallet, n int) map[string]bool {
+	issued := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		addr, pastGap, err := w.NewAddress(wallet.RECEIVING)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pastGap {
+			t.Fatalf("address %s within the gap limit flagged past it", addr)
+		}
+		if issued[addr.String()] {
+			t.Fatalf("address %s issued twice", addr)
+		}
+		issued[addr.String()] = true
+	}
+	return issued
+}
+
+func TestNewAddressGapPolicyError(t *testing.T) {
+	w, _ := newGapWallet(t, 5, wallet.GapPolicyError)
+	if w.GapLimit() != 5 {
+		t.Fatalf("expected gap limit 5 - got %d", w.GapLimit())
+	}
+	issued := issueAddresses(t, w, 5)
+	_, _, err := w.NewAddress(wallet.RECEIVING)
+	if !errors.Is(err, wallet.ErrGapLimit) {
+		t.Fatalf("expected %v - got %v", wallet.ErrGapLimit, err)
+	}
+
+	// a payment to an issued address moves the window on
+	for a := range issued {
+		addr, err := w.DecodeAddress(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.MarkAddressUsed(addr); err != nil {
+			t.Fatal(err)
+		}
+		break
+	}
+	addr, _, err := w.NewAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued[addr.String()] {
+		t.Fatalf("address %s issued twice", addr)
+	}
+	if !w.HasAddress(addr) {
+		t.Fatalf("expected wallet to watch %s", addr)
+	}
+}
+
+func TestNewAddressGapPolicyReuse(t *testing.T) {
+	w, _ := newGapWallet(t, 3, wallet.GapPolicyReuse)
+	issued := issueAddresses(t, w, 3)
+	addr, _, err := w.NewAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !issued[addr.String()] {
+		t.Fatalf("expected an issued address to be reused - got %s", addr)
+	}
+}
+
+func TestNewAddressGapPolicyExtend(t *testing.T) {
+	w, _ := newGapWallet(t, 3, wallet.GapPolicyExtend)
+	issued := issueAddresses(t, w, 3)
+	for i := 0; i < 3; i++ {
+		addr, pastGap, err := w.NewAddress(wallet.RECEIVING)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pastGap {
+			t.Fatalf("expected address %s flagged past the gap limit", addr)
+		}
+		if issued[addr.String()] {
+			t.Fatalf("address %s issued twice", addr)
+		}
+		issued[addr.String()] = true
+		if !w.HasAddress(addr) {
+			t.Fatalf("expected wallet to watch %s", addr)
+		}
+	}
+}
+
+func TestGapLimitStored(t *testing.T) {
+	w, config := newGapWallet(t, 20, wallet.GapPolicyError)
+	unused, err := w.keyManager.datastore.GetUnused(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unused) != 20 {
+		t.Fatalf("expected 20 unused keys - got %d", len(unused))
+	}
+	issued := issueAddresses(t, w, 2)
+
+	// the stored gap limit & issued addresses are used on load
+	config.GapLimit = 0
+	loaded, err := loadBtcElectrumWallet(config, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.GapLimit() != 20 {
+		t.Fatalf("expected gap limit 20 - got %d", loaded.GapLimit())
+	}
+	addr, _, err := loaded.NewAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued[addr.String()] {
+		t.Fatalf("address %s issued twice", addr)
+	}
+}
@@ -0,0 +1,273 @@
+package wltbtc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// TxHistory returns the wallet transaction history newest first.
+func (w *BtcElectrumWallet) TxHistory() ([]wallet.TxHistoryEntry, error) {
+	txns, err := w.txstore.Txns().GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	stxos, err := w.txstore.Stxos().GetAll()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := w.txstore.Utxos().GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ourScripts := w.walletScripts()
+
+	// wallet coins by outpoint to value the inputs of wallet txs
+	coins := make(map[wire.OutPoint]int64)
+	for _, s := range stxos {
+		coins[s.Utxo.Op] = s.Utxo.Value
+	}
+	for _, u := range utxos {
+		coins[u.Op] = u.Value
+	}
+
+	// wallet txs and the outpoints they spend to find replacements
+	msgTxs := make(map[string]*wire.MsgTx)
+	spentBy := make(map[wire.OutPoint][]string)
+	for _, txn := range txns {
+		msgTx, err := newWireTx(txn.Bytes, false)
+		if err != nil {
+			continue
+		}
+		msgTxs[txn.Txid] = msgTx
+		if txn.Height < 0 {
+			continue
+		}
+		for _, in := range msgTx.TxIn {
+			spentBy[in.PreviousOutPoint] = append(spentBy[in.PreviousOutPoint], txn.Txid)
+		}
+	}
+
+	var history []wallet.TxHistoryEntry
+	for _, txn := range txns {
+		msgTx, ok := msgTxs[txn.Txid]
+		if !ok {
+			continue
+		}
+		entry := wallet.TxHistoryEntry{
+			Txid:      txn.Txid,
+			Height:    txn.Height,
+			Timestamp: txn.Timestamp,
+		}
+		if label, err := w.txstore.Labels().Get(wallet.LabelTx, txn.Txid); err == nil {
+			entry.Label = label.Label
+		}
+
+		var ourIn, ourOut, otherOut int64
+		ourInputs := 0
+		for _, in := range msgTx.TxIn {
+			if value, ok := coins[in.PreviousOutPoint]; ok {
+				ourIn += value
+				ourInputs++
+			}
+		}
+		var otherAddrs []string
+		for _, out := range msgTx.TxOut {
+			if ourScripts[string(out.PkScript)] {
+				ourOut += out.Value
+				continue
+			}
+			otherOut += out.Value
+			if addr := pkScriptAddress(out.PkScript, w.params); addr != "" {
+				otherAddrs = append(otherAddrs, addr)
+			}
+		}
+
+		if ourInputs == len(msgTx.TxIn) {
+			entry.Fee = ourIn - ourOut - otherOut
+		}
+		entry.Amount = ourOut - ourIn
+		switch {
+		case ourIn == 0:
+			entry.Direction = wallet.TxReceive
+			for _, in := range msgTx.TxIn {
+				if addr := inputAddress(in, w.params); addr != "" {
+					entry.Counterparties = append(entry.Counterparties, addr)
+				}
+			}
+		case otherOut == 0:
+			entry.Direction = wallet.TxSelfTransfer
+		default:
+			entry.Direction = wallet.TxSend
+			entry.Counterparties = otherAddrs
+		}
+
+		w.setTxnStatus(&txn)
+		if txn.Status == wallet.StatusDead {
+			// replaced by a wallet tx spending the same coins
+			if replacedBy := spentByOther(msgTx, txn.Txid, spentBy); replacedBy != "" {
+				txn.Status = wallet.StatusReplaced
+				txn.ReplacedBy = replacedBy
+			}
+		}
+		entry.Status = txn.Status
+		entry.ReplacedBy = txn.ReplacedBy
+		entry.Confirmations = txn.Confirmations
+		history = append(history, entry)
+	}
+	wallet.SortHistory(history)
+	return history, nil
+}
+
+// spentByOther returns the first tx in spentBy other than txid spending an
+// input of msgTx or "" if there is none
+func spentByOther(msgTx *wire.MsgTx, txid string, spentBy map[wire.OutPoint][]string) string {
+	for _, in := range msgTx.TxIn {
+		for _, other := range spentBy[in.PreviousOutPoint] {
+			if other != txid {
+				return other
+			}
+		}
+	}
+	return ""
+}
+
+// setTxnStatus fills in the calculated confirmations and status of a txn
+func (w *BtcElectrumWallet) setTxnStatus(txn *wallet.Txn) {
+	txn.Confirmations = w.confirmations(txn.Height)
+	if txn.Height < 0 {
+		txn.ReplacedBy = w.txstore.replacedBy(txn.Txid)
+	}
+	switch {
+	case txn.ReplacedBy != "":
+		txn.Status = wallet.StatusReplaced
+	case txn.Height < 0:
+		txn.Status = wallet.StatusDead
+	case txn.Height == 0 && w.isStuck(txn.Txid):
+		txn.Status = wallet.StatusStuck
+	case txn.Height == 0:
+		txn.Status = wallet.StatusPending
+	default:
+		txn.Status = wallet.StatusConfirmed
+	}
+}
+
+// SetTxStuck marks an unconfirmed wallet tx as stuck, or not stuck. A mined
+// tx is never stuck. Subscribers are notified of a newly stuck tx.
+func (w *BtcElectrumWallet) SetTxStuck(txid string, stuck bool) error {
+	has, txn := w.HasTransaction(txid)
+	if !has {
+		return fmt.Errorf("%w: %s", wallet.ErrUnknownTx, txid)
+	}
+	stuck = stuck && txn.Height == 0
+	w.stuckMutex.Lock()
+	was := w.stuck[txid]
+	if stuck {
+		if w.stuck == nil {
+			w.stuck = make(map[string]bool)
+		}
+		w.stuck[txid] = true
+	} else {
+		delete(w.stuck, txid)
+	}
+	w.stuckMutex.Unlock()
+	if stuck && !was {
+		w.txstore.publish(&wallet.WalletEvent{
+			Type:  wallet.EventTxStuck,
+			Txid:  txid,
+			Value: txn.Value,
+		})
+	}
+	return nil
+}
+
+// isStuck returns true if the tx was marked stuck
+func (w *BtcElectrumWallet) isStuck(txid string) bool {
+	w.stuckMutex.Lock()
+	defer w.stuckMutex.Unlock()
+	return w.stuck[txid]
+}
+
+// confirmations returns the number of confirmations at the current tip for a
+// tx mined at height.
+func (w *BtcElectrumWallet) confirmations(height int64) int64 {
+	tip := w.blockchainTip.Load()
+	if height <= 0 || tip < height {
+		return 0
+	}
+	return tip - height + 1
+}
+
+// walletScripts returns the set of output scripts paying to wallet addresses
+// keyed on the string of the script bytes.
+func (w *BtcElectrumWallet) walletScripts() map[string]bool {
+	ts := w.txstore
+	ts.addrMutex.Lock()
+	defer ts.addrMutex.Unlock()
+	scripts := make(map[string]bool, len(ts.adrs))
+	for _, adr := range ts.adrs {
+		script, err := txscript.PayToAddrScript(adr)
+		if err != nil {
+			continue
+		}
+		scripts[string(script)] = true
+	}
+	return scripts
+}
+
+// pkScriptAddress returns the encoded address for a standard output script or
+// an empty string.
+func pkScriptAddress(pkScript []byte, params *chaincfg.Params) string {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, params)
+	if err != nil || len(addrs) != 1 {
+		return ""
+	}
+	return addrs[0].EncodeAddress()
+}
+
+// inputAddress works out the address an input spends from its signature script
+// and witness. It handles P2WPKH, P2SH-P2WPKH and P2PKH inputs and returns an
+// empty string for anything else.
+func inputAddress(in *wire.TxIn, params *chaincfg.Params) string {
+	var pushes [][]byte
+	if len(in.SignatureScript) > 0 {
+		var err error
+		pushes, err = txscript.PushedData(in.SignatureScript)
+		if err != nil {
+			return ""
+		}
+	}
+	switch {
+	case len(in.Witness) == 2 && len(in.Witness[1]) == 33:
+		pkHash := btcutil.Hash160(in.Witness[1])
+		if len(pushes) == 1 {
+			// nested in p2sh
+			addr, err := btcutil.NewAddressScriptHash(pushes[0], params)
+			if err != nil {
+				return ""
+			}
+			return addr.EncodeAddress()
+		}
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(pkHash, params)
+		if err != nil {
+			return ""
+		}
+		return addr.EncodeAddress()
+	case len(in.Witness) == 0 && len(pushes) == 2:
+		pubKey := pushes[1]
+		if len(pubKey) != 33 && len(pubKey) != 65 {
+			return ""
+		}
+		addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), params)
+		if err != nil {
+			return ""
+		}
+		return addr.EncodeAddress()
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package wltbtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestTxHistory(t *testing.T) {
+	w := MockWallet("abc")
+	err := fundWallet(w, 100, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.UpdateTip(105, true)
+
+	history, err := w.TxHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(makeTxList()) {
+		t.Fatalf("expected %d txs - got %d", len(makeTxList()), len(history))
+	}
+	var received int64
+	for _, h := range history {
+		if h.Direction != wallet.TxReceive {
+			t.Fatalf("expected receive - got %s", h.Direction)
+		}
+		if h.Status != wallet.StatusConfirmed || h.Confirmations != 6 {
+			t.Fatalf("expected confirmed with 6 confirmations - got %s %d", h.Status, h.Confirmations)
+		}
+		received += h.Amount
+	}
+	if received != 588000000 {
+		t.Fatalf("expected to receive 588000000 - got %d", received)
+	}
+
+	// spend some
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, tx, err := w.Spend("abc", 10000000, address, wallet.NORMAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.AddTransaction(tx, 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err = w.TxHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := history[0]
+	if send.Txid != tx.TxHash().String() || send.Direction != wallet.TxSend || send.Status != wallet.StatusPending {
+		t.Fatalf("expected the pending send first - got %+v", send)
+	}
+	if send.Fee <= 0 || send.Amount != -(10000000+send.Fee) {
+		t.Fatalf("bad send amount %d or fee %d", send.Amount, send.Fee)
+	}
+	if len(send.Counterparties) != 1 || send.Counterparties[0] != address.EncodeAddress() {
+		t.Fatalf("expected counterparty %s - got %v", address, send.Counterparties)
+	}
+
+	// paging and height filtering
+	filtered := wallet.FilterHistory(history, &wallet.HistoryFilter{Limit: 1})
+	if len(filtered) != 1 || filtered[0].Txid != send.Txid {
+		t.Fatal("expected one tx")
+	}
+	filtered = wallet.FilterHistory(history, &wallet.HistoryFilter{FromHeight: 100, Offset: 1})
+	if len(filtered) != len(history)-2 {
+		t.Fatalf("expected %d txs - got %d", len(history)-2, len(filtered))
+	}
+}
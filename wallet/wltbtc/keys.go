@@ -2,14 +2,20 @@ package wltbtc
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
 	hd "github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/dev-warrior777/go-electrum-client/client"
 	"github.com/dev-warrior777/go-electrum-client/wallet"
 )
 
-// Lookahead window size from client constants
+// Default lookahead window size from client constants
 const GAP_LIMIT = client.GAP_LIMIT
 
 type KeyManager struct {
@@ -18,6 +24,21 @@ type KeyManager struct {
 
 	internalKey *hd.ExtendedKey
 	externalKey *hd.ExtendedKey
+
+	// kind of address made from keys
+	addrType wallet.AddressType
+
+	// accounts added by account discovery
+	accounts      []*accountKeys
+	accountsMutex sync.RWMutex
+
+	// number of unused keys kept past the last used key of each chain
+	gapLimit int
+	// what IssueKey does when the window has been issued
+	gapPolicy wallet.GapPolicy
+	// highest key index issued by IssueKey on each chain
+	issued     map[wallet.KeyPurpose]int
+	issueMutex sync.Mutex
 }
 
 func NewKeyManager(db wallet.Keys, params *chaincfg.Params, masterPrivKey *hd.ExtendedKey) (*KeyManager, error) {
@@ -26,11 +47,36 @@ func NewKeyManager(db wallet.Keys, params *chaincfg.Params, masterPrivKey *hd.Ex
 	if err != nil {
 		return nil, err
 	}
+	return NewKeyManagerFromChainKeys(db, params, internal, external, wallet.AddrP2WPKH)
+}
+
+// NewElectrumSeedKeyManager makes a key manager for a wallet made from an
+// Electrum seed of seedType.
+func NewElectrumSeedKeyManager(db wallet.Keys, params *chaincfg.Params, masterPrivKey *hd.ExtendedKey, seedType wallet.ElectrumSeedType) (*KeyManager, error) {
+	internal, external, err := ElectrumDerivation(masterPrivKey, seedType)
+	masterPrivKey.Zero()
+	if err != nil {
+		return nil, err
+	}
+	addrType := wallet.AddrP2WPKH
+	if seedType == wallet.ElectrumSeedStandard {
+		addrType = wallet.AddrP2PKH
+	}
+	return NewKeyManagerFromChainKeys(db, params, internal, external, addrType)
+}
+
+// NewKeyManagerFromChainKeys makes a key manager from the extended keys of the
+// internal (change) and external (receive) chains making addrType addresses.
+// Public keys give a watch-only key manager.
+func NewKeyManagerFromChainKeys(db wallet.Keys, params *chaincfg.Params, internal, external *hd.ExtendedKey, addrType wallet.AddressType) (*KeyManager, error) {
 	km := &KeyManager{
 		datastore:   db,
 		params:      params,
 		internalKey: internal,
 		externalKey: external,
+		addrType:    addrType,
+		gapLimit:    GAP_LIMIT,
+		issued:      map[wallet.KeyPurpose]int{wallet.EXTERNAL: -1, wallet.INTERNAL: -1},
 	}
 	if err := km.lookahead(); err != nil {
 		return nil, err
@@ -68,22 +114,206 @@ func Bip44Derivation(masterPrivKey *hd.ExtendedKey) (internal, external *hd.Exte
 	return internal, external, nil
 }
 
-// GetUnusedKey gets the first unused key for 'purpose'. CAUTION: There may not
-// be any keys within the gap limit. In this case a used key can be utilized or
-// user can wait until the gap is updated with new key(s). This happens when a
-// transaction newly gets client.AGEDTX confirmations.
+// ElectrumDerivation returns the chain keys of a wallet made from an Electrum
+// seed. Standard seeds use m/1 & m/0 and segwit seeds m/0'/1 & m/0'/0.
+func ElectrumDerivation(masterPrivKey *hd.ExtendedKey, seedType wallet.ElectrumSeedType) (internal, external *hd.ExtendedKey, err error) {
+	account := masterPrivKey
+	switch seedType {
+	case wallet.ElectrumSeedStandard:
+	case wallet.ElectrumSeedSegwit:
+		account, err = masterPrivKey.Derive(hd.HardenedKeyStart + 0)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, wallet.ErrInvalidElectrumSeed
+	}
+	external, err = account.Derive(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	internal, err = account.Derive(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return internal, external, nil
+}
+
+// AddressType returns the kind of address made from keys
+func (km *KeyManager) AddressType() wallet.AddressType {
+	return km.addrType
+}
+
+// Address returns the wallet address for a key
+func (km *KeyManager) Address(key *hd.ExtendedKey) (btcutil.Address, error) {
+	return addressForKey(key, km.addrType, km.params)
+}
+
+// addressForKey returns the addrType address paying to key
+func addressForKey(key *hd.ExtendedKey, addrType wallet.AddressType, params *chaincfg.Params) (btcutil.Address, error) {
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	pkHash := btcutil.Hash160(pubKey.SerializeCompressed())
+	switch addrType {
+	case wallet.AddrP2WPKH:
+		return btcutil.NewAddressWitnessPubKeyHash(pkHash, params)
+	case wallet.AddrP2PKH:
+		return btcutil.NewAddressPubKeyHash(pkHash, params)
+	case wallet.AddrP2SHP2WPKH:
+		return btcutil.NewAddressScriptHash(p2wpkhWitnessProgram(pkHash), params)
+	case wallet.AddrP2TR:
+		outputKey := txscript.ComputeTaprootKeyNoScript(pubKey)
+		return btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), params)
+	}
+	return nil, fmt.Errorf("unsupported address type %s", addrType)
+}
+
+// p2wpkhWitnessProgram returns the p2wpkh script which is the redeem script
+// of a p2sh-p2wpkh address
+func p2wpkhWitnessProgram(pkHash []byte) []byte {
+	return append([]byte{txscript.OP_0, txscript.OP_DATA_20}, pkHash...)
+}
+
+// GapLimit returns the number of unused keys kept past the last used key
+func (km *KeyManager) GapLimit() int {
+	return km.gapLimit
+}
+
+// SetGapLimit sets the gap limit & policy and the highest issued key index of
+// each chain, then extends the lookahead windows to the gap limit. Keys
+// already stored past a smaller gap limit are kept.
+func (km *KeyManager) SetGapLimit(gapLimit int, policy wallet.GapPolicy, issued map[wallet.KeyPurpose]int) error {
+	if gapLimit <= 0 {
+		return fmt.Errorf("invalid gap limit %d", gapLimit)
+	}
+	km.issueMutex.Lock()
+	km.gapLimit = gapLimit
+	km.gapPolicy = policy
+	for purpose, index := range issued {
+		km.issued[purpose] = index
+	}
+	km.issueMutex.Unlock()
+	if err := km.extendAccounts(gapLimit); err != nil {
+		return err
+	}
+	return km.lookahead()
+}
+
+// Issued returns the highest key index issued by IssueKey for 'purpose' or -1
+func (km *KeyManager) Issued(purpose wallet.KeyPurpose) int {
+	km.issueMutex.Lock()
+	defer km.issueMutex.Unlock()
+	return km.issued[purpose]
+}
+
+// GetUnusedKey gets the first unused key for 'purpose'. The same key is
+// returned until it is used. If there are no unused keys a fresh key is made.
 func (km *KeyManager) GetUnusedKey(purpose wallet.KeyPurpose) (*hd.ExtendedKey, error) {
 	i, err := km.datastore.GetUnused(purpose)
 	if err != nil {
 		return nil, err
 	}
 	if len(i) == 0 {
-		return nil, errors.New("no unused keys in database")
+		return km.GetFreshKey(purpose)
 	}
 	return km.generateChildKey(purpose, uint32(i[0]))
 }
 
+// IssueKey gets the next unused key for 'purpose' that has not been issued
+// before. Keys are issued from the lookahead window, which reaches the gap
+// limit past the last used key. When every key in the window has been issued
+// the gap policy applies. It returns true if the key is past the gap limit, as
+// issued by GapPolicyExtend.
+func (km *KeyManager) IssueKey(purpose wallet.KeyPurpose) (*hd.ExtendedKey, bool, error) {
+	km.issueMutex.Lock()
+	defer km.issueMutex.Unlock()
+	lastUsed, err := km.lastUsedIndex(purpose)
+	if err != nil {
+		return nil, false, err
+	}
+	unused, err := km.datastore.GetUnused(purpose)
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Ints(unused)
+	issued := km.issued[purpose]
+	for _, index := range unused {
+		if index > issued && index <= lastUsed+km.gapLimit {
+			key, err := km.generateChildKey(purpose, uint32(index))
+			if err != nil {
+				continue
+			}
+			km.issued[purpose] = index
+			return key, false, nil
+		}
+	}
+
+	switch km.gapPolicy {
+	case wallet.GapPolicyReuse:
+		for _, index := range unused {
+			if index > lastUsed {
+				key, err := km.generateChildKey(purpose, uint32(index))
+				if err != nil {
+					continue
+				}
+				return key, false, nil
+			}
+		}
+		return nil, false, wallet.ErrGapLimit
+	case wallet.GapPolicyExtend:
+		for _, index := range unused {
+			if index > issued {
+				key, err := km.generateChildKey(purpose, uint32(index))
+				if err != nil {
+					continue
+				}
+				km.issued[purpose] = index
+				return key, true, nil
+			}
+		}
+		key, index, err := km.freshKey(purpose)
+		if err != nil {
+			return nil, false, err
+		}
+		km.issued[purpose] = index
+		return key, true, nil
+	}
+	return nil, false, wallet.ErrGapLimit
+}
+
+// lastUsedIndex returns the highest used key index for 'purpose' or -1
+func (km *KeyManager) lastUsedIndex(purpose wallet.KeyPurpose) (int, error) {
+	all, err := km.datastore.GetAll()
+	if err != nil {
+		return -1, err
+	}
+	unused, err := km.datastore.GetUnused(purpose)
+	if err != nil {
+		return -1, err
+	}
+	isUnused := make(map[int]bool, len(unused))
+	for _, index := range unused {
+		isUnused[index] = true
+	}
+	last := -1
+	for _, path := range all {
+		if path.Purpose == purpose && !isUnused[path.Index] && path.Index > last {
+			last = path.Index
+		}
+	}
+	return last, nil
+}
+
 func (km *KeyManager) GetFreshKey(purpose wallet.KeyPurpose) (*hd.ExtendedKey, error) {
+	key, _, err := km.freshKey(purpose)
+	return key, err
+}
+
+// freshKey stores the key after the last stored key for 'purpose' and returns
+// it with its index
+func (km *KeyManager) freshKey(purpose wallet.KeyPurpose) (*hd.ExtendedKey, int, error) {
 	index, _, err := km.datastore.GetLastKeyIndex(purpose)
 	var childKey *hd.ExtendedKey
 	if err != nil {
@@ -103,7 +333,7 @@ func (km *KeyManager) GetFreshKey(purpose wallet.KeyPurpose) (*hd.ExtendedKey, e
 	}
 	addr, err := childKey.Address(km.params)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	p := wallet.KeyPath{
 		Purpose: wallet.KeyPurpose(purpose),
@@ -111,9 +341,9 @@ func (km *KeyManager) GetFreshKey(purpose wallet.KeyPurpose) (*hd.ExtendedKey, e
 	}
 	err = km.datastore.Put(addr.ScriptAddress(), p)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return childKey, nil
+	return childKey, index, nil
 }
 
 func (km *KeyManager) GetKeys() []*hd.ExtendedKey {
@@ -135,6 +365,9 @@ func (km *KeyManager) GetKeys() []*hd.ExtendedKey {
 func (km *KeyManager) GetKeyForScript(scriptAddress []byte) (*hd.ExtendedKey, error) {
 	keyPath, err := km.datastore.GetPathForKey(scriptAddress)
 	if err != nil {
+		if key, ok := km.accountKeyForScript(scriptAddress); ok {
+			return key, nil
+		}
 		return nil, err
 	}
 	return km.generateChildKey(keyPath.Purpose, uint32(keyPath.Index))
@@ -143,6 +376,9 @@ func (km *KeyManager) GetKeyForScript(scriptAddress []byte) (*hd.ExtendedKey, er
 // Mark the given key as used and extend the lookahead window
 func (km *KeyManager) MarkKeyAsUsed(scriptAddress []byte) error {
 	if err := km.datastore.MarkKeyAsUsed(scriptAddress); err != nil {
+		if km.markAccountKeyUsed(scriptAddress) {
+			return nil
+		}
 		return err
 	}
 	return km.lookahead()
@@ -157,15 +393,22 @@ func (km *KeyManager) generateChildKey(purpose wallet.KeyPurpose, index uint32)
 	return nil, errors.New("unknown key purpose")
 }
 
+// lookahead stores keys up to the gap limit past the last used key of each
+// chain
 func (km *KeyManager) lookahead() error {
-	lookaheadWindows := km.datastore.GetLookaheadWindows()
-	for purpose, size := range lookaheadWindows {
-		if size < GAP_LIMIT {
-			for i := 0; i < (GAP_LIMIT - size); i++ {
-				_, err := km.GetFreshKey(purpose)
-				if err != nil {
-					return err
-				}
+	for _, purpose := range []wallet.KeyPurpose{wallet.EXTERNAL, wallet.INTERNAL} {
+		lastUsed, err := km.lastUsedIndex(purpose)
+		if err != nil {
+			return err
+		}
+		lastStored, _, err := km.datastore.GetLastKeyIndex(purpose)
+		if err != nil {
+			lastStored = -1
+		}
+		for i := lastStored; i < lastUsed+km.gapLimit; i++ {
+			_, err := km.GetFreshKey(purpose)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -0,0 +1,190 @@
+package wltbtc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// SetLabel sets or, for an empty label text, removes a label.
+func (w *BtcElectrumWallet) SetLabel(label wallet.Label) error {
+	if !label.Type.Valid() {
+		return fmt.Errorf("%w: unknown type %q", wallet.ErrBadLabel, label.Type)
+	}
+	if label.Ref == "" {
+		return fmt.Errorf("%w: missing ref", wallet.ErrBadLabel)
+	}
+	if label.Label == "" {
+		// removing a label that does not exist is not an error
+		w.txstore.Labels().Delete(label.Type, label.Ref)
+		return nil
+	}
+	return w.txstore.Labels().Put(label)
+}
+
+func (w *BtcElectrumWallet) GetLabel(labelType wallet.LabelType, ref string) (*wallet.Label, error) {
+	label, err := w.txstore.Labels().Get(labelType, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+func (w *BtcElectrumWallet) ListLabels(labelType wallet.LabelType) ([]wallet.Label, error) {
+	labels, err := w.txstore.Labels().GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if labelType == "" {
+		return labels, nil
+	}
+	var ret []wallet.Label
+	for _, label := range labels {
+		if label.Type == labelType {
+			ret = append(ret, label)
+		}
+	}
+	return ret, nil
+}
+
+// ImportLabels imports BIP329 labels replacing any existing label for the same
+// reference. Wallet outputs labelled spendable=false are frozen and those
+// labelled spendable=true unfrozen. An output not in the wallet yet keeps its
+// label and is frozen when it arrives if labelled spendable=false. It returns
+// the number of labels written, also when an error stops the import.
+func (w *BtcElectrumWallet) ImportLabels(r io.Reader) (int, error) {
+	labels, err := wallet.ImportBIP329(r)
+	if err != nil {
+		return 0, err
+	}
+	frozen, err := w.frozenByOutPoint()
+	if err != nil {
+		return 0, err
+	}
+	for i, label := range labels {
+		if err := w.txstore.Labels().Put(label); err != nil {
+			return i, err
+		}
+		if label.Type != wallet.LabelOutput || label.Spendable == nil {
+			continue
+		}
+		isFrozen, ok := frozen[label.Ref]
+		if !ok || isFrozen != *label.Spendable {
+			// not a wallet output yet or already as labelled
+			continue
+		}
+		op, err := parseOutPoint(label.Ref)
+		if err != nil {
+			return i + 1, err
+		}
+		if *label.Spendable {
+			err = w.UnFreezeUTXO(op)
+		} else {
+			err = w.FreezeUTXO(op)
+		}
+		if err != nil {
+			return i + 1, err
+		}
+	}
+	return len(labels), nil
+}
+
+// ExportLabels exports all labels as BIP329 JSON Lines. The spendable flag of
+// a wallet output is its frozen state. Frozen outputs without a label are
+// exported with an empty label so the freeze is not lost.
+func (w *BtcElectrumWallet) ExportLabels(wr io.Writer) error {
+	labels, err := w.txstore.Labels().GetAll()
+	if err != nil {
+		return err
+	}
+	frozen, err := w.frozenByOutPoint()
+	if err != nil {
+		return err
+	}
+	labelled := make(map[string]bool)
+	for i := range labels {
+		if labels[i].Type != wallet.LabelOutput {
+			continue
+		}
+		labelled[labels[i].Ref] = true
+		if isFrozen, ok := frozen[labels[i].Ref]; ok {
+			spendable := !isFrozen
+			labels[i].Spendable = &spendable
+		}
+	}
+	for ref, isFrozen := range frozen {
+		if isFrozen && !labelled[ref] {
+			spendable := false
+			labels = append(labels, wallet.Label{
+				Type:      wallet.LabelOutput,
+				Ref:       ref,
+				Spendable: &spendable,
+			})
+		}
+	}
+	return wallet.ExportBIP329(wr, labels)
+}
+
+// frozenByOutPoint returns the frozen state of the wallet utxos keyed on
+// outpoint
+func (w *BtcElectrumWallet) frozenByOutPoint() (map[string]bool, error) {
+	utxos, err := w.txstore.Utxos().GetAll()
+	if err != nil {
+		return nil, err
+	}
+	frozen := make(map[string]bool, len(utxos))
+	for _, u := range utxos {
+		frozen[u.Op.String()] = u.Frozen
+	}
+	return frozen, nil
+}
+
+// labelledUnspendable returns true if the output op is labelled
+// spendable=false
+func (ts *TxStore) labelledUnspendable(op wire.OutPoint) bool {
+	label, err := ts.Labels().Get(wallet.LabelOutput, op.String())
+	return err == nil && label.Spendable != nil && !*label.Spendable
+}
+
+// labelUtxos fills in the labels of utxos: the output label if set else the
+// label of the address paid.
+func (w *BtcElectrumWallet) labelUtxos(utxos []wallet.Utxo) {
+	labels := w.txstore.Labels()
+	for i := range utxos {
+		// an output label may only carry the spendable flag
+		if label, err := labels.Get(wallet.LabelOutput, utxos[i].Op.String()); err == nil && label.Label != "" {
+			utxos[i].Label = label.Label
+			continue
+		}
+		addr := pkScriptAddress(utxos[i].ScriptPubkey, w.params)
+		if addr == "" {
+			continue
+		}
+		if label, err := labels.Get(wallet.LabelAddr, addr); err == nil {
+			utxos[i].Label = label.Label
+		}
+	}
+}
+
+// parseOutPoint parses a "txid:index" reference
+func parseOutPoint(ref string) (*wire.OutPoint, error) {
+	parts := strings.Split(ref, ":")
+	if len(parts) != 2 {
+		return nil, errors.New("invalid outpoint")
+	}
+	hash, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewOutPoint(hash, uint32(index)), nil
+}
@@ -0,0 +1,212 @@
+package wltbtc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestLabels(t *testing.T) {
+	w := MockWallet("abc")
+	ops := putTestUtxos(t, w)
+
+	// an address label applies to its utxos unless the output is labelled
+	utxos, err := w.ListUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addr0 string
+	for _, u := range utxos {
+		if u.Op == ops[1] {
+			addr0 = pkScriptAddress(u.ScriptPubkey, &chaincfg.RegressionNetParams)
+		}
+	}
+	err = w.SetLabel(wallet.Label{Type: wallet.LabelAddr, Ref: addr0, Label: "savings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.SetLabel(wallet.Label{Type: wallet.LabelOutput, Ref: ops[0].String(), Label: "from exchange"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxos, err = w.ListUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range utxos {
+		switch u.Op {
+		case ops[0]:
+			if u.Label != "from exchange" {
+				t.Fatalf("expected output label - got %q", u.Label)
+			}
+		case ops[1]:
+			if u.Label != "savings" {
+				t.Fatalf("expected address label - got %q", u.Label)
+			}
+		}
+	}
+
+	// export, clear and import
+	var buf bytes.Buffer
+	if err := w.ExportLabels(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetLabel(wallet.Label{Type: wallet.LabelAddr, Ref: addr0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.GetLabel(wallet.LabelAddr, addr0); err == nil {
+		t.Fatal("expected the empty label to remove the label")
+	}
+	n, err := w.ImportLabels(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 labels imported - got %d", n)
+	}
+	labels, err := w.ListLabels(wallet.LabelAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0].Label != "savings" {
+		t.Fatalf("expected the address label - got %v", labels)
+	}
+
+	// an unspendable output label freezes the utxo
+	in := `{"type":"output","ref":"` + ops[1].String() + `","label":"do not spend","spendable":false}`
+	if _, err := w.ImportLabels(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	frozen, err := w.ListFrozenUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frozen) != 1 || frozen[0].Op != ops[1] || frozen[0].Label != "do not spend" {
+		t.Fatalf("expected the labelled output to be frozen - got %v", frozen)
+	}
+
+	// the export has the frozen state of wallet outputs
+	buf.Reset()
+	if err := w.UnFreezeUTXO(&ops[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.FreezeUTXO(&ops[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SetLabel(wallet.Label{Type: wallet.LabelOutput, Ref: ops[0].String()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ExportLabels(&buf); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := wallet.ImportBIP329(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spendable := make(map[string]bool)
+	for _, label := range exported {
+		if label.Type == wallet.LabelOutput && label.Spendable != nil {
+			spendable[label.Ref] = *label.Spendable
+		}
+	}
+	if s, ok := spendable[ops[1].String()]; !ok || !s {
+		t.Fatalf("expected the unfrozen output exported spendable - got %v", exported)
+	}
+	if s, ok := spendable[ops[0].String()]; !ok || s {
+		t.Fatalf("expected the unlabelled frozen output exported unspendable - got %v", exported)
+	}
+
+	// a spendable output label unfreezes the utxo
+	in = `{"type":"output","ref":"` + ops[0].String() + `","label":"spend me","spendable":true}`
+	if _, err := w.ImportLabels(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	frozen, err = w.ListFrozenUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frozen) != 0 {
+		t.Fatalf("expected no frozen utxos - got %v", frozen)
+	}
+
+	// an unspendable output not in the wallet yet is frozen when it arrives
+	address, err := w.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000000, script))
+	op := wire.OutPoint{Hash: tx.TxHash()}
+	in = `{"type":"output","ref":"` + op.String() + `","label":"later","spendable":false}`
+	if _, err := w.ImportLabels(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddTransaction(tx, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	frozen, err = w.ListFrozenUnspent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frozen) != 1 || frozen[0].Op != op {
+		t.Fatalf("expected the arrived output to be frozen - got %v", frozen)
+	}
+
+	err = w.SetLabel(wallet.Label{Type: "bogus", Ref: "x", Label: "x"})
+	if err == nil {
+		t.Fatal("expected an error for an invalid label type")
+	}
+}
+
+// putFailLabels fails Put after puts labels are written
+type putFailLabels struct {
+	wallet.Labels
+	puts int
+}
+
+func (l *putFailLabels) Put(label wallet.Label) error {
+	if l.puts == 0 {
+		return errors.New("disk full")
+	}
+	l.puts--
+	return l.Labels.Put(label)
+}
+
+type putFailDatastore struct {
+	wallet.Datastore
+	labels *putFailLabels
+}
+
+func (d *putFailDatastore) Labels() wallet.Labels {
+	return d.labels
+}
+
+func TestImportLabelsPutFails(t *testing.T) {
+	w := MockWallet("abc")
+	w.txstore.Datastore = &putFailDatastore{
+		Datastore: w.txstore.Datastore,
+		labels:    &putFailLabels{Labels: w.txstore.Labels(), puts: 1},
+	}
+	in := `{"type":"tx","ref":"a","label":"one"}
+{"type":"tx","ref":"b","label":"two"}`
+	n, err := w.ImportLabels(strings.NewReader(in))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 label written - got %d", n)
+	}
+}
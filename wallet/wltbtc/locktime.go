@@ -0,0 +1,122 @@
+package wltbtc
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Timelocks
+//
+// Unless told otherwise a spend's nLockTime is the current tip so that a miner
+// re-mining the tip cannot take our fees (anti fee sniping). As in Bitcoin
+// Core one time in ten it is moved back up to 99 blocks so that transactions
+// which were delayed in being broadcast are not singled out. A non-zero lock
+// time is only enforced when an input's nSequence is not final. This wallet
+// never signals RBF so such inputs get 0xfffffffe.
+
+// antiFeeSnipingMaxDelta is the most blocks the anti fee sniping lock time is
+// moved back from the tip
+const antiFeeSnipingMaxDelta = 100
+
+// nonFinalSequence enables the lock time without signaling RBF
+const nonFinalSequence = wire.MaxTxInSequenceNum - 1
+
+// lockTime returns the nLockTime of a spend. The anti fee sniping default is
+// only used when the wallet is synced to the tip.
+func (w *BtcElectrumWallet) lockTime(info *wallet.SpendInfo) uint32 {
+	if info.LockTime != 0 {
+		return info.LockTime
+	}
+	lockTime := w.blockchainTip.Load()
+	if info.NoAntiFeeSniping || !w.blockchainSynced.Load() || lockTime <= 0 {
+		return 0
+	}
+	if rand.Intn(10) == 0 {
+		lockTime -= int64(rand.Intn(antiFeeSnipingMaxDelta))
+		if lockTime < 0 {
+			lockTime = 0
+		}
+	}
+	return uint32(lockTime)
+}
+
+// hasRelativeLock returns true if nSequence enables a BIP68 relative lock
+func hasRelativeLock(sequence uint32) bool {
+	return sequence&wire.SequenceLockTimeDisabled == 0
+}
+
+// sequenceOutPoints returns the outpoints of info.Sequences in a stable order
+func sequenceOutPoints(info *wallet.SpendInfo) []wire.OutPoint {
+	ops := make([]wire.OutPoint, 0, len(info.Sequences))
+	for op := range info.Sequences {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].String() < ops[j].String()
+	})
+	return ops
+}
+
+// checkRelativeLock returns an error if the relative lock of an input with
+// nSequence sequence spending u cannot be met in the next block. Time based
+// locks need the median time past of the coin's block which we do not have so
+// are left to the node on broadcast.
+func (w *BtcElectrumWallet) checkRelativeLock(u wallet.Utxo, sequence uint32) error {
+	if !hasRelativeLock(sequence) || sequence&wire.SequenceLockTimeIsSeconds != 0 {
+		return nil
+	}
+	blocks := int64(sequence & wire.SequenceLockTimeMask)
+	confirmations := w.confirmations(u.AtHeight)
+	if confirmations < blocks {
+		return fmt.Errorf("%w: %s has %d of %d confirmations", wallet.ErrRelativeLockNotMet,
+			u.Op.String(), confirmations, blocks)
+	}
+	return nil
+}
+
+// inputSequences returns the nSequence of each selected input and the
+// transaction version needed to enforce them. lockTime is updated to zero if
+// the anti fee sniping default would not be enforced.
+func (w *BtcElectrumWallet) inputSequences(info *wallet.SpendInfo, selected []wallet.Utxo, lockTime *uint32) (map[wire.OutPoint]uint32, int32, error) {
+	defaultSequence := uint32(wire.MaxTxInSequenceNum)
+	if *lockTime != 0 {
+		defaultSequence = nonFinalSequence
+	}
+	version := int32(wire.TxVersion)
+	sequences := make(map[wire.OutPoint]uint32, len(selected))
+	final := true
+	for _, u := range selected {
+		sequence, ok := info.Sequences[u.Op]
+		if !ok {
+			sequence = defaultSequence
+		}
+		err := w.checkRelativeLock(u, sequence)
+		if err != nil {
+			return nil, 0, err
+		}
+		if hasRelativeLock(sequence) {
+			version = 2
+		}
+		if sequence != wire.MaxTxInSequenceNum {
+			final = false
+		}
+		sequences[u.Op] = sequence
+	}
+	if final && *lockTime != 0 {
+		if info.LockTime != 0 {
+			return nil, 0, wallet.ErrLockTimeNotEnforced
+		}
+		*lockTime = 0
+	}
+	return sequences, version, nil
+}
+
+// isLockTimeHeight returns true if lockTime is a block height
+func isLockTimeHeight(lockTime uint32) bool {
+	return lockTime < txscript.LockTimeThreshold
+}
@@ -0,0 +1,109 @@
+package wltbtc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestRelativeLockTime(t *testing.T) {
+	sequence := wallet.RelativeLockTime(time.Hour)
+	if sequence != wire.SequenceLockTimeIsSeconds|8 {
+		t.Fatalf("expected 8 units of 512s - got %x", sequence)
+	}
+	if wallet.RelativeLockBlocks(144) != 144 {
+		t.Fatal("expected 144 blocks")
+	}
+}
+
+func TestSpendTimelocks(t *testing.T) {
+	w := MockWallet("abc")
+	w.UpdateTip(500, true)
+	ops := putTestUtxos(t, w)
+
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := []wallet.TransactionOutput{{Address: address, Value: 10000000}}
+
+	// anti fee sniping
+	res, err := w.SpendTx("abc", &wallet.SpendInfo{Outputs: outputs, FeeLevel: wallet.NORMAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tx.LockTime > 500 || res.Tx.LockTime <= 500-antiFeeSnipingMaxDelta {
+		t.Fatalf("expected an anti fee sniping lock time near the tip - got %d", res.Tx.LockTime)
+	}
+	if res.BroadcastHeight != 0 {
+		t.Fatalf("expected no broadcast height - got %d", res.BroadcastHeight)
+	}
+	for _, in := range res.Tx.TxIn {
+		if in.Sequence != nonFinalSequence {
+			t.Fatalf("expected sequence %x - got %x", nonFinalSequence, in.Sequence)
+		}
+	}
+	res, err = w.SpendTx("abc", &wallet.SpendInfo{Outputs: outputs, FeeLevel: wallet.NORMAL, NoAntiFeeSniping: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tx.LockTime != 0 || res.Tx.TxIn[0].Sequence != wire.MaxTxInSequenceNum {
+		t.Fatal("expected no lock time")
+	}
+
+	// future absolute lock time
+	res, err = w.SpendTx("abc", &wallet.SpendInfo{Outputs: outputs, FeeLevel: wallet.NORMAL, LockTime: 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tx.LockTime != 600 || res.Tx.Version != wire.TxVersion {
+		t.Fatalf("expected lock time 600 version 1 - got %d version %d", res.Tx.LockTime, res.Tx.Version)
+	}
+	if res.BroadcastHeight != 600 {
+		t.Fatalf("expected broadcast height 600 - got %d", res.BroadcastHeight)
+	}
+
+	// a lock time with final sequences does nothing
+	info := &wallet.SpendInfo{
+		Outputs:   outputs,
+		FeeLevel:  wallet.NORMAL,
+		LockTime:  600,
+		MustUse:   ops[:1],
+		Coins:     ops[:1],
+		Sequences: map[wire.OutPoint]uint32{ops[0]: wire.MaxTxInSequenceNum},
+	}
+	_, err = w.SpendTx("abc", info)
+	if err != wallet.ErrLockTimeNotEnforced {
+		t.Fatalf("expected %v - got %v", wallet.ErrLockTimeNotEnforced, err)
+	}
+
+	// relative locks; the coins have 75 confirmations
+	info = &wallet.SpendInfo{
+		Outputs:   outputs,
+		FeeLevel:  wallet.NORMAL,
+		Coins:     ops[:1],
+		Sequences: map[wire.OutPoint]uint32{ops[0]: wallet.RelativeLockBlocks(75)},
+	}
+	res, err = w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tx.Version != 2 || len(res.Tx.TxIn) != 1 || res.Tx.TxIn[0].Sequence != 75 {
+		t.Fatalf("expected a version 2 tx with relative lock 75 - got version %d", res.Tx.Version)
+	}
+	info.Sequences[ops[0]] = wallet.RelativeLockBlocks(76)
+	_, err = w.SpendTx("abc", info)
+	if !errors.Is(err, wallet.ErrRelativeLockNotMet) {
+		t.Fatalf("expected %v - got %v", wallet.ErrRelativeLockNotMet, err)
+	}
+	info.Sequences[ops[0]] = wallet.RelativeLockTime(time.Hour)
+	_, err = w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,256 @@
+package wltbtc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+// Message signing
+//
+// A BIP137 signature is the 65 byte compact ECDSA signature of Bitcoin Core's
+// signmessage. The header byte holds the public key recovery id and the type
+// of address signing. A BIP322 simple signature is the witness of a virtual
+// transaction spending a virtual output which pays to the address and commits
+// to the message.
+
+const bitcoinMessageMagic = "Bitcoin Signed Message:\n"
+
+var bip322Tag = []byte("BIP0322-signed-message")
+
+// BIP137 header bytes for recovery id 0
+const (
+	bip137HeaderP2PKHUncompressed = 27
+	bip137HeaderP2PKH             = 31
+	bip137HeaderP2SHP2WPKH        = 35
+	bip137HeaderP2WPKH            = 39
+	bip137HeaderMax               = 42
+)
+
+// max size of a BIP322 witness item
+const bip322MaxWitnessItemSize = 10000
+
+// SignMessage signs message with the key of a wallet address. The default
+// format is BIP137 for P2PKH, P2SH-P2WPKH & P2WPKH addresses and BIP322 simple
+// for P2TR addresses.
+func (w *BtcElectrumWallet) SignMessage(pw string, address btcutil.Address, message string, format wallet.MessageFormat) (string, error) {
+	if w.watchOnly {
+		return "", wallet.ErrWatchOnly
+	}
+	if ok := w.storageManager.IsValidPw(pw); !ok {
+		return "", errors.New("invalid password")
+	}
+	if format == wallet.MessageFormatDefault {
+		format = wallet.MessageFormatBIP137
+		if _, ok := address.(*btcutil.AddressTaproot); ok {
+			format = wallet.MessageFormatBIP322
+		}
+	}
+	hdKey, err := w.keyManager.GetKeyForScript(address.ScriptAddress())
+	if err != nil {
+		return "", err
+	}
+	defer hdKey.Zero()
+	privKey, err := hdKey.ECPrivKey()
+	if err != nil {
+		return "", err
+	}
+	var sig []byte
+	switch format {
+	case wallet.MessageFormatBIP137:
+		sig, err = signMessageBIP137(privKey, address, message)
+	case wallet.MessageFormatBIP322:
+		sig, err = signMessageBIP322(privKey, address, message)
+	default:
+		return "", fmt.Errorf("unknown message format %d", format)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyMessage returns true if signature is a BIP137 or BIP322 simple
+// signature of message by address. As in Electrum a BIP137 signature is valid
+// for any address type made from the recovered key whatever its header says.
+func (w *BtcElectrumWallet) VerifyMessage(address btcutil.Address, message, signature string) (bool, error) {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if len(sig) == 65 && sig[0] >= bip137HeaderP2PKHUncompressed && sig[0] <= bip137HeaderMax {
+		return verifyMessageBIP137(address, message, sig)
+	}
+	return verifyMessageBIP322(address, message, sig)
+}
+
+// bip137Hash returns the hash signed by a BIP137 signature
+func bip137Hash(message string) []byte {
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, bitcoinMessageMagic)
+	wire.WriteVarString(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+func signMessageBIP137(privKey *btcec.PrivateKey, address btcutil.Address, message string) ([]byte, error) {
+	var header byte
+	switch address.(type) {
+	case *btcutil.AddressPubKeyHash:
+		header = bip137HeaderP2PKH
+	case *btcutil.AddressScriptHash:
+		header = bip137HeaderP2SHP2WPKH
+	case *btcutil.AddressWitnessPubKeyHash:
+		header = bip137HeaderP2WPKH
+	default:
+		return nil, fmt.Errorf("%w: %s with %s", wallet.ErrMessageFormat, wallet.MessageFormatBIP137, address)
+	}
+	sig, err := ecdsa.SignCompact(privKey, bip137Hash(message), true)
+	if err != nil {
+		return nil, err
+	}
+	sig[0] += header - bip137HeaderP2PKH
+	return sig, nil
+}
+
+func verifyMessageBIP137(address btcutil.Address, message string, sig []byte) (bool, error) {
+	// recover as P2PKH
+	compact := make([]byte, len(sig))
+	copy(compact, sig)
+	switch {
+	case sig[0] >= bip137HeaderP2WPKH:
+		compact[0] -= bip137HeaderP2WPKH - bip137HeaderP2PKH
+	case sig[0] >= bip137HeaderP2SHP2WPKH:
+		compact[0] -= bip137HeaderP2SHP2WPKH - bip137HeaderP2PKH
+	}
+	pubKey, compressed, err := ecdsa.RecoverCompact(compact, bip137Hash(message))
+	if err != nil {
+		return false, nil
+	}
+	serialized := pubKey.SerializeUncompressed()
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	}
+	pkHash := btcutil.Hash160(serialized)
+	switch address.(type) {
+	case *btcutil.AddressPubKeyHash:
+		return bytes.Equal(address.ScriptAddress(), pkHash), nil
+	case *btcutil.AddressScriptHash:
+		redeemScript := p2wpkhWitnessProgram(pkHash)
+		return compressed && bytes.Equal(address.ScriptAddress(), btcutil.Hash160(redeemScript)), nil
+	case *btcutil.AddressWitnessPubKeyHash:
+		return compressed && bytes.Equal(address.ScriptAddress(), pkHash), nil
+	}
+	return false, fmt.Errorf("%w: %s with %s", wallet.ErrMessageFormat, wallet.MessageFormatBIP137, address)
+}
+
+// bip322ToSpend returns the virtual transaction paying to pkScript which
+// commits to message
+func bip322ToSpend(pkScript []byte, message string) *wire.MsgTx {
+	msgHash := chainhash.TaggedHash(bip322Tag, []byte(message))
+	sigScript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(msgHash[:]).
+		Script()
+	tx := wire.NewMsgTx(0)
+	in := wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), sigScript, nil)
+	in.Sequence = 0
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(0, pkScript))
+	return tx
+}
+
+// bip322ToSign returns the virtual transaction spending toSpend with witness
+func bip322ToSign(toSpend *wire.MsgTx, witness wire.TxWitness) *wire.MsgTx {
+	txid := toSpend.TxHash()
+	tx := wire.NewMsgTx(0)
+	in := wire.NewTxIn(wire.NewOutPoint(&txid, 0), nil, witness)
+	in.Sequence = 0
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return tx
+}
+
+// bip322Script returns the output script of a BIP322 simple signing address
+func bip322Script(address btcutil.Address) ([]byte, error) {
+	switch address.(type) {
+	case *btcutil.AddressWitnessPubKeyHash, *btcutil.AddressWitnessScriptHash, *btcutil.AddressTaproot:
+		return txscript.PayToAddrScript(address)
+	}
+	return nil, fmt.Errorf("%w: %s with %s", wallet.ErrMessageFormat, wallet.MessageFormatBIP322, address)
+}
+
+func signMessageBIP322(privKey *btcec.PrivateKey, address btcutil.Address, message string) ([]byte, error) {
+	pkScript, err := bip322Script(address)
+	if err != nil {
+		return nil, err
+	}
+	toSign := bip322ToSign(bip322ToSpend(pkScript, message), nil)
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	sigHashes := txscript.NewTxSigHashes(toSign, prevOutFetcher)
+	var witness wire.TxWitness
+	switch address.(type) {
+	case *btcutil.AddressWitnessPubKeyHash:
+		witness, err = txscript.WitnessSignature(toSign, sigHashes, 0, 0,
+			pkScript, txscript.SigHashAll, privKey, true)
+	case *btcutil.AddressTaproot:
+		// BIP86 key path spend
+		witness, err = txscript.TaprootWitnessSignature(toSign, sigHashes, 0, 0,
+			pkScript, txscript.SigHashDefault, privKey)
+	default:
+		return nil, fmt.Errorf("%w: %s with %s", wallet.ErrMessageFormat, wallet.MessageFormatBIP322, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = wire.WriteVarInt(&buf, 0, uint64(len(witness)))
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range witness {
+		err = wire.WriteVarBytes(&buf, 0, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func verifyMessageBIP322(address btcutil.Address, message string, sig []byte) (bool, error) {
+	pkScript, err := bip322Script(address)
+	if err != nil {
+		return false, err
+	}
+	r := bytes.NewReader(sig)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil || n > uint64(len(sig)) {
+		return false, errors.New("invalid BIP322 signature")
+	}
+	witness := make(wire.TxWitness, n)
+	for i := range witness {
+		witness[i], err = wire.ReadVarBytes(r, 0, bip322MaxWitnessItemSize, "witness item")
+		if err != nil {
+			return false, errors.New("invalid BIP322 signature")
+		}
+	}
+	if r.Len() != 0 {
+		return false, errors.New("invalid BIP322 signature")
+	}
+	toSign := bip322ToSign(bip322ToSpend(pkScript, message), witness)
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+	vm, err := txscript.NewEngine(pkScript, toSign, 0, txscript.StandardVerifyFlags,
+		nil, txscript.NewTxSigHashes(toSign, prevOutFetcher), 0, prevOutFetcher)
+	if err != nil {
+		return false, nil
+	}
+	return vm.Execute() == nil, nil
+}
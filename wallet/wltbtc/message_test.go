@@ -0,0 +1,112 @@
+package wltbtc
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
+)
+
+func TestBIP322Vectors(t *testing.T) {
+	w := MockWallet("abc")
+	for message, hash := range map[string]string{
+		"":            "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1",
+		"Hello World": "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a",
+	} {
+		h := chainhash.TaggedHash(bip322Tag, []byte(message))
+		if hex.EncodeToString(h[:]) != hash {
+			t.Fatalf("message hash of %q: got %x", message, h[:])
+		}
+	}
+	address, err := btcutil.DecodeAddress("bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		message   string
+		signature string
+		valid     bool
+	}{
+		{"", "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=", true},
+		{"Hello World", "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=", true},
+		{"Hello World", "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=", false},
+	}
+	for _, test := range tests {
+		valid, err := w.VerifyMessage(address, test.message, test.signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid != test.valid {
+			t.Fatalf("%q: expected valid %v", test.message, test.valid)
+		}
+	}
+}
+
+func TestSignMessage(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+
+	segwit, err := w.GetUnusedAddress(wallet.RECEIVING)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := w.GetUnusedLegacyAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := wallet.NewAccount(wallet.PurposeBIP86, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.EnableAccounts("abc", []wallet.Account{*account}); err != nil {
+		t.Fatal(err)
+	}
+	taproot, err := w.AccountAddress(account, wallet.EXTERNAL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const message = "I control this address"
+	tests := []struct {
+		address btcutil.Address
+		format  wallet.MessageFormat
+	}{
+		{segwit, wallet.MessageFormatDefault},
+		{segwit, wallet.MessageFormatBIP322},
+		{legacy, wallet.MessageFormatBIP137},
+		{taproot, wallet.MessageFormatDefault},
+	}
+	for _, test := range tests {
+		sig, err := w.SignMessage("abc", test.address, message, test.format)
+		if err != nil {
+			t.Fatalf("%s %s: %v", test.address, test.format, err)
+		}
+		valid, err := w.VerifyMessage(test.address, message, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Fatalf("%s %s: expected a valid signature", test.address, test.format)
+		}
+		valid, err = w.VerifyMessage(test.address, message+".", sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid {
+			t.Fatalf("%s %s: expected an invalid signature", test.address, test.format)
+		}
+	}
+
+	_, err = w.SignMessage("abc", legacy, message, wallet.MessageFormatBIP322)
+	if !errors.Is(err, wallet.ErrMessageFormat) {
+		t.Fatalf("expected %v - got %v", wallet.ErrMessageFormat, err)
+	}
+	_, err = w.SignMessage("xyz", segwit, message, wallet.MessageFormatDefault)
+	if err == nil {
+		t.Fatal("expected an invalid password error")
+	}
+}
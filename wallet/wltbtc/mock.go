@@ -23,8 +23,8 @@ func makeRegtestSeed() []byte {
 	return bip39.NewSeed(test_mnemonic, "")
 }
 
-func createTxStore() (*TxStore, *StorageManager) {
-	mockDb := MockDatastore{
+func newMockDatastore() *MockDatastore {
+	return &MockDatastore{
 		&mockConfig{creationDate: time.Now()},
 		&mockStorage{blob: make([]byte, 10)},
 		&mockKeyStore{make(map[string]*keyStoreEntry)},
@@ -32,14 +32,19 @@ func createTxStore() (*TxStore, *StorageManager) {
 		&mockStxoStore{make(map[string]*wallet.Stxo)},
 		&mockTxnStore{make(map[string]*wallet.Txn)},
 		&mockSubscriptionsStore{make(map[string]*wallet.Subscription)},
+		&mockLabelStore{make(map[string]wallet.Label)},
 	}
+}
+
+func createTxStore() (*TxStore, *StorageManager) {
+	mockDb := newMockDatastore()
 
 	seed := makeRegtestSeed()
 	// fmt.Println("Made test seed")
 	key, _ := hdkeychain.NewMaster(seed, &chaincfg.RegressionNetParams)
 	km, _ := NewKeyManager(mockDb.Keys(), &chaincfg.RegressionNetParams, key)
 	sm := NewStorageManager(mockDb.Enc(), &chaincfg.RegressionNetParams)
-	txStore, _ := NewTxStore(&chaincfg.RegressionNetParams, &mockDb, km)
+	txStore, _ := NewTxStore(&chaincfg.RegressionNetParams, mockDb, km)
 	return txStore, sm
 }
 
@@ -47,8 +52,11 @@ func createTxStore() (*TxStore, *StorageManager) {
 func MockWallet(pw string) *BtcElectrumWallet {
 	txstore, storageMgr := createTxStore()
 
-	storageMgr.store.Xprv = "tprv8ZgxMBicQKsPfJU6JyiVdmFAtAzmWmTeEv85nTAHjLQyL35tdP2fAPWDSBBnFqGhhfTHVQMcnZhZDFkzFmCjm1bgf5UDwMAeFUWhJ9Dr8c4"
-	storageMgr.store.Xpub = "tpubD6NzVbkrYhZ4YmVtCdP63AuHTCWhg6eYpDis4yCb9cDNAXLfFmrFLt85cLFTwHiDJ9855NiE7cgQdiTGt5mb2RS9RfaxgVDkwBybJWm54Gh"
+	// the master keys of the txstore's key manager
+	mPrivKey, _ := hdkeychain.NewMaster(makeRegtestSeed(), &chaincfg.RegressionNetParams)
+	mPubKey, _ := mPrivKey.Neuter()
+	storageMgr.store.Xprv = mPrivKey.String()
+	storageMgr.store.Xpub = mPubKey.String()
 	storageMgr.store.ShaPw = chainhash.HashB([]byte(pw))
 	storageMgr.store.Seed = []byte{0x01, 0x02, 0x03}
 
@@ -73,6 +81,7 @@ type MockDatastore struct {
 	stxos            wallet.Stxos
 	txns             wallet.Txns
 	subscribeScripts wallet.Subscriptions
+	labels           wallet.Labels
 }
 
 func (m *MockDatastore) Cfg() wallet.Cfg {
@@ -103,8 +112,13 @@ func (m *MockDatastore) Subscriptions() wallet.Subscriptions {
 	return m.subscribeScripts
 }
 
+func (m *MockDatastore) Labels() wallet.Labels {
+	return m.labels
+}
+
 type mockConfig struct {
 	creationDate time.Time
+	settings     map[string][]byte
 }
 
 func (mc *mockConfig) PutCreationDate(date time.Time) error {
@@ -116,9 +130,23 @@ func (mc *mockConfig) GetCreationDate() (time.Time, error) {
 	return mc.creationDate, nil
 }
 
+func (mc *mockConfig) PutSetting(key string, value []byte) error {
+	if mc.settings == nil {
+		mc.settings = make(map[string][]byte)
+	}
+	mc.settings[key] = value
+	return nil
+}
+
+func (mc *mockConfig) GetSetting(key string) ([]byte, error) {
+	return mc.settings[key], nil
+}
+
 // encrypted blob
 type mockStorage struct {
 	blob []byte
+	// password, "abc" if not set
+	pw string
 }
 
 // reverse simulates encryption/decryption between bytes and a database blob
@@ -130,8 +158,15 @@ func reverse(s []byte) []byte {
 	return d
 }
 
+func (ms *mockStorage) password() string {
+	if ms.pw == "" {
+		return "abc"
+	}
+	return ms.pw
+}
+
 func (ms *mockStorage) PutEncrypted(b []byte, pw string) error {
-	if pw != "abc" {
+	if pw != ms.password() {
 		return errors.New("invalid password")
 	}
 	ms.blob = reverse(b)
@@ -139,12 +174,21 @@ func (ms *mockStorage) PutEncrypted(b []byte, pw string) error {
 }
 
 func (ms *mockStorage) GetDecrypted(pw string) ([]byte, error) {
-	if pw != "abc" {
+	if pw != ms.password() {
 		return nil, errors.New("invalid password")
 	}
 	return reverse(ms.blob), nil
 }
 
+func (ms *mockStorage) ChangePassword(b []byte, oldPw, newPw string) error {
+	if oldPw != ms.password() || newPw == "" {
+		return errors.New("invalid password")
+	}
+	ms.blob = reverse(b)
+	ms.pw = newPw
+	return nil
+}
+
 type keyStoreEntry struct {
 	scriptAddress []byte
 	path          wallet.KeyPath
@@ -295,7 +339,7 @@ func (m *mockUtxoStore) UnFreeze(utxo wallet.Utxo) error {
 	if !ok {
 		return errors.New("not found")
 	}
-	u.WatchOnly = false
+	u.Frozen = false
 	return nil
 }
 
@@ -435,6 +479,44 @@ func (m *mockSubscriptionsStore) Delete(scriptPubKey string) error {
 	return nil
 }
 
+type mockLabelStore struct {
+	labels map[string]wallet.Label
+}
+
+func (m *mockLabelStore) Put(label wallet.Label) error {
+	if !label.Type.Valid() {
+		return errors.New("invalid label type")
+	}
+	m.labels[string(label.Type)+" "+label.Ref] = label
+	return nil
+}
+
+func (m *mockLabelStore) Get(labelType wallet.LabelType, ref string) (wallet.Label, error) {
+	label, ok := m.labels[string(labelType)+" "+ref]
+	if !ok {
+		return wallet.Label{}, errors.New("not found")
+	}
+	return label, nil
+}
+
+func (m *mockLabelStore) GetAll() ([]wallet.Label, error) {
+	var ret []wallet.Label
+	for _, label := range m.labels {
+		ret = append(ret, label)
+	}
+	return ret, nil
+}
+
+func (m *mockLabelStore) Delete(labelType wallet.LabelType, ref string) error {
+	key := string(labelType) + " " + ref
+	_, ok := m.labels[key]
+	if !ok {
+		return errors.New("not found")
+	}
+	delete(m.labels, key)
+	return nil
+}
+
 func TestUtxo_IsEqual(t *testing.T) {
 	h, err := chainhash.NewHashFromStr("16bed6368b8b1542cd6eb87f5bc20dc830b41a2258dde40438a75fa701d24e9a")
 	if err != nil {
@@ -3,8 +3,6 @@
 package wltbtc
 
 import (
-	"bytes"
-	"encoding/hex"
 	"errors"
 	"fmt"
 
@@ -17,6 +15,8 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcwallet/wallet/txauthor"
+	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/btcsuite/btcwallet/wallet/txsizes"
 	"github.com/dev-warrior777/go-electrum-client/wallet"
 )
 
@@ -45,8 +45,21 @@ func (ss *secretSource) GetKey(address btcutil.Address) (*btcec.PrivateKey, bool
 }
 
 // GetScript fetches the redemption script for the specified p2sh/p2wsh address.
+// The wallet's only p2sh addresses are p2sh-p2wpkh account addresses.
 func (ss *secretSource) GetScript(address btcutil.Address) ([]byte, error) {
-	return txscript.PayToAddrScript(address)
+	if _, ok := address.(*btcutil.AddressScriptHash); !ok {
+		return txscript.PayToAddrScript(address)
+	}
+	extKey, err := ss.w.keyManager.GetKeyForScript(address.ScriptAddress())
+	if err != nil {
+		return nil, err
+	}
+	defer extKey.Zero()
+	pubKey, err := extKey.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return p2wpkhWitnessProgram(btcutil.Hash160(pubKey.SerializeCompressed())), nil
 }
 
 // satisfies coinset.Coin
@@ -78,9 +91,22 @@ func newUnspentCoin(txHash *chainhash.Hash, index uint32, value btcutil.Amount,
 
 // gatherCoins aggregates acceptable utxos into a alice of coinset.Coin's
 func (w *BtcElectrumWallet) gatherCoins(excludeUnconfirmed bool) []coinset.Coin {
-	tip := w.blockchainTip
-	utxos, _ := w.txstore.Utxos().GetAll()
 	var unspentCoins []coinset.Coin
+	for _, u := range w.gatherUtxos(excludeUnconfirmed) {
+		var confirmations int64
+		if u.AtHeight > 0 {
+			confirmations = w.blockchainTip.Load() - u.AtHeight
+		}
+		unspent := newUnspentCoin(&u.Op.Hash, u.Op.Index, btcutil.Amount(u.Value), confirmations, u.ScriptPubkey)
+		unspentCoins = append(unspentCoins, unspent)
+	}
+	return unspentCoins
+}
+
+// gatherUtxos returns the wallet utxos that can be spent
+func (w *BtcElectrumWallet) gatherUtxos(excludeUnconfirmed bool) []wallet.Utxo {
+	utxos, _ := w.txstore.Utxos().GetAll()
+	var spendable []wallet.Utxo
 	for _, u := range utxos {
 		if u.WatchOnly {
 			continue
@@ -91,14 +117,9 @@ func (w *BtcElectrumWallet) gatherCoins(excludeUnconfirmed bool) []coinset.Coin
 		if excludeUnconfirmed && u.AtHeight <= 0 {
 			continue
 		}
-		var confirmations int64
-		if u.AtHeight > 0 {
-			confirmations = tip - u.AtHeight
-		}
-		unspent := newUnspentCoin(&u.Op.Hash, u.Op.Index, btcutil.Amount(u.Value), confirmations, u.ScriptPubkey)
-		unspentCoins = append(unspentCoins, unspent)
+		spendable = append(spendable, u)
 	}
-	return unspentCoins
+	return spendable
 }
 
 // Spend creates and signs a new transaction from wallet coins
@@ -108,112 +129,486 @@ func (w *BtcElectrumWallet) Spend(
 	address btcutil.Address,
 	feeLevel wallet.FeeLevel) (int, *wire.MsgTx, error) {
 
+	info := &wallet.SpendInfo{
+		Outputs: []wallet.TransactionOutput{
+			{Address: address, Value: amount},
+		},
+		FeeLevel: feeLevel,
+	}
+	res, err := w.SpendTx(pw, info)
+	if err != nil {
+		return -1, nil, err
+	}
+	return res.ChangeIndex, res.Tx, nil
+}
+
+// SpendTx creates and signs a new transaction from wallet coins as described
+// by info.
+func (w *BtcElectrumWallet) SpendTx(pw string, info *wallet.SpendInfo) (*wallet.SpendResult, error) {
+	if w.watchOnly {
+		return nil, wallet.ErrWatchOnly
+	}
 	if ok := w.storageManager.IsValidPw(pw); !ok {
-		return -1, nil, errors.New("invalid password")
+		return nil, errors.New("invalid password")
 	}
+	return w.buildTx(info)
+}
 
-	changeIndex, tx, err := w.buildTx(amount, address, feeLevel)
+// chosenUtxo returns the utxo for an outpoint chosen by the caller or an
+// error saying why it cannot be spent.
+func chosenUtxo(utxos []wallet.Utxo, op wire.OutPoint, includeUnconfirmed bool) (wallet.Utxo, error) {
+	for _, u := range utxos {
+		if !outPointsEqual(u.Op, op) {
+			continue
+		}
+		switch {
+		case u.WatchOnly:
+			return u, fmt.Errorf("%w: %s is watch only", wallet.ErrUnknownCoin, op.String())
+		case u.Frozen:
+			return u, fmt.Errorf("%w: %s", wallet.ErrFrozenCoin, op.String())
+		case !includeUnconfirmed && u.AtHeight <= 0:
+			return u, fmt.Errorf("%w: %s", wallet.ErrUnconfirmedCoin, op.String())
+		}
+		return u, nil
+	}
+	return wallet.Utxo{}, fmt.Errorf("%w: %s", wallet.ErrUnknownCoin, op.String())
+}
+
+// spendableCoins returns the coins that the spend must use and the other
+// coins it may use.
+func (w *BtcElectrumWallet) spendableCoins(info *wallet.SpendInfo) ([]wallet.Utxo, []wallet.Utxo, error) {
+	utxos, err := w.txstore.Utxos().GetAll()
 	if err != nil {
-		return -1, nil, err
+		return nil, nil, err
+	}
+	var required []wallet.Utxo
+	isRequired := make(map[wire.OutPoint]bool)
+	for _, op := range info.MustUse {
+		if isRequired[op] {
+			continue
+		}
+		u, err := chosenUtxo(utxos, op, info.IncludeUnconfirmed)
+		if err != nil {
+			return nil, nil, err
+		}
+		required = append(required, u)
+		isRequired[op] = true
+	}
+
+	var candidates []wallet.Utxo
+	if len(info.Coins) == 0 {
+		for _, u := range w.gatherUtxos(!info.IncludeUnconfirmed) {
+			if !isRequired[u.Op] {
+				candidates = append(candidates, u)
+			}
+		}
+		return required, candidates, nil
 	}
-	return changeIndex, tx, nil
+	isCandidate := make(map[wire.OutPoint]bool)
+	for _, op := range info.Coins {
+		if isRequired[op] || isCandidate[op] {
+			continue
+		}
+		u, err := chosenUtxo(utxos, op, info.IncludeUnconfirmed)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates = append(candidates, u)
+		isCandidate[op] = true
+	}
+	return required, candidates, nil
 }
 
-// buildTx builds a normal Pay to (witness) pubkey hash transaction.
-func (w *BtcElectrumWallet) buildTx(
-	amount int64,
-	address btcutil.Address,
-	feeLevel wallet.FeeLevel) (int, *wire.MsgTx, error) {
+// utxosValue sums the value of utxos.
+func utxosValue(utxos []wallet.Utxo) int64 {
+	var total int64
+	for _, u := range utxos {
+		total += u.Value
+	}
+	return total
+}
+
+// estimateTxVSize returns a worst case virtual size for a signed transaction
+// spending utxos to outputs. A change output with a changeScriptSize script is
+// included in the estimate if changeScriptSize is not zero.
+func estimateTxVSize(utxos []wallet.Utxo, outputs []*wire.TxOut, changeScriptSize int) int {
+	var p2pkh, p2tr, p2wpkh, nested int
+	for _, u := range utxos {
+		switch {
+		case txscript.IsPayToScriptHash(u.ScriptPubkey):
+			nested++
+		case txscript.IsPayToWitnessPubKeyHash(u.ScriptPubkey):
+			p2wpkh++
+		case txscript.IsPayToTaproot(u.ScriptPubkey):
+			p2tr++
+		default:
+			p2pkh++
+		}
+	}
+	return txsizes.EstimateVirtualSize(p2pkh, p2tr, p2wpkh, nested, outputs, changeScriptSize)
+}
+
+// changeScriptSize returns the script size of the wallet's change outputs
+func (w *BtcElectrumWallet) changeScriptSize() int {
+	if w.keyManager.AddressType() == wallet.AddrP2PKH {
+		return txsizes.P2PKHPkScriptSize
+	}
+	return txsizes.P2WPKHPkScriptSize
+}
+
+// selectionParams makes the coin selection parameters for paying outputs at
+// feeRate sats/vbyte.
+func (w *BtcElectrumWallet) selectionParams(
+	required []wallet.Utxo,
+	outputs []*wire.TxOut,
+	feeRate int64) *wallet.SelectionParams {
 
-	// Check for dust
-	if w.IsDust(amount) {
-		return -1, nil, wallet.ErrDustAmount
+	var target int64
+	for _, out := range outputs {
+		target += out.Value
+	}
+	changeVSize := txsizes.P2WPKHOutputSize
+	changeSpendVSize := txsizes.RedeemP2WPKHInputSize + (txsizes.RedeemP2WPKHInputWitnessWeight+3)/4
+	if w.keyManager.AddressType() == wallet.AddrP2PKH {
+		changeVSize = txsizes.P2PKHOutputSize
+		changeSpendVSize = txsizes.RedeemP2PKHInputSize
+	}
+	// the long term fee rate is the last economic rate known so that spends
+	// do not wait on the network for it
+	return &wallet.SelectionParams{
+		Target:          target,
+		FeeRate:         feeRate,
+		LongTermFeeRate: w.feeProvider.LastFeePerByte(wallet.ECONOMIC),
+		// segwit marker and flag are counted here rather than per input
+		BaseVSize:        txsizes.EstimateVirtualSize(0, 0, 0, 0, outputs, 0) + 1,
+		ChangeVSize:      changeVSize,
+		ChangeSpendVSize: changeSpendVSize,
+		MinChange:        int64(txrules.DefaultRelayFeePerKb),
+		Required:         required,
+	}
+}
+
+// selectCoins selects coins to pay outputs using selector. All of the
+// required coins are selected and as many of coins as are needed. It returns
+// the selected coins, the change amount (zero for no change output), the fee
+// and the waste metric for the selection. The fee is absoluteFee if set else
+// it is from feeRate. If subtractFee is true the coins need only cover the
+// outputs as the fee will be taken from the outputs; any leftover too small
+// for change is then given to the fee.
+func (w *BtcElectrumWallet) selectCoins(
+	selector wallet.CoinSelector,
+	required []wallet.Utxo,
+	coins []wallet.Utxo,
+	outputs []*wire.TxOut,
+	feeRate int64,
+	absoluteFee int64,
+	subtractFee bool) ([]wallet.Utxo, int64, int64, int64, error) {
+
+	txFee := func(vsize int) int64 {
+		if absoluteFee > 0 {
+			return absoluteFee
+		}
+		return feeRate * int64(vsize)
 	}
 
-	// check payto address
-	script, err := txscript.PayToAddrScript(address)
+	params := w.selectionParams(required, outputs, feeRate)
+	selectParams := params
+	if subtractFee || absoluteFee > 0 {
+		// fees are paid by the outputs or are fixed
+		p := *params
+		p.FeeRate = 0
+		p.LongTermFeeRate = 0
+		if !subtractFee {
+			p.Target += absoluteFee
+		}
+		selectParams = &p
+	}
+	selected, err := selector.Select(coins, selectParams)
 	if err != nil {
-		return -1, nil, err
+		return nil, 0, 0, 0, err
 	}
+	total := utxosValue(selected)
+	target := params.Target
 
-	// create input source
-	coins := w.gatherCoins(true)
-	for i, coin := range coins {
-		fmt.Println(i, coin.Hash().String(), coin.Index(), coin.PkScript())
+	feeNoChange := txFee(estimateTxVSize(selected, outputs, 0))
+	feeWithChange := txFee(estimateTxVSize(selected, outputs, w.changeScriptSize()))
+
+	if subtractFee {
+		change := total - target
+		if change > 0 && !w.IsDust(change) {
+			return selected, change, feeWithChange, wallet.SelectionWaste(selected, params, true), nil
+		}
+		fee := feeNoChange
+		if change > fee {
+			fee = change
+		}
+		return selected, 0, fee, wallet.SelectionWaste(selected, params, false), nil
 	}
 
-	var prevScripts map[wire.OutPoint]*wire.TxOut
+	if total < target+feeNoChange {
+		return nil, 0, 0, 0, wallet.ErrInsufficientFunds
+	}
+	change := total - target - feeWithChange
+	if change > 0 && !w.IsDust(change) {
+		return selected, change, feeWithChange, wallet.SelectionWaste(selected, params, true), nil
+	}
+	// no change output - the remainder goes to the miner
+	return selected, 0, total - target, wallet.SelectionWaste(selected, params, false), nil
+}
 
-	inputSource := func(target btcutil.Amount) (
-		total btcutil.Amount,
-		inputs []*wire.TxIn,
-		inputValues []btcutil.Amount,
-		scripts [][]byte, err error) {
+// checkFeeLimits returns a *wallet.FeeLimitError if fee for a tx of vsize
+// paying amount is above any of the wallet's fee guards.
+func (w *BtcElectrumWallet) checkFeeLimits(fee int64, vsize int, amount int64) error {
+	if vsize > 0 && w.feeProvider.MaxFee > 0 {
+		feeRate := float64(fee) / float64(vsize)
+		if feeRate > float64(w.feeProvider.MaxFee) {
+			return &wallet.FeeLimitError{
+				Limit: wallet.FeeRateLimit,
+				Value: feeRate,
+				Max:   float64(w.feeProvider.MaxFee),
+			}
+		}
+	}
+	if w.maxAbsoluteFee > 0 && fee > w.maxAbsoluteFee {
+		return &wallet.FeeLimitError{
+			Limit: wallet.AbsoluteFeeLimit,
+			Value: float64(fee),
+			Max:   float64(w.maxAbsoluteFee),
+		}
+	}
+	if w.maxFeePercent > 0 && amount > 0 {
+		percent := float64(fee) * 100 / float64(amount)
+		if percent > w.maxFeePercent {
+			return &wallet.FeeLimitError{
+				Limit: wallet.FeePercentLimit,
+				Value: percent,
+				Max:   w.maxFeePercent,
+			}
+		}
+	}
+	return nil
+}
 
-		coinSelector := coinset.MaxValueAgeCoinSelector{MaxInputs: 10000, MinChangeAmount: btcutil.Amount(0)}
-		coins, err := coinSelector.CoinSelect(target, coins)
-		if err != nil {
-			return total, inputs, []btcutil.Amount{}, scripts, wallet.ErrInsufficientFunds
+// subtractFeeFromOutputs splits fee equally between the outputs at indexes.
+// Any remainder is taken from the first of them.
+func (w *BtcElectrumWallet) subtractFeeFromOutputs(outputs []*wire.TxOut, indexes []int, fee int64) error {
+	share := fee / int64(len(indexes))
+	remainder := fee % int64(len(indexes))
+	for i, idx := range indexes {
+		sub := share
+		if i == 0 {
+			sub += remainder
 		}
-		prevScripts = make(map[wire.OutPoint]*wire.TxOut)
-		for _, c := range coins.Coins() {
-			total += c.Value()
-			outpoint := wire.NewOutPoint(c.Hash(), c.Index())
-			in := wire.NewTxIn(outpoint, []byte{}, [][]byte{})
-			in.Sequence = uint32(0xffffffff)
-			inputs = append(inputs, in)
-			prevScripts[*outpoint] = wire.NewTxOut(int64(c.Value()), c.PkScript())
+		outputs[idx].Value -= sub
+		if outputs[idx].Value <= 0 || w.IsDust(outputs[idx].Value) {
+			return wallet.ErrDustAmount
 		}
-		return total, inputs, []btcutil.Amount{}, scripts, nil
 	}
+	return nil
+}
+
+// dataOutputScript returns the OP_RETURN script of a data output
+func dataOutputScript(out wallet.TransactionOutput) ([]byte, error) {
+	if out.Address != nil || out.Value != 0 {
+		return nil, fmt.Errorf("%w: a data output has no address and zero value", wallet.ErrDataOutput)
+	}
+	if len(out.Data) > wallet.MaxDataCarrierSize {
+		return nil, fmt.Errorf("%w: %d bytes is over the %d byte limit", wallet.ErrDataOutput,
+			len(out.Data), wallet.MaxDataCarrierSize)
+	}
+	return txscript.NullDataScript(out.Data)
+}
 
-	// Get the fee per kilobyte
-	feePerKB := int64(w.GetFeePerByte(feeLevel)) * 1000
+// buildTx builds a Pay to (witness) pubkey hash transaction as described by
+// info.
+func (w *BtcElectrumWallet) buildTx(info *wallet.SpendInfo) (*wallet.SpendResult, error) {
+	if len(info.Outputs) == 0 {
+		return nil, wallet.ErrNoOutputs
+	}
+	for _, idx := range info.SubtractFeeFrom {
+		if idx < 0 || idx >= len(info.Outputs) || info.Outputs[idx].Data != nil {
+			return nil, wallet.ErrBadSubtractFeeIndex
+		}
+	}
 
 	// outputs
-	out := wire.NewTxOut(amount, script)
-
-	// create change source
-	changeSource := func() ([]byte, error) {
-		address, err := w.GetUnusedAddress(wallet.CHANGE)
-		if err != nil {
-			return []byte{}, err
+	outputs := make([]*wire.TxOut, 0, len(info.Outputs))
+	var payOutputs, dataOutputs int
+	// the output receiving a send-all
+	sendAllIndex := -1
+	for i, out := range info.Outputs {
+		if out.Data != nil {
+			dataOutputs++
+			if dataOutputs > 1 {
+				return nil, fmt.Errorf("%w: only one data output is relayed", wallet.ErrDataOutput)
+			}
+			script, err := dataOutputScript(out)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, wire.NewTxOut(0, script))
+			continue
 		}
-		script, err := txscript.PayToAddrScript(address)
+		payOutputs++
+		sendAllIndex = i
+		// Check for dust
+		if !info.SendAll && w.IsDust(out.Value) {
+			return nil, wallet.ErrDustAmount
+		}
+		// check payto address
+		script, err := txscript.PayToAddrScript(out.Address)
 		if err != nil {
-			return []byte{}, err
+			return nil, err
+		}
+		outputs = append(outputs, wire.NewTxOut(out.Value, script))
+	}
+	if info.SendAll && payOutputs != 1 {
+		return nil, wallet.ErrSendAllOutputs
+	}
+
+	// inputs with a set sequence must be spent
+	if len(info.Sequences) > 0 {
+		spendInfo := *info
+		spendInfo.MustUse = append(append([]wire.OutPoint{}, info.MustUse...), sequenceOutPoints(info)...)
+		info = &spendInfo
+	}
+	required, coins, err := w.spendableCoins(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the fee per vbyte
+	feeRate := info.FeeRate
+	if feeRate <= 0 {
+		feeRate = w.GetFeePerByte(info.FeeLevel)
+	}
+	if info.AbsoluteFee <= 0 && w.feeProvider.MaxFee > 0 && feeRate > w.feeProvider.MaxFee {
+		return nil, &wallet.FeeLimitError{
+			Limit: wallet.FeeRateLimit,
+			Value: float64(feeRate),
+			Max:   float64(w.feeProvider.MaxFee),
 		}
-		return script, nil
 	}
-	var scriptSize int = 33 // max size, over-estimated
-	changeOutputsSource := txauthor.ChangeSource{
-		NewScript:  changeSource,
-		ScriptSize: scriptSize,
+
+	var selected []wallet.Utxo
+	var change, fee, waste int64
+	if info.SendAll {
+		selected = append(required, coins...)
+		if len(selected) == 0 {
+			return nil, wallet.ErrInsufficientFunds
+		}
+		fee = info.AbsoluteFee
+		if fee <= 0 {
+			fee = feeRate * int64(estimateTxVSize(selected, outputs, 0))
+		}
+		outputs[sendAllIndex].Value = utxosValue(selected) - fee
+		if outputs[sendAllIndex].Value <= 0 || w.IsDust(outputs[sendAllIndex].Value) {
+			return nil, wallet.ErrInsufficientFunds
+		}
+	} else {
+		selector := info.CoinSelector
+		if selector == nil {
+			selector = w.coinSelector
+		}
+		if selector == nil {
+			selector = wallet.DefaultCoinSelector()
+		}
+		subtractFee := len(info.SubtractFeeFrom) > 0
+		selected, change, fee, waste, err = w.selectCoins(selector, required, coins, outputs, feeRate, info.AbsoluteFee, subtractFee)
+		if err != nil {
+			return nil, err
+		}
+		if subtractFee {
+			// any leftover not made into change already pays part of the fee
+			toSubtract := fee
+			if change == 0 {
+				var target int64
+				for _, out := range outputs {
+					target += out.Value
+				}
+				toSubtract -= utxosValue(selected) - target
+			}
+			if toSubtract > 0 {
+				err = w.subtractFeeFromOutputs(outputs, info.SubtractFeeFrom, toSubtract)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
 
-	outputs := []*wire.TxOut{out}
+	// fee guards
+	var amount int64
+	for _, out := range outputs {
+		amount += out.Value
+	}
+	changeScriptSize := 0
+	if change > 0 {
+		changeScriptSize = w.changeScriptSize()
+	}
+	err = w.checkFeeLimits(fee, estimateTxVSize(selected, outputs, changeScriptSize), amount)
+	if err != nil {
+		return nil, err
+	}
 
-	authoredTx, err := txauthor.NewUnsignedTransaction(
-		outputs,
-		btcutil.Amount(feePerKB),
-		inputSource,
-		&changeOutputsSource)
+	// timelocks
+	lockTime := w.lockTime(info)
+	sequences, version, err := w.inputSequences(info, selected, &lockTime)
 	if err != nil {
-		return -1, nil, err
+		return nil, err
+	}
+	var broadcastHeight int64
+	if isLockTimeHeight(lockTime) && int64(lockTime) > w.blockchainTip.Load()+1 {
+		broadcastHeight = int64(lockTime)
+	}
+
+	tx := wire.NewMsgTx(version)
+	tx.LockTime = lockTime
+	prevScripts := make(map[wire.OutPoint]*wire.TxOut)
+	for _, u := range selected {
+		outpoint := wire.NewOutPoint(&u.Op.Hash, u.Op.Index)
+		in := wire.NewTxIn(outpoint, []byte{}, [][]byte{})
+		in.Sequence = sequences[u.Op]
+		tx.AddTxIn(in)
+		prevScripts[*outpoint] = wire.NewTxOut(u.Value, u.ScriptPubkey)
+	}
+	for _, out := range outputs {
+		tx.AddTxOut(out)
+	}
+
+	// change output
+	var changeOut *wire.TxOut
+	if change > 0 {
+		address, err := w.GetUnusedAddress(wallet.CHANGE)
+		if err != nil {
+			return nil, err
+		}
+		if address == nil {
+			return nil, errors.New("no change address")
+		}
+		script, err := txscript.PayToAddrScript(address)
+		if err != nil {
+			return nil, err
+		}
+		changeOut = wire.NewTxOut(change, script)
+		tx.AddTxOut(changeOut)
 	}
 
 	// BIP 69 sorting
-	txsort.InPlaceSort(authoredTx.Tx)
+	txsort.InPlaceSort(tx)
 
-	b := make([]byte, 0, 300)
-	br := bytes.NewBuffer(b)
-	authoredTx.Tx.Serialize(br)
-	fmt.Println("unsigned tx:", hex.EncodeToString(br.Bytes()))
+	changeIndex := -1
+	for i, out := range tx.TxOut {
+		if out == changeOut {
+			changeIndex = i
+			break
+		}
+	}
 
 	// Sign
 	var prevPkScripts [][]byte
 	var inputValues []btcutil.Amount
-	for _, txIn := range authoredTx.Tx.TxIn {
+	for _, txIn := range tx.TxIn {
 		op := txIn.PreviousOutPoint
 		prevOut := prevScripts[op]
 		inputValues = append(inputValues, btcutil.Amount(prevOut.Value))
@@ -223,11 +618,22 @@ func (w *BtcElectrumWallet) buildTx(
 		txIn.SignatureScript = nil
 		txIn.Witness = nil
 	}
-	err = txauthor.AddAllInputScripts(authoredTx.Tx, prevPkScripts, inputValues, &secretSource{w})
+	err = txauthor.AddAllInputScripts(tx, prevPkScripts, inputValues, &secretSource{w})
 	if err != nil {
-		return -1, nil, err
+		return nil, err
 	}
-	return authoredTx.ChangeIndex, authoredTx.Tx, nil
+	inputs := make([]wire.OutPoint, 0, len(tx.TxIn))
+	for _, txIn := range tx.TxIn {
+		inputs = append(inputs, txIn.PreviousOutPoint)
+	}
+	return &wallet.SpendResult{
+		Tx:              tx,
+		ChangeIndex:     changeIndex,
+		Fee:             fee,
+		Inputs:          inputs,
+		Waste:           waste,
+		BroadcastHeight: broadcastHeight,
+	}, nil
 }
 
 func (w *BtcElectrumWallet) GetFeePerByte(feeLevel wallet.FeeLevel) int64 {
@@ -241,7 +647,12 @@ func (w *BtcElectrumWallet) EstimateFee(
 
 	tx := new(wire.MsgTx)
 	for _, out := range outs {
-		scriptPubKey, _ := txscript.PayToAddrScript(out.Address)
+		var scriptPubKey []byte
+		if out.Data != nil {
+			scriptPubKey, _ = txscript.NullDataScript(out.Data)
+		} else {
+			scriptPubKey, _ = txscript.PayToAddrScript(out.Address)
+		}
 		output := wire.NewTxOut(out.Value, scriptPubKey)
 		tx.TxOut = append(tx.TxOut, output)
 	}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/dev-warrior777/go-electrum-client/wallet"
@@ -16,6 +17,9 @@ const scriptDebug = false
 
 // Sign an unsigned transaction with the wallet
 func (w *BtcElectrumWallet) SignTx(pw string, info *wallet.SigningInfo) ([]byte, error) {
+	if w.watchOnly {
+		return nil, wallet.ErrWatchOnly
+	}
 	if ok := w.storageManager.IsValidPw(pw); !ok {
 		return nil, errors.New("invalid password")
 	}
@@ -33,13 +37,20 @@ func (w *BtcElectrumWallet) SignTx(pw string, info *wallet.SigningInfo) ([]byte,
 		return nil, err
 	}
 	tx := info.UnsignedTx
-	prevOutFetcher := new(txscript.CannedPrevOutputFetcher)
+	// taproot signatures commit to all of the previous outputs
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	utxos := make([]*wallet.Utxo, len(tx.TxIn))
 	for idx, input := range tx.TxIn {
 		op := input.PreviousOutPoint
 		utxo, valid := validConfirmedUtxo(op)
 		if !valid {
 			return nil, fmt.Errorf("outpoint %s is not valid (maybe not confirmed?)", op.String())
 		}
+		utxos[idx] = utxo
+		prevOutFetcher.AddPrevOut(op, wire.NewTxOut(utxo.Value, utxo.ScriptPubkey))
+	}
+	for idx, input := range tx.TxIn {
+		utxo := utxos[idx]
 		pkScript, err := txscript.ParsePkScript(utxo.ScriptPubkey)
 		if err != nil {
 			return nil, err
@@ -71,6 +82,33 @@ func (w *BtcElectrumWallet) SignTx(pw string, info *wallet.SigningInfo) ([]byte,
 			// add witness
 			input.SignatureScript = nil
 			input.Witness = append(input.Witness, sig...)
+		case txscript.ScriptHashTy:
+			// p2sh-p2wpkh is the only p2sh the wallet makes
+			pkHash := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+			redeemScript := p2wpkhWitnessProgram(pkHash)
+			if !bytes.Equal(btcutil.Hash160(redeemScript), address.ScriptAddress()) {
+				return nil, errors.New("signing P2SH other than P2SH-P2WPKH not supported")
+			}
+			sig, err := txscript.WitnessSignature(tx, sigHashes, idx, utxo.Value,
+				redeemScript, txscript.SigHashAll, privKey, true)
+			if err != nil {
+				return nil, err
+			}
+			sigScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+			if err != nil {
+				return nil, err
+			}
+			input.SignatureScript = sigScript
+			input.Witness = sig
+		case txscript.WitnessV1TaprootTy:
+			// BIP86 key path spend
+			sig, err := txscript.TaprootWitnessSignature(tx, sigHashes, idx, utxo.Value,
+				pkScript.Script(), txscript.SigHashDefault, privKey)
+			if err != nil {
+				return nil, err
+			}
+			input.SignatureScript = nil
+			input.Witness = sig
 		case txscript.PubKeyHashTy:
 			// note we do not really support P2PK for outbound txs
 			sig, err := txscript.SignatureScript(tx, idx,
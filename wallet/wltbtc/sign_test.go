@@ -111,7 +111,7 @@ func TestSignTx(t *testing.T) {
 		VerifyTx:   true,
 	}
 	w := MockWallet("abc")
-	w.blockchainTip = 500
+	w.blockchainTip.Store(500)
 	utxos := getUtxos()
 	for _, utxo := range utxos {
 		err := w.txstore.Utxos().Put(*utxo)
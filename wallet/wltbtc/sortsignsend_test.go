@@ -1,7 +1,9 @@
 package wltbtc
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -60,7 +62,7 @@ import (
 // test gather coins
 func Test_gatherCoins(t *testing.T) {
 	w := MockWallet("abc")
-	w.blockchainTip = 500
+	w.blockchainTip.Store(500)
 	// utxo 1
 	txid1 := "edfab2f9b2a013a36c524bf63e9778a5d13ca8bf1fce279647fbcee30bf7dd62"
 	h1, err := chainhash.NewHashFromStr(txid1)
@@ -122,7 +124,7 @@ func Test_gatherCoins(t *testing.T) {
 // The wallet is segwit by default. Here we test making a transaction from 2 inputs
 func Test_newSegwitMultiInputTransaction(t *testing.T) {
 	w := MockWallet(pw)
-	w.blockchainTip = 500
+	w.blockchainTip.Store(500)
 	// utxo 1
 	txid1 := "edfab2f9b2a013a36c524bf63e9778a5d13ca8bf1fce279647fbcee30bf7dd62"
 	h1, err := chainhash.NewHashFromStr(txid1)
@@ -182,7 +184,7 @@ func Test_newSegwitMultiInputTransaction(t *testing.T) {
 // default segwit transaction - 1 utxo consumed
 func Test_newSegwitTransaction(t *testing.T) {
 	w := MockWallet("abc")
-	w.blockchainTip = 500
+	w.blockchainTip.Store(500)
 
 	// A real Tx from harness->goele
 
@@ -246,7 +248,7 @@ func Test_newSegwitTransaction(t *testing.T) {
 // addresses. Hopefully never though!
 func Test_newLegacyTransaction(t *testing.T) {
 	w := MockWallet("abc")
-	w.blockchainTip = 500
+	w.blockchainTip.Store(500)
 
 	// make one utxo
 	txid := "50b636d971e7d4d918d92876d6d53a22ccc960e051f540108056ca4ad6ec080c"
@@ -302,3 +304,312 @@ func Test_newLegacyTransaction(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// put the two utxos used in Test_newSegwitMultiInputTransaction
+func putTestUtxos(t *testing.T, w *BtcElectrumWallet) []wire.OutPoint {
+	var ops []wire.OutPoint
+	coins := []struct {
+		txid   string
+		index  uint32
+		script string
+		value  int64
+	}{
+		{"edfab2f9b2a013a36c524bf63e9778a5d13ca8bf1fce279647fbcee30bf7dd62", 1, "0014b8da433782cd9d142f32f726926bcc8161beeaef", 17556690040},
+		{"5ebbbeafb0d23805c09c87a1442d58c3900b4ab643c23871893cad1f4f421c60", 0, "0014df0683535861d41af232009259b5d3811d4471a8", 53333300000},
+	}
+	for _, c := range coins {
+		h, err := chainhash.NewHashFromStr(c.txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		script, err := hex.DecodeString(c.script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		op := wire.OutPoint{Hash: *h, Index: c.index}
+		err = w.txstore.Utxos().Put(wallet.Utxo{Op: op, ScriptPubkey: script, AtHeight: 426, Value: c.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func Test_sendAllTransaction(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+	ops := putTestUtxos(t, w)
+
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &wallet.SpendInfo{
+		Outputs:  []wallet.TransactionOutput{{Address: address}},
+		FeeLevel: wallet.NORMAL,
+		SendAll:  true,
+	}
+	res, err := w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tx.TxIn) != 2 || len(res.Tx.TxOut) != 1 || res.ChangeIndex != -1 {
+		t.Fatalf("expected 2 inputs, 1 output and no change - got %d %d %d",
+			len(res.Tx.TxIn), len(res.Tx.TxOut), res.ChangeIndex)
+	}
+	if res.Tx.TxOut[0].Value+res.Fee != 17556690040+53333300000 {
+		t.Fatal("output value plus fee should be the total of all coins")
+	}
+
+	// send all of one chosen coin
+	info.Coins = ops[:1]
+	res, err = w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tx.TxIn) != 1 || res.Tx.TxIn[0].PreviousOutPoint != ops[0] {
+		t.Fatal("expected only the chosen coin to be spent")
+	}
+	if res.Tx.TxOut[0].Value+res.Fee != 17556690040 {
+		t.Fatal("output value plus fee should be the value of the chosen coin")
+	}
+
+	// two outputs is not a send all
+	info.Outputs = append(info.Outputs, wallet.TransactionOutput{Address: address})
+	_, err = w.SpendTx("abc", info)
+	if err != wallet.ErrSendAllOutputs {
+		t.Fatalf("expected %v - got %v", wallet.ErrSendAllOutputs, err)
+	}
+}
+
+func Test_subtractFeeTransaction(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+	putTestUtxos(t, w)
+
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := int64(10000000)
+	info := &wallet.SpendInfo{
+		Outputs:         []wallet.TransactionOutput{{Address: address, Value: amount}},
+		FeeLevel:        wallet.NORMAL,
+		SubtractFeeFrom: []int{0},
+	}
+	res, err := w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ChangeIndex < 0 {
+		t.Fatal("expected a change output")
+	}
+	for i, out := range res.Tx.TxOut {
+		if i == res.ChangeIndex {
+			continue
+		}
+		if out.Value != amount-res.Fee {
+			t.Fatalf("expected recipient to receive %d - got %d", amount-res.Fee, out.Value)
+		}
+	}
+
+	info.SubtractFeeFrom = []int{1}
+	_, err = w.SpendTx("abc", info)
+	if err != wallet.ErrBadSubtractFeeIndex {
+		t.Fatalf("expected %v - got %v", wallet.ErrBadSubtractFeeIndex, err)
+	}
+}
+
+func Test_coinControlTransaction(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+	ops := putTestUtxos(t, w)
+
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the smaller coin alone would pay this but we must use the larger
+	info := &wallet.SpendInfo{
+		Outputs:  []wallet.TransactionOutput{{Address: address, Value: 10000000}},
+		FeeLevel: wallet.NORMAL,
+		MustUse:  ops[1:],
+	}
+	res, err := w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Inputs) != 1 || res.Inputs[0] != ops[1] {
+		t.Fatalf("expected only must use input %s - got %v", ops[1], res.Inputs)
+	}
+
+	// may use only the smaller coin which cannot pay this
+	info = &wallet.SpendInfo{
+		Outputs:  []wallet.TransactionOutput{{Address: address, Value: 20000000000}},
+		FeeLevel: wallet.NORMAL,
+		Coins:    ops[:1],
+	}
+	_, err = w.SpendTx("abc", info)
+	if err != wallet.ErrInsufficientFunds {
+		t.Fatalf("expected %v - got %v", wallet.ErrInsufficientFunds, err)
+	}
+
+	// unknown coin
+	unknown := wire.OutPoint{Hash: ops[0].Hash, Index: 7}
+	info.Coins = []wire.OutPoint{unknown}
+	_, err = w.SpendTx("abc", info)
+	if !errors.Is(err, wallet.ErrUnknownCoin) {
+		t.Fatalf("expected %v - got %v", wallet.ErrUnknownCoin, err)
+	}
+
+	// frozen coin
+	err = w.FreezeUTXO(&ops[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.Coins = nil
+	info.MustUse = ops[:1]
+	_, err = w.SpendTx("abc", info)
+	if !errors.Is(err, wallet.ErrFrozenCoin) {
+		t.Fatalf("expected %v - got %v", wallet.ErrFrozenCoin, err)
+	}
+}
+
+func Test_explicitFeeTransaction(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+	putTestUtxos(t, w)
+
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &wallet.SpendInfo{
+		Outputs: []wallet.TransactionOutput{{Address: address, Value: 10000000}},
+		FeeRate: 7,
+	}
+	res, err := w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Fee%7 != 0 {
+		t.Fatalf("expected a fee at 7 sats/vbyte - got %d", res.Fee)
+	}
+
+	info.FeeRate = 0
+	info.AbsoluteFee = 1234
+	res, err = w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Fee != 1234 {
+		t.Fatalf("expected a fee of 1234 - got %d", res.Fee)
+	}
+
+	// guards
+	var limitErr *wallet.FeeLimitError
+	w.maxAbsoluteFee = 1000
+	_, err = w.SpendTx("abc", info)
+	if !errors.As(err, &limitErr) || limitErr.Limit != wallet.AbsoluteFeeLimit {
+		t.Fatalf("expected an absolute fee limit error - got %v", err)
+	}
+	w.maxAbsoluteFee = 0
+	w.maxFeePercent = 0.01
+	_, err = w.SpendTx("abc", info)
+	if !errors.As(err, &limitErr) || limitErr.Limit != wallet.FeePercentLimit {
+		t.Fatalf("expected a fee percent limit error - got %v", err)
+	}
+	w.maxFeePercent = 0
+	info.AbsoluteFee = 0
+	info.FeeRate = w.feeProvider.MaxFee + 1
+	_, err = w.SpendTx("abc", info)
+	if !errors.As(err, &limitErr) || limitErr.Limit != wallet.FeeRateLimit {
+		t.Fatalf("expected a fee rate limit error - got %v", err)
+	}
+}
+
+func Test_dataOutputTransaction(t *testing.T) {
+	w := MockWallet("abc")
+	w.blockchainTip.Store(500)
+	putTestUtxos(t, w)
+
+	address, err := btcutil.DecodeAddress("bcrt1qqfepzsehqytlfvm3gmmx3zrz3yhjw2nm3yuccd", &chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("settlement record 42")
+	dataScript, err := txscript.NullDataScript(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasDataOutput := func(tx *wire.MsgTx) bool {
+		for _, out := range tx.TxOut {
+			if out.Value == 0 && bytes.Equal(out.PkScript, dataScript) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// a timestamp needs no payment
+	info := &wallet.SpendInfo{
+		Outputs:  []wallet.TransactionOutput{{Data: data}},
+		FeeLevel: wallet.NORMAL,
+	}
+	res, err := w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tx.TxOut) != 2 || !hasDataOutput(res.Tx) || res.ChangeIndex < 0 {
+		t.Fatal("expected a data output and change")
+	}
+
+	// with a send-all
+	info = &wallet.SpendInfo{
+		Outputs:  []wallet.TransactionOutput{{Data: data}, {Address: address}},
+		FeeLevel: wallet.NORMAL,
+		SendAll:  true,
+	}
+	res, err = w.SpendTx("abc", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tx.TxOut) != 2 || !hasDataOutput(res.Tx) {
+		t.Fatal("expected a data output and the send-all output")
+	}
+
+	bad := []struct {
+		outputs []wallet.TransactionOutput
+		err     error
+	}{
+		{[]wallet.TransactionOutput{{Data: data, Value: 1000}}, wallet.ErrDataOutput},
+		{[]wallet.TransactionOutput{{Data: data, Address: address}}, wallet.ErrDataOutput},
+		{[]wallet.TransactionOutput{{Data: make([]byte, wallet.MaxDataCarrierSize+1)}}, wallet.ErrDataOutput},
+		{[]wallet.TransactionOutput{{Data: data}, {Data: data}}, wallet.ErrDataOutput},
+	}
+	for i, test := range bad {
+		info := &wallet.SpendInfo{Outputs: test.outputs, FeeLevel: wallet.NORMAL}
+		_, err := w.SpendTx("abc", info)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%d: expected %v - got %v", i, test.err, err)
+		}
+	}
+	info = &wallet.SpendInfo{
+		Outputs:         []wallet.TransactionOutput{{Data: data}, {Address: address, Value: 10000000}},
+		FeeLevel:        wallet.NORMAL,
+		SubtractFeeFrom: []int{0},
+	}
+	_, err = w.SpendTx("abc", info)
+	if err != wallet.ErrBadSubtractFeeIndex {
+		t.Fatalf("expected %v - got %v", wallet.ErrBadSubtractFeeIndex, err)
+	}
+
+	// the data is paid for
+	pay := []wallet.TransactionOutput{{Address: address, Value: 10000000}}
+	fee := w.EstimateFee(make([]wallet.InputInfo, 1), pay, 10)
+	feeWithData := w.EstimateFee(make([]wallet.InputInfo, 1), append(pay, wallet.TransactionOutput{Data: data}), 10)
+	if feeWithData-fee != int64(10*wire.NewTxOut(0, dataScript).SerializeSize()) {
+		t.Fatalf("expected the data output in the fee estimate - got %d and %d", fee, feeWithData)
+	}
+}
@@ -19,6 +19,16 @@ type Storage struct {
 	Xpub    string `json:"xpub"`
 	ShaPw   []byte `json:"shapw"`
 	Seed    []byte `json:"seed,omitempty"`
+	// BIP39 passphrase, stored with the seed if StoreEncSeed
+	Passphrase string `json:"passphrase,omitempty"`
+	// Receive & change descriptors of a wallet imported from descriptors.
+	// Xprv is empty for these wallets.
+	Descriptors []string `json:"descriptors,omitempty"`
+	// Electrum seed type of a wallet made from an Electrum seed. Empty for
+	// BIP39 wallets.
+	SeedType wallet.ElectrumSeedType `json:"seedtype,omitempty"`
+	// Accounts added by account discovery
+	Accounts []wallet.Account `json:"accounts,omitempty"`
 }
 
 // String returns the string representation of the Storage but only of the
@@ -41,6 +51,14 @@ func (s *Storage) blank() {
 	s.Xpub = ""
 	zero(s.ShaPw)
 	zero(s.Seed)
+	s.Passphrase = ""
+	// descriptors may hold private keys
+	for i := range s.Descriptors {
+		s.Descriptors[i] = ""
+	}
+	s.Descriptors = nil
+	s.SeedType = ""
+	s.Accounts = nil
 	// runtime.GC()
 }
 
@@ -91,6 +109,34 @@ func (sm *StorageManager) Get(pw string) error {
 	return json.Unmarshal(b, sm.store)
 }
 
+// ChangePassword re-encrypts the stored secrets with newPw. The in memory
+// store is only updated once the datastore has committed the change.
+func (sm *StorageManager) ChangePassword(oldPw, newPw string) error {
+	if len(newPw) == 0 {
+		return errors.New("no password")
+	}
+	if !sm.IsValidPw(oldPw) {
+		return errors.New("invalid password")
+	}
+	store := *sm.store
+	store.ShaPw = chainhash.HashB([]byte(newPw))
+	b, err := json.Marshal(&store)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for i := range b {
+			b[i] = 0
+		}
+	}()
+	err = sm.datastore.ChangePassword(b, oldPw, newPw)
+	if err != nil {
+		return err
+	}
+	sm.store.ShaPw = store.ShaPw
+	return nil
+}
+
 func (sm *StorageManager) IsValidPw(pw string) bool {
 	if len(pw) == 0 {
 		return false
@@ -7,6 +7,7 @@ import (
 
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/dev-warrior777/go-electrum-client/wallet"
 )
 
 func createStorageManager() *StorageManager {
@@ -102,3 +103,66 @@ func TestValidPw(t *testing.T) {
 	// }
 	fmt.Println("valid pw")
 }
+
+func TestChangePassword(t *testing.T) {
+	sm := createStorageManager()
+	populateStorage(sm)
+	err := sm.Put(pw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := sm.store.String()
+
+	err = sm.ChangePassword("wrong", "def")
+	if err == nil {
+		t.Fatal("changed password with the wrong old password")
+	}
+	err = sm.ChangePassword(pw, "def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.IsValidPw(pw) || !sm.IsValidPw("def") {
+		t.Fatal("pw check not updated")
+	}
+
+	sm.store.blank()
+	err = sm.Get(pw)
+	if err == nil {
+		t.Fatal("old password still opens storage")
+	}
+	err = sm.Get("def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.store.String() != before {
+		t.Fatal("Storage before != Storage after")
+	}
+	if !sm.IsValidPw("def") {
+		t.Fatal("stored pw check not updated")
+	}
+}
+
+func TestBlankStorage(t *testing.T) {
+	s := &Storage{
+		Version:     "0.1",
+		Xprv:        xprv,
+		Xpub:        xpub,
+		ShaPw:       chainhash.HashB([]byte(pw)),
+		Seed:        []byte{0x01, 0x02, 0x03},
+		Passphrase:  "secret",
+		Descriptors: []string{"wpkh(" + xprv + "/84h/1h/0h/0/*)"},
+		SeedType:    wallet.ElectrumSeedSegwit,
+		Accounts:    []wallet.Account{{Purpose: 84, LastReceive: 3}},
+	}
+	descriptors := s.Descriptors
+	s.blank()
+	if s.Xprv != "" || s.Xpub != "" || s.Passphrase != "" || s.SeedType != "" {
+		t.Fatalf("expected blank keys - got %+v", s)
+	}
+	if len(s.Descriptors) != 0 || descriptors[0] != "" || len(s.Accounts) != 0 {
+		t.Fatalf("expected blank descriptors and accounts - got %+v", s)
+	}
+	if !bytes.Equal(s.Seed, make([]byte, 3)) || !bytes.Equal(s.ShaPw, make([]byte, 32)) {
+		t.Fatal("expected zeroed seed and password hash")
+	}
+}
@@ -27,18 +27,27 @@ type TxStore struct {
 
 	params *chaincfg.Params
 
+	// wallet events
+	events *wallet.EventFeed
+	// unconfirmed conflicts notified
+	conflictsMutex *sync.Mutex
+	conflicts      map[[2]chainhash.Hash]bool
+
 	wallet.Datastore
 }
 
 func NewTxStore(params *chaincfg.Params, db wallet.Datastore, keyManager *KeyManager) (*TxStore, error) {
 	txs := &TxStore{
-		params:     params,
-		keyManager: keyManager,
-		addrMutex:  new(sync.Mutex),
-		cbMutex:    new(sync.Mutex),
-		txidsMutex: new(sync.RWMutex),
-		txids:      make(map[string]int64),
-		Datastore:  db,
+		params:         params,
+		keyManager:     keyManager,
+		addrMutex:      new(sync.Mutex),
+		cbMutex:        new(sync.Mutex),
+		txidsMutex:     new(sync.RWMutex),
+		txids:          make(map[string]int64),
+		events:         wallet.NewEventFeed(),
+		conflictsMutex: new(sync.Mutex),
+		conflicts:      make(map[[2]chainhash.Hash]bool),
+		Datastore:      db,
 	}
 	txs.PopulateAdrs()
 	return txs, nil
@@ -52,19 +61,15 @@ func (ts *TxStore) PopulateAdrs() {
 	ts.addrMutex.Lock()
 	ts.adrs = []btcutil.Address{}
 	for _, k := range keys {
-		address, err := k.Address(ts.params)
+		address, err := ts.keyManager.Address(k)
+		k.Zero()
 		if err != nil {
+			fmt.Println(err)
 			continue
 		}
-		script := address.ScriptAddress()
-		segwitAddress, swerr := btcutil.NewAddressWitnessPubKeyHash(script, ts.params)
-		if swerr != nil {
-			fmt.Println(swerr)
-			continue
-		}
-		ts.adrs = append(ts.adrs, segwitAddress)
-		k.Zero()
+		ts.adrs = append(ts.adrs, address)
 	}
+	ts.adrs = append(ts.adrs, ts.keyManager.AccountAddresses()...)
 	ts.addrMutex.Unlock()
 
 	txns, _ := ts.Txns().GetAll(true)
@@ -102,11 +107,17 @@ func (ts *TxStore) AddTransaction(tx *wire.MsgTx, height int64, timestamp time.T
 	if len(doubleSpends) > 0 {
 		// First seen rule
 		if height == 0 {
+			for _, double := range doubleSpends {
+				ts.notifyConflict(*double, tx.TxHash(), height)
+			}
 			return 0, nil
 		} else {
-			// mark any unconfirmed doubles as dead
+			// mark any unconfirmed doubles as dead, replaced by this tx
 			for _, double := range doubleSpends {
-				ts.markAsDead(*double)
+				err = ts.replaceTransaction(*double, tx.TxHash(), height)
+				if err != nil {
+					return hits, err
+				}
 			}
 		}
 	}
@@ -144,6 +155,10 @@ func (ts *TxStore) AddTransaction(tx *wire.MsgTx, height int64, timestamp time.T
 					Op:           newop,
 					WatchOnly:    false,
 				}
+				if !ok {
+					// labelled spendable=false before it arrived
+					newu.Frozen = ts.labelledUnspendable(newop)
+				}
 				value += newu.Value
 				ts.Utxos().Put(newu)
 				hits++
@@ -239,11 +254,7 @@ func (ts *TxStore) markAsDead(txid chainhash.Hash) error {
 		if err != nil {
 			return err
 		}
-		err = ts.Txns().UpdateHeight(s.SpendTxid.String(), -1, time.Now())
-		if err != nil {
-			return err
-		}
-		return nil
+		return ts.markTxnDead(s.SpendTxid.String())
 	}
 	for _, s := range stxos {
 		// if an stxo is marked dead, move it back into the utxo table/bucket
@@ -278,7 +289,71 @@ func (ts *TxStore) markAsDead(txid chainhash.Hash) error {
 			}
 		}
 	}
-	ts.Txns().UpdateHeight(txid.String(), -1, time.Now())
+	ts.markTxnDead(txid.String())
+	ts.publish(&wallet.WalletEvent{
+		Type:   wallet.EventTxDropped,
+		Txid:   txid.String(),
+		Height: -1,
+	})
+	return nil
+}
+
+// markTxnDead sets the height of a stored tx to -1 keeping its first seen time
+func (ts *TxStore) markTxnDead(txid string) error {
+	txn, err := ts.Txns().Get(txid)
+	if err != nil {
+		return err
+	}
+	return ts.Txns().UpdateHeight(txid, -1, txn.Timestamp)
+}
+
+// reorgTransaction moves a mined tx and the wallet coins it makes and spends
+// to height: another block or 0 for the mempool.
+func (ts *TxStore) reorgTransaction(tx *wire.MsgTx, height int64) error {
+	txid := tx.TxHash()
+	utxos, err := ts.Utxos().GetAll()
+	if err != nil {
+		return err
+	}
+	for _, u := range utxos {
+		if u.Op.Hash.IsEqual(&txid) {
+			u.AtHeight = height
+			if err := ts.Utxos().Put(u); err != nil {
+				return err
+			}
+		}
+	}
+	stxos, err := ts.Stxos().GetAll()
+	if err != nil {
+		return err
+	}
+	for _, s := range stxos {
+		changed := false
+		if s.Utxo.Op.Hash.IsEqual(&txid) {
+			s.Utxo.AtHeight = height
+			changed = true
+		}
+		if s.SpendTxid.IsEqual(&txid) {
+			s.SpendHeight = height
+			changed = true
+		}
+		if changed {
+			if err := ts.Stxos().Put(s); err != nil {
+				return err
+			}
+		}
+	}
+	txn, err := ts.Txns().Get(txid.String())
+	if err != nil {
+		return err
+	}
+	err = ts.Txns().UpdateHeight(txid.String(), int(height), txn.Timestamp)
+	if err != nil {
+		return err
+	}
+	ts.txidsMutex.Lock()
+	ts.txids[txid.String()] = height
+	ts.txidsMutex.Unlock()
 	return nil
 }
 
@@ -292,6 +367,7 @@ func (ts *TxStore) CheckDoubleSpends(argTx *wire.MsgTx) ([]*chainhash.Hash, erro
 	if err != nil {
 		return dubs, err
 	}
+nextTx:
 	for _, compTx := range txs {
 		if compTx.Height < 0 {
 			continue
@@ -307,7 +383,7 @@ func (ts *TxStore) CheckDoubleSpends(argTx *wire.MsgTx) ([]*chainhash.Hash, erro
 				if outPointsEqual(argIn.PreviousOutPoint, compIn.PreviousOutPoint) && !compTxid.IsEqual(&argTxid) {
 					// found double spend
 					dubs = append(dubs, &compTxid)
-					break // back to argIn loop
+					continue nextTx
 				}
 			}
 		}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/btcsuite/btcd/btcutil"
@@ -34,6 +35,14 @@ type BtcElectrumWallet struct {
 
 	feeProvider *wallet.FeeProvider
 
+	coinSelector wallet.CoinSelector
+
+	maxAbsoluteFee int64
+	maxFeePercent  float64
+
+	// imported from public descriptors with no private keys
+	watchOnly bool
+
 	repoPath string
 
 	storageManager      *StorageManager
@@ -43,17 +52,28 @@ type BtcElectrumWallet struct {
 
 	mutex *sync.RWMutex
 
-	creationDate time.Time
+	creationDate   time.Time
+	birthdayHeight int64
 
-	blockchainSynced bool
-	blockchainTip    int64
+	blockchainSynced atomic.Bool
+	blockchainTip    atomic.Int64
+
+	// confirmations notified by tx confirmed events
+	eventConfirmations int
+	// balance last notified
+	balanceMutex sync.Mutex
+	balance      *[3]int64
+	// unconfirmed txs marked stuck by the client
+	stuckMutex sync.Mutex
+	stuck      map[string]bool
 
 	running bool
 }
 
 // NewBtcElectrumWallet mskes new wallet with a new seed. The Mnemonic should
-// be saved offline by the user.
-func NewBtcElectrumWallet(config *wallet.WalletConfig, pw string) (*BtcElectrumWallet, error) {
+// be saved offline by the user. passphrase is an optional BIP39 passphrase
+// which is needed with the mnemonic to recreate the wallet.
+func NewBtcElectrumWallet(config *wallet.WalletConfig, pw, passphrase string) (*BtcElectrumWallet, error) {
 	if pw == "" {
 		return nil, ErrEmptyPassword
 	}
@@ -69,26 +89,71 @@ func NewBtcElectrumWallet(config *wallet.WalletConfig, pw string) (*BtcElectrumW
 	// TODO: dbg remove
 	fmt.Println("Save: ", mnemonic)
 
-	seed := bip39.NewSeed(mnemonic, "")
+	seed := bip39.NewSeed(mnemonic, passphrase)
 
-	return makeBtcElectrumWallet(config, pw, seed)
+	return makeBtcElectrumWallet(config, pw, seed, passphrase, "", time.Now())
 }
 
 // RecreateElectrumWallet makes new wallet with a mnenomic seed from an existing wallet.
-// pw does not need to be the same as the old wallet
-func RecreateElectrumWallet(config *wallet.WalletConfig, pw, mnemonic string) (*BtcElectrumWallet, error) {
+// pw does not need to be the same as the old wallet. passphrase is the BIP39
+// passphrase of the old wallet if it had one. A wrong passphrase makes a
+// different, empty, wallet; check MasterFingerprint to confirm it.
+func RecreateElectrumWallet(config *wallet.WalletConfig, pw, mnemonic, passphrase string) (*BtcElectrumWallet, error) {
 	if pw == "" {
 		return nil, ErrEmptyPassword
 	}
-	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
 	if err != nil {
 		return nil, err
 	}
 
-	return makeBtcElectrumWallet(config, pw, seed)
+	return makeBtcElectrumWallet(config, pw, seed, passphrase, "", config.Birthday)
 }
 
-func makeBtcElectrumWallet(config *wallet.WalletConfig, pw string, seed []byte) (*BtcElectrumWallet, error) {
+// NewElectrumSeedWallet makes a new wallet with a new Electrum seed of
+// seedType. The returned mnemonic should be saved offline by the user.
+func NewElectrumSeedWallet(config *wallet.WalletConfig, pw string, seedType wallet.ElectrumSeedType) (*BtcElectrumWallet, string, error) {
+	if pw == "" {
+		return nil, "", ErrEmptyPassword
+	}
+	mnemonic, err := wallet.NewElectrumMnemonic(seedType)
+	if err != nil {
+		return nil, "", err
+	}
+	seed, _, err := wallet.ElectrumSeed(mnemonic, "")
+	if err != nil {
+		return nil, "", err
+	}
+	w, err := makeBtcElectrumWallet(config, pw, seed, "", seedType, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	return w, mnemonic, nil
+}
+
+// RecreateElectrumSeedWallet makes a new wallet from the mnemonic of an
+// existing Electrum standard or segwit wallet.
+func RecreateElectrumSeedWallet(config *wallet.WalletConfig, pw, mnemonic string) (*BtcElectrumWallet, error) {
+	if pw == "" {
+		return nil, ErrEmptyPassword
+	}
+	seed, seedType, err := wallet.ElectrumSeed(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	return makeBtcElectrumWallet(config, pw, seed, "", seedType, config.Birthday)
+}
+
+// newSeedKeyManager makes the key manager of a wallet with a master key. An
+// empty seedType is a BIP39 seed with BIP44 derivation.
+func newSeedKeyManager(db wallet.Keys, params *chaincfg.Params, masterPrivKey *hdkeychain.ExtendedKey, seedType wallet.ElectrumSeedType) (*KeyManager, error) {
+	if seedType == "" {
+		return NewKeyManager(db, params, masterPrivKey)
+	}
+	return NewElectrumSeedKeyManager(db, params, masterPrivKey, seedType)
+}
+
+func makeBtcElectrumWallet(config *wallet.WalletConfig, pw string, seed []byte, passphrase string, seedType wallet.ElectrumSeedType, birthday time.Time) (*BtcElectrumWallet, error) {
 
 	// dbg
 	fmt.Println("seed: ", hex.EncodeToString(seed))
@@ -102,11 +167,16 @@ func makeBtcElectrumWallet(config *wallet.WalletConfig, pw string, seed []byte)
 		return nil, err
 	}
 	w := &BtcElectrumWallet{
-		repoPath:     config.DataDir,
-		params:       config.Params,
-		creationDate: time.Now(),
-		feeProvider:  wallet.DefaultFeeProvider(),
-		mutex:        new(sync.RWMutex),
+		repoPath:           config.DataDir,
+		params:             config.Params,
+		creationDate:       birthday,
+		birthdayHeight:     config.BirthdayHeight,
+		feeProvider:        wallet.ConfigFeeProvider(config),
+		coinSelector:       config.CoinSelector,
+		maxAbsoluteFee:     config.MaxAbsoluteFee,
+		maxFeePercent:      config.MaxFeePercent,
+		eventConfirmations: config.EventConfirmations,
+		mutex:              new(sync.RWMutex),
 	}
 
 	sm := NewStorageManager(config.DB.Enc(), config.Params)
@@ -114,8 +184,10 @@ func makeBtcElectrumWallet(config *wallet.WalletConfig, pw string, seed []byte)
 	sm.store.Xprv = mPrivKey.String()
 	sm.store.Xpub = mPubKey.String()
 	sm.store.ShaPw = chainhash.HashB([]byte(pw))
+	sm.store.SeedType = seedType
 	if config.StoreEncSeed {
 		sm.store.Seed = bytes.Clone(seed)
+		sm.store.Passphrase = passphrase
 	}
 	err = sm.Put(pw)
 	if err != nil {
@@ -123,12 +195,16 @@ func makeBtcElectrumWallet(config *wallet.WalletConfig, pw string, seed []byte)
 	}
 	w.storageManager = sm
 
-	w.keyManager, err = NewKeyManager(config.DB.Keys(), w.params, mPrivKey)
+	w.keyManager, err = newSeedKeyManager(config.DB.Keys(), w.params, mPrivKey, seedType)
 	mPrivKey.Zero()
 	mPubKey.Zero()
 	if err != nil {
 		return nil, err
 	}
+	err = w.setGapLimit(config)
+	if err != nil {
+		return nil, err
+	}
 
 	w.txstore, err = NewTxStore(w.params, config.DB, w.keyManager)
 	if err != nil {
@@ -137,7 +213,7 @@ func makeBtcElectrumWallet(config *wallet.WalletConfig, pw string, seed []byte)
 
 	w.subscriptionManager = NewSubscriptionManager(config.DB.Subscriptions(), w.params)
 
-	err = config.DB.Cfg().PutCreationDate(w.creationDate)
+	err = w.putBirthday(config.DB.Cfg())
 	if err != nil {
 		return nil, err
 	}
@@ -171,21 +247,45 @@ func loadBtcElectrumWallet(config *wallet.WalletConfig, pw string) (*BtcElectrum
 		return nil, err
 	}
 
-	mPrivKey, err := hdkeychain.NewKeyFromString(sm.store.Xprv)
-	if err != nil {
-		return nil, err
-	}
-
 	w := &BtcElectrumWallet{
-		repoPath:       config.DataDir,
-		storageManager: sm,
-		params:         config.Params,
-		feeProvider:    wallet.DefaultFeeProvider(),
-		mutex:          new(sync.RWMutex),
+		repoPath:           config.DataDir,
+		storageManager:     sm,
+		params:             config.Params,
+		feeProvider:        wallet.ConfigFeeProvider(config),
+		coinSelector:       config.CoinSelector,
+		maxAbsoluteFee:     config.MaxAbsoluteFee,
+		maxFeePercent:      config.MaxFeePercent,
+		eventConfirmations: config.EventConfirmations,
+		mutex:              new(sync.RWMutex),
+	}
+
+	if len(sm.store.Descriptors) == 2 {
+		// imported from descriptors
+		receive, change, err := parseWalletDescriptors(sm.store.Descriptors[0], sm.store.Descriptors[1], w.params)
+		if err != nil {
+			return nil, err
+		}
+		w.watchOnly = !receive.IsPrivate()
+		w.keyManager, err = newDescriptorKeyManager(config.DB.Keys(), w.params, receive, change)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		mPrivKey, err := hdkeychain.NewKeyFromString(sm.store.Xprv)
+		if err != nil {
+			return nil, err
+		}
+		w.keyManager, err = newSeedKeyManager(config.DB.Keys(), w.params, mPrivKey, sm.store.SeedType)
+		mPrivKey.Zero()
+		if err != nil {
+			return nil, err
+		}
+		err = w.loadAccounts()
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	w.keyManager, err = NewKeyManager(config.DB.Keys(), w.params, mPrivKey)
-	mPrivKey.Zero()
+	err = w.setGapLimit(config)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +297,7 @@ func loadBtcElectrumWallet(config *wallet.WalletConfig, pw string) (*BtcElectrum
 
 	w.subscriptionManager = NewSubscriptionManager(config.DB.Subscriptions(), w.params)
 
-	w.creationDate, err = config.DB.Cfg().GetCreationDate()
+	err = w.getBirthday(config.DB.Cfg())
 	if err != nil {
 		return nil, err
 	}
@@ -257,17 +357,12 @@ func (w *BtcElectrumWallet) GetAddress(kp *wallet.KeyPath /*, addressType*/) (bt
 	if err != nil {
 		return nil, err
 	}
-	address, err := key.Address(w.params)
+	address, err := w.keyManager.Address(key)
 	key.Zero()
 	if err != nil {
 		return nil, err
 	}
-	script := address.ScriptAddress()
-	segwitAddress, err := btcutil.NewAddressWitnessPubKeyHash(script, w.params)
-	if err != nil {
-		return nil, err
-	}
-	return segwitAddress, nil
+	return address, nil
 }
 
 func (w *BtcElectrumWallet) GetUnusedAddress(purpose wallet.KeyPurpose) (btcutil.Address, error) {
@@ -275,17 +370,12 @@ func (w *BtcElectrumWallet) GetUnusedAddress(purpose wallet.KeyPurpose) (btcutil
 	if err != nil {
 		return nil, nil
 	}
-	address, err := key.Address(w.params)
+	address, err := w.keyManager.Address(key)
 	key.Zero()
 	if err != nil {
-		return nil, nil
-	}
-	script := address.ScriptAddress()
-	segwitAddress, swerr := btcutil.NewAddressWitnessPubKeyHash(script, w.params)
-	if swerr != nil {
-		return nil, swerr
+		return nil, err
 	}
-	return segwitAddress, nil
+	return address, nil
 }
 
 // For receiving simple payments from legacy wallets only!
@@ -302,7 +392,19 @@ func (w *BtcElectrumWallet) GetUnusedLegacyAddress() (btcutil.Address, error) {
 	return addrP2PKH, nil
 }
 
+// ChangePassword changes the wallet password. The stored secrets are
+// re-encrypted with the new password and verified before being committed.
+func (w *BtcElectrumWallet) ChangePassword(oldPw, newPw string) error {
+	if newPw == "" {
+		return ErrEmptyPassword
+	}
+	return w.storageManager.ChangePassword(oldPw, newPw)
+}
+
 func (w *BtcElectrumWallet) GetPrivKeyForAddress(pw string, address btcutil.Address) (string, error) {
+	if w.watchOnly {
+		return "", wallet.ErrWatchOnly
+	}
 	if ok := w.storageManager.IsValidPw(pw); !ok {
 		return "", errors.New("invalid password")
 	}
@@ -321,9 +423,15 @@ func (w *BtcElectrumWallet) GetPrivKeyForAddress(pw string, address btcutil.Addr
 	return wif.String(), nil
 }
 
-// Marks the address as used (involved in at least one transaction)
+// Marks the address as used (involved in at least one transaction) and
+// extends the lookahead window past it
 func (w *BtcElectrumWallet) MarkAddressUsed(address btcutil.Address) error {
-	return w.txstore.Keys().MarkKeyAsUsed(address.ScriptAddress())
+	err := w.keyManager.MarkKeyAsUsed(address.ScriptAddress())
+	if err != nil {
+		return err
+	}
+	w.txstore.PopulateAdrs()
+	return nil
 }
 
 func (w *BtcElectrumWallet) DecodeAddress(addr string) (btcutil.Address, error) {
@@ -374,12 +482,13 @@ func (w *BtcElectrumWallet) ListAddresses() []btcutil.Address {
 	keys := w.keyManager.GetKeys()
 	addresses := []btcutil.Address{}
 	for _, k := range keys {
-		address, err := k.Address(w.params)
+		address, err := w.keyManager.Address(k)
 		if err != nil {
 			continue
 		}
 		addresses = append(addresses, address)
 	}
+	addresses = append(addresses, w.keyManager.AccountAddresses()...)
 	return addresses
 }
 
@@ -445,7 +554,14 @@ func (w *BtcElectrumWallet) Balance() (int64, int64, int64, error) {
 }
 
 func (w *BtcElectrumWallet) ListTransactions() ([]wallet.Txn, error) {
-	return w.txstore.Txns().GetAll(false)
+	txns, err := w.txstore.Txns().GetAll(false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range txns {
+		w.setTxnStatus(&txns[i])
+	}
+	return txns, nil
 }
 
 func (w *BtcElectrumWallet) HasTransaction(txid string) (bool, *wallet.Txn) {
@@ -462,6 +578,7 @@ func (w *BtcElectrumWallet) GetTransaction(txid string) (*wallet.Txn, error) {
 	if err != nil {
 		return nil, fmt.Errorf("no such transaction")
 	}
+	w.setTxnStatus(&txn)
 	return &txn, err
 }
 
@@ -475,13 +592,27 @@ func (w *BtcElectrumWallet) GetWalletAddressHistory(address btcutil.Address) ([]
 
 // Add a transaction to the database
 func (w *BtcElectrumWallet) AddTransaction(tx *wire.MsgTx, height int64, timestamp time.Time) error {
+	txid := tx.TxHash().String()
+	had, before := w.HasTransaction(txid)
+	if had && before.Height > 0 && height >= 0 && height != before.Height {
+		return w.reorgTransaction(tx, before, height)
+	}
 	_, err := w.txstore.AddTransaction(tx, height, timestamp)
-	return err
+	if err != nil {
+		return err
+	}
+	w.notifyTransaction(txid, before)
+	return nil
 }
 
 // List all unspent outputs in the wallet
 func (w *BtcElectrumWallet) ListUnspent() ([]wallet.Utxo, error) {
-	return w.txstore.Utxos().GetAll()
+	utxos, err := w.txstore.Utxos().GetAll()
+	if err != nil {
+		return nil, err
+	}
+	w.labelUtxos(utxos)
+	return utxos, nil
 }
 
 func (w *BtcElectrumWallet) ListConfirmedUnspent() ([]wallet.Utxo, error) {
@@ -495,6 +626,7 @@ func (w *BtcElectrumWallet) ListConfirmedUnspent() ([]wallet.Utxo, error) {
 			confirmed = append(confirmed, utxo)
 		}
 	}
+	w.labelUtxos(confirmed)
 	return confirmed, nil
 }
 
@@ -509,6 +641,7 @@ func (w *BtcElectrumWallet) ListFrozenUnspent() ([]wallet.Utxo, error) {
 			frozen = append(frozen, utxo)
 		}
 	}
+	w.labelUtxos(frozen)
 	return frozen, nil
 }
 
@@ -524,7 +657,12 @@ func (w *BtcElectrumWallet) FreezeUTXO(op *wire.OutPoint) error {
 	}
 	for _, utxo := range utxos {
 		if utxo.Op.Hash.IsEqual(&op.Hash) && utxo.Op.Index == op.Index {
-			return w.txstore.Utxos().Freeze(utxo)
+			err = w.txstore.Utxos().Freeze(utxo)
+			if err != nil {
+				return err
+			}
+			w.notifyUtxo(wallet.EventUtxoFrozen, utxo.Op)
+			return nil
 		}
 	}
 	return errors.New("utxo not found")
@@ -537,7 +675,12 @@ func (w *BtcElectrumWallet) UnFreezeUTXO(op *wire.OutPoint) error {
 	}
 	for _, utxo := range utxos {
 		if utxo.Op.Hash.IsEqual(&op.Hash) && utxo.Op.Index == op.Index {
-			return w.txstore.Utxos().UnFreeze(utxo)
+			err = w.txstore.Utxos().UnFreeze(utxo)
+			if err != nil {
+				return err
+			}
+			w.notifyUtxo(wallet.EventUtxoUnfrozen, utxo.Op)
+			return nil
 		}
 	}
 	return errors.New("utxo not found")
@@ -545,8 +688,11 @@ func (w *BtcElectrumWallet) UnFreezeUTXO(op *wire.OutPoint) error {
 
 // Update the wallet's view of the blockchain
 func (w *BtcElectrumWallet) UpdateTip(newTip int64, synced bool) {
-	w.blockchainTip = newTip
-	w.blockchainSynced = synced
+	w.blockchainSynced.Store(synced)
+	oldTip := w.blockchainTip.Swap(newTip)
+	if oldTip > 0 && newTip > oldTip {
+		w.notifyConfirmations(oldTip, newTip)
+	}
 }
 
 func (w *BtcElectrumWallet) Close() {
@@ -554,6 +700,7 @@ func (w *BtcElectrumWallet) Close() {
 		// Any other teardown here .. long running threads, etc.
 		w.running = false
 	}
+	w.txstore.events.Close()
 	fmt.Println("btc wallet closed")
 }
 
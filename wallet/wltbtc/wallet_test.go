@@ -152,3 +152,51 @@ func TestBalance(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRecreateWalletWithPassphrase(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	w, err := RecreateElectrumWallet(newMockConfig(), "abc", mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := w.MasterFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fingerprint != "73c5da0a" {
+		t.Fatalf("expected fingerprint 73c5da0a - got %s", fingerprint)
+	}
+
+	config := newMockConfig()
+	config.StoreEncSeed = true
+	w, err = RecreateElectrumWallet(config, "abc", mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withPassphrase, err := w.MasterFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withPassphrase == fingerprint {
+		t.Fatal("expected a different fingerprint with a passphrase")
+	}
+	loaded, err := loadBtcElectrumWallet(config, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.storageManager.store.Passphrase != "TREZOR" {
+		t.Fatalf("expected stored passphrase - got %q", loaded.storageManager.store.Passphrase)
+	}
+	if fp, _ := loaded.MasterFingerprint(); fp != withPassphrase {
+		t.Fatalf("expected fingerprint %s - got %s", withPassphrase, fp)
+	}
+
+	// the passphrase is not stored without the seed
+	w, err = RecreateElectrumWallet(newMockConfig(), "abc", mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.storageManager.store.Passphrase != "" {
+		t.Fatal("expected no stored passphrase")
+	}
+}